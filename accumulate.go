@@ -0,0 +1,113 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"sort"
+	"sync"
+)
+
+// accumulating is the registry of parameters for which every occurrence takes
+// effect instead of only the last one, keyed by canonical key.
+var accumulating = struct {
+	sync.RWMutex
+	keys map[string]bool
+}{
+	keys: map[string]bool{
+		"console":        true,
+		"initrd":         true,
+		"memmap":         true,
+		"video":          true,
+		"systemd.setenv": true,
+	},
+}
+
+// AccumulatingKeys returns the canonical keys of all parameters registered as
+// accumulating, sorted alphabetically.
+func AccumulatingKeys() []string {
+	accumulating.RLock()
+	defer accumulating.RUnlock()
+	keys := make([]string, 0, len(accumulating.keys))
+	for key := range accumulating.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsAccumulating returns whether the parameter identified by key is registered
+// as accumulating, i.e. whether each of its occurrences takes effect instead of
+// only the last one.
+func IsAccumulating(key string) bool {
+	accumulating.RLock()
+	defer accumulating.RUnlock()
+	return accumulating.keys[canonicalizeKey(key)]
+}
+
+// RegisterAccumulating registers the parameters identified by keys as
+// accumulating. The registry is shared by the whole program and ships with
+// console, initrd, memmap, video, and systemd.setenv registered. It is consulted
+// by Effective, Deduplicate, and SetKarg.
+func RegisterAccumulating(keys ...string) {
+	accumulating.Lock()
+	defer accumulating.Unlock()
+	for _, key := range keys {
+		accumulating.keys[canonicalizeKey(key)] = true
+	}
+}
+
+// UnregisterAccumulating registers the parameters identified by keys as unique,
+// undoing RegisterAccumulating or removing one of the defaults.
+func UnregisterAccumulating(keys ...string) {
+	accumulating.Lock()
+	defer accumulating.Unlock()
+	for _, key := range keys {
+		delete(accumulating.keys, canonicalizeKey(key))
+	}
+}
+
+// effectiveItems returns the items of k that take effect, in list order: the
+// last occurrence of each key, or every occurrence of accumulating keys. If
+// uniqueValues is set, occurrences of accumulating keys repeating an earlier
+// value are left out.
+func (k *Kargs) effectiveItems(uniqueValues bool) []*kargItem {
+	accumulating.RLock()
+	defer accumulating.RUnlock()
+
+	var kept []*kargItem
+	seen := make(map[string]bool)
+	for llTracker := k.last; llTracker != nil; llTracker = llTracker.prev {
+		canonicalKey := llTracker.karg.CanonicalKey
+		if seen[canonicalKey] && !accumulating.keys[canonicalKey] {
+			continue
+		}
+		seen[canonicalKey] = true
+		kept = append(kept, llTracker)
+	}
+
+	// Restore list order
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	if !uniqueValues {
+		return kept
+	}
+
+	unique := kept[:0]
+	seenValues := make(map[string]map[string]bool)
+	for _, item := range kept {
+		canonicalKey := item.karg.CanonicalKey
+		if accumulating.keys[canonicalKey] {
+			if seenValues[canonicalKey] == nil {
+				seenValues[canonicalKey] = make(map[string]bool)
+			}
+			if seenValues[canonicalKey][item.karg.Value] {
+				continue
+			}
+			seenValues[canonicalKey][item.karg.Value] = true
+		}
+		unique = append(unique, item)
+	}
+	return unique
+}
@@ -0,0 +1,37 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccumulatingKeys(t *testing.T) {
+	assert.Equal(t, []string{"console", "initrd", "memmap", "systemd.setenv", "video"}, AccumulatingKeys())
+}
+
+func TestRegisterAccumulating(t *testing.T) {
+	assert.False(t, IsAccumulating("my-param"))
+
+	RegisterAccumulating("my-param")
+	defer UnregisterAccumulating("my_param")
+	assert.True(t, IsAccumulating("my-param"))
+	assert.True(t, IsAccumulating("my_param"))
+
+	k := NewKargs([]byte("my-param=1 my_param=2 other=1 other=2"))
+	assert.Equal(t, "my-param=1 my_param=2 other=2", k.Effective().String())
+}
+
+func TestUnregisterAccumulating(t *testing.T) {
+	assert.True(t, IsAccumulating("video"))
+
+	UnregisterAccumulating("video")
+	defer RegisterAccumulating("video")
+	assert.False(t, IsAccumulating("video"))
+
+	k := NewKargs([]byte("video=a video=b"))
+	assert.Equal(t, "video=b", k.Effective().String())
+}
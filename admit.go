@@ -0,0 +1,53 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "fmt"
+
+// Admitter reviews a proposed change of a command line before it is persisted,
+// like an admission webhook. Review is given the current command line old,
+// which is nil if there is none yet, and the proposed one new, which it must
+// not modify. It returns whether the change is allowed and, if not, the
+// reasons why; it may also return reasons for an allowed change, e.g. warnings.
+type Admitter interface {
+	Review(old, new *Kargs) (allowed bool, reasons []string)
+}
+
+// AdmitterFunc adapts an ordinary function to the Admitter interface.
+type AdmitterFunc func(old, new *Kargs) (bool, []string)
+
+// Review calls f(old, new).
+func (f AdmitterFunc) Review(old, new *Kargs) (bool, []string) {
+	return f(old, new)
+}
+
+// AdmitChain is an Admitter running several Admitters in order.
+type AdmitChain []Admitter
+
+// Review runs every Admitter of c, even after one has denied the change, so
+// that all reasons are collected, and returns the reasons of all of them in
+// order. The change is allowed if all Admitters allow it, which includes the
+// case of an empty chain.
+func (c AdmitChain) Review(old, new *Kargs) (bool, []string) {
+	allowed := true
+	var reasons []string
+	for _, admitter := range c {
+		ok, r := admitter.Review(old, new)
+		allowed = allowed && ok
+		reasons = append(reasons, r...)
+	}
+	return allowed, reasons
+}
+
+// PolicyAdmitter returns an Admitter that denies changes whose new command line
+// violates any of policies, giving one reason per violated policy.
+func PolicyAdmitter(policies ...*Policy) Admitter {
+	return AdmitterFunc(func(_, new *Kargs) (bool, []string) {
+		var reasons []string
+		for _, policy := range new.CheckPolicies(policies...) {
+			reasons = append(reasons, fmt.Sprintf("violates policy %s: %s", policy.Name(), policy))
+		}
+		return len(reasons) == 0, reasons
+	})
+}
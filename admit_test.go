@@ -0,0 +1,51 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmitChain_Review(t *testing.T) {
+	noRootChange := AdmitterFunc(func(old, new *Kargs) (bool, []string) {
+		if old == nil {
+			return true, nil
+		}
+		oldRoot, _ := old.GetKarg("root")
+		newRoot, _ := new.GetKarg("root")
+		if len(oldRoot) == len(newRoot) && (len(oldRoot) == 0 || oldRoot[0] == newRoot[0]) {
+			return true, nil
+		}
+		return false, []string{"root must not change"}
+	})
+	debugWarning := AdmitterFunc(func(_, new *Kargs) (bool, []string) {
+		if new.ContainsKarg("debug") {
+			return true, []string{"debug is enabled"}
+		}
+		return true, nil
+	})
+	policy, err := ParsePolicy("no-init-override", "!has('init')")
+	assert.NoError(t, err)
+	chain := AdmitChain{noRootChange, debugWarning, PolicyAdmitter(policy)}
+
+	old := NewKargs([]byte("root=/dev/sda1 quiet"))
+
+	allowed, reasons := chain.Review(old, NewKargs([]byte("root=/dev/sda1 debug")))
+	assert.True(t, allowed)
+	assert.Equal(t, []string{"debug is enabled"}, reasons)
+
+	allowed, reasons = chain.Review(old, NewKargs([]byte("root=/dev/sda2 debug init=/bin/sh")))
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"root must not change", "debug is enabled", "violates policy no-init-override: !has('init')"}, reasons)
+
+	allowed, reasons = chain.Review(nil, NewKargs([]byte("root=/dev/sda2")))
+	assert.True(t, allowed)
+	assert.Empty(t, reasons)
+
+	allowed, reasons = AdmitChain{}.Review(old, NewKargsEmpty())
+	assert.True(t, allowed)
+	assert.Empty(t, reasons)
+}
@@ -0,0 +1,316 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// AnonymizePolicy configures Anonymize.
+type AnonymizePolicy struct {
+	// Hash replaces each sensitive item with a pseudonym derived from a
+	// SHA-256 hash of it, so that equal items map to equal pseudonyms and
+	// remain distinguishable. Otherwise, all items of a kind are replaced by
+	// the same placeholder. Note that unkeyed hashes of guessable items like
+	// IPv4 addresses can be reversed by trying all candidates; set Secret to
+	// prevent this.
+	Hash bool
+
+	// Secret, if set, makes Hash use an HMAC-SHA256 keyed with Secret instead
+	// of a plain hash, so that pseudonyms cannot be reversed or recomputed
+	// without it. Reusing the same Secret for several snapshots maps the same
+	// item to the same pseudonym in all of them, so that e.g. changes of a
+	// node can be followed over time.
+	Secret []byte
+
+	// Keys lists keys whose values are replaced as a whole, e.g. those holding
+	// serial numbers or host names, which cannot be recognized by their form.
+	Keys []string
+}
+
+var (
+	// anonURL matches URLs, capturing the optional user info including the @
+	// and the host, which may be a bracketed IPv6 address.
+	anonURL = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://([^/@\s]*@)?(\[[^\]]*\]|[^/:\s?#,\[\]]+)`)
+
+	// anonUUID matches UUIDs.
+	anonUUID = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+
+	// anonIPv6 matches candidates for IPv6 addresses, with an optional zone.
+	anonIPv6 = regexp.MustCompile(`[0-9a-fA-F:.]*:[0-9a-fA-F:.]*:[0-9a-fA-F:.]*(%[a-zA-Z0-9_.-]+)?`)
+
+	// anonIPv4 matches candidates for IPv4 addresses.
+	anonIPv4 = regexp.MustCompile(`\b[0-9]{1,3}(\.[0-9]{1,3}){3}\b`)
+
+	// netrootProtos are the protocols of dracut network root values, as in
+	// root=nfs:server:/path, that are followed by a host.
+	netrootProtos = []string{"nfs", "nfs4", "nbd", "iscsi"}
+)
+
+// Anonymize returns a copy of k with sensitive items in its values replaced,
+// so that command lines can be shared without revealing details of the
+// infrastructure they come from, while keeping their structure, e.g. ip=
+// still holds valid addresses. Replaced are:
+//
+//   - IPv4 and IPv6 addresses, except netmasks and unspecified addresses, by
+//     addresses from the ranges 192.0.2.0/24 or 10.0.0.0/8 and 2001:db8::/32
+//   - UUIDs, by UUIDs
+//   - MAC addresses, by locally administered MAC addresses
+//   - the host of URLs, by a name below .invalid, and their user info
+//   - the host name field of ip= values and the value of hostname=
+//   - the server of network root devices, as in nfsroot=server:/path,
+//     root=nfs:server:/path, netroot=iscsi:user:password@server::3260::target,
+//     and nbdroot=server:2000/export, and the credentials of iSCSI ones
+//   - the whole values of the keys listed in policy.Keys
+//
+// The keys and all other parts of the values are kept. The copy has the
+// settings of k, but values are quoted as needed regardless of its QuoteMode,
+// so that no value is left unchanged because it would be rejected.
+func (k *Kargs) Anonymize(policy AnonymizePolicy) *Kargs {
+	a := anonymizer{policy: policy, keys: make(map[string]bool, len(policy.Keys))}
+	for _, key := range policy.Keys {
+		a.keys[canonicalizeKey(key)] = true
+	}
+	anonymized := k.Clone()
+	for llTracker := anonymized.list; llTracker != nil; llTracker = llTracker.next {
+		karg := llTracker.karg
+		if karg.Value == "" {
+			continue
+		}
+		if value := a.value(karg.CanonicalKey, karg.Value); value != karg.Value {
+			llTracker.karg = quotedKarg(karg.Key, value)
+		}
+	}
+	anonymized.invalidate()
+	return anonymized
+}
+
+// anonymizer replaces sensitive items as configured by policy.
+type anonymizer struct {
+	policy AnonymizePolicy
+	keys   map[string]bool // Canonical keys whose values are replaced as a whole
+}
+
+// value returns value, the value of canonicalKey, with its sensitive items
+// replaced.
+func (a anonymizer) value(canonicalKey, value string) string {
+	if a.keys[canonicalKey] {
+		return a.pseudonym("value", value, "redacted", "anon-")
+	}
+	var repls []replacement
+	add := func(start, end int, text string) {
+		for _, repl := range repls {
+			if start < repl.end && repl.start < end {
+				return
+			}
+		}
+		repls = append(repls, replacement{start: start, end: end, text: text})
+	}
+
+	for _, m := range anonURL.FindAllStringSubmatchIndex(value, -1) {
+		if m[2] >= 0 {
+			user := a.pseudonym("user", value[m[2]:m[3]-1], "user", "user-")
+			add(m[2], m[3], user+"@")
+		}
+		add(m[4], m[5], a.host(value[m[4]:m[5]]))
+	}
+	switch canonicalKey {
+	case "ip":
+		if start, end := ipHostnameSpan(value); start < end {
+			add(start, end, a.pseudonym("host", value[start:end], "host", "host-"))
+		}
+	case "hostname":
+		add(0, len(value), a.pseudonym("host", value, "host", "host-"))
+	}
+	if user, host := netrootSpans(canonicalKey, value); host[0] < host[1] {
+		if user[0] < user[1] {
+			add(user[0], user[1], a.pseudonym("user", value[user[0]:user[1]-1], "user", "user-")+"@")
+		}
+		add(host[0], host[1], a.host(value[host[0]:host[1]]))
+	}
+	for _, m := range anonUUID.FindAllStringIndex(value, -1) {
+		add(m[0], m[1], a.uuid(value[m[0]:m[1]]))
+	}
+	for _, span := range macSpans(value) {
+		add(span[0], span[1], a.mac(value[span[0]:span[1]]))
+	}
+	for _, re := range []*regexp.Regexp{anonIPv6, anonIPv4} {
+		for _, m := range re.FindAllStringIndex(value, -1) {
+			if addr, err := netip.ParseAddr(value[m[0]:m[1]]); err == nil && !addr.IsUnspecified() && !isNetmask(addr) {
+				add(m[0], m[1], a.addr(addr).String())
+			}
+		}
+	}
+	sort.Slice(repls, func(i, j int) bool { return repls[i].start < repls[j].start })
+	return applyReplacements(value, repls)
+}
+
+// sum returns the hash the pseudonym of item, an item of kind, is derived
+// from.
+func (a anonymizer) sum(kind, item string) []byte {
+	if len(a.policy.Secret) > 0 {
+		mac := hmac.New(sha256.New, a.policy.Secret)
+		mac.Write([]byte(kind + "\x00" + item))
+		return mac.Sum(nil)
+	}
+	sum := sha256.Sum256([]byte(kind + "\x00" + item))
+	return sum[:]
+}
+
+// pseudonym returns the replacement for item, an item of kind: mask if
+// policy.Hash is false, otherwise prefix followed by hexadecimal digits of its
+// hash.
+func (a anonymizer) pseudonym(kind, item, mask, prefix string) string {
+	if !a.policy.Hash {
+		return mask
+	}
+	return prefix + hex.EncodeToString(a.sum(kind, item)[:6])
+}
+
+// host returns the replacement for host, the host part of a URL.
+func (a anonymizer) host(host string) string {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		if addr.Is6() {
+			return "[" + a.addr(addr).String() + "]"
+		}
+		return a.addr(addr).String()
+	}
+	return a.pseudonym("host", host, "host", "host-") + ".invalid"
+}
+
+// addr returns the replacement for addr, keeping its family and zone.
+func (a anonymizer) addr(addr netip.Addr) netip.Addr {
+	if addr.Is4() {
+		if !a.policy.Hash {
+			return netip.AddrFrom4([4]byte{192, 0, 2, 1})
+		}
+		sum := a.sum("ip", addr.String())
+		return netip.AddrFrom4([4]byte{10, sum[0], sum[1], sum[2]})
+	}
+	b := [16]byte{0x20, 0x01, 0x0d, 0xb8}
+	if a.policy.Hash {
+		copy(b[4:], a.sum("ip", addr.WithZone("").String()))
+	} else {
+		b[15] = 1
+	}
+	return netip.AddrFrom16(b).WithZone(addr.Zone())
+}
+
+// uuid returns the replacement for uuid.
+func (a anonymizer) uuid(uuid string) string {
+	var b [16]byte
+	if a.policy.Hash {
+		copy(b[:], a.sum("uuid", strings.ToLower(uuid)))
+	}
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", b[:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// mac returns the replacement for mac, in the same notation.
+func (a anonymizer) mac(mac string) string {
+	b := [6]byte{0x02}
+	if a.policy.Hash {
+		normalized, _ := NormalizeMAC(mac)
+		copy(b[1:], a.sum("mac", normalized))
+	}
+	switch {
+	case strings.Contains(mac, "."):
+		return fmt.Sprintf("%04x.%04x.%04x", b[:2], b[2:4], b[4:])
+	case strings.Contains(mac, "-"):
+		return fmt.Sprintf("%02x-%02x-%02x-%02x-%02x-%02x", b[0], b[1], b[2], b[3], b[4], b[5])
+	default:
+		return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", b[0], b[1], b[2], b[3], b[4], b[5])
+	}
+}
+
+// ipHostnameSpan returns the byte offsets of the host name field of value, the
+// value of an ip= parameter, or equal offsets if it has none.
+func ipHostnameSpan(value string) (start, end int) {
+	fields, err := SplitAddressFields(value)
+	if err != nil || len(fields) < 7 || isAutoconf(fields[1]) || fields[4] == "" {
+		return 0, 0
+	}
+	// Find the fifth colon outside of brackets
+	colons, depth := 0, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth > 0 {
+				continue
+			}
+			colons++
+			if colons == 4 {
+				start = i + 1
+			} else if colons == 5 {
+				return start, i
+			}
+		}
+	}
+	return 0, 0
+}
+
+// netrootSpans returns the byte offsets of the user info, including the @, and
+// of the host in value, the value of canonicalKey, if it names a network root
+// device by its server, as in nfsroot=server:/path or root=nfs:server:/path.
+// Absent parts have equal offsets.
+func netrootSpans(canonicalKey, value string) (user, host [2]int) {
+	start, proto := 0, ""
+	switch canonicalKey {
+	case "nfsroot":
+		proto = "nfs"
+	case "root", "netroot", "nbdroot":
+		if prefix, _, ok := strings.Cut(value, ":"); ok && slices.Contains(netrootProtos, prefix) {
+			start, proto = len(prefix)+1, prefix
+		} else if canonicalKey == "nbdroot" {
+			proto = "nbd"
+		} else {
+			return user, host
+		}
+	default:
+		return user, host
+	}
+	if proto == "iscsi" {
+		if at := strings.LastIndexByte(value, '@'); at >= start {
+			user = [2]int{start, at + 1}
+			start = at + 1
+		}
+	}
+	rest := value[start:]
+	end := len(rest)
+	if strings.HasPrefix(rest, "[") {
+		if idx := strings.IndexByte(rest, ']'); idx >= 0 {
+			end = idx + 1
+		}
+	} else if idx := strings.IndexAny(rest, ":/,"); idx >= 0 {
+		end = idx
+	}
+	// NFS servers are optional and always followed by the path.
+	if strings.HasPrefix(proto, "nfs") && (end == len(rest) || rest[end] != ':') {
+		return [2]int{}, host
+	}
+	return user, [2]int{start, start + end}
+}
+
+// isNetmask returns whether addr is an IPv4 netmask, i.e. consists of
+// contiguous one bits followed by zero bits.
+func isNetmask(addr netip.Addr) bool {
+	if !addr.Is4() {
+		return false
+	}
+	b := addr.As4()
+	inverted := ^binary.BigEndian.Uint32(b[:])
+	return inverted&(inverted+1) == 0
+}
@@ -0,0 +1,62 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+// defaultArenaChunkSize is the number of parameters allocated at once by an
+// Arena created with a non-positive chunk size.
+const defaultArenaChunkSize = 64
+
+// Arena allocates the storage backing the parameters of one or more Kargs in
+// chunks, so that services creating and discarding many Kargs generate far less
+// garbage. Pass it to NewKargs with WithArena.
+//
+// Memory handed out by an Arena is only reclaimed by Reset, which makes it
+// available to the next Kargs that uses the arena. Once Reset has been called,
+// every Kargs previously created with the arena, and every Kargs derived from
+// them, must no longer be used. Parameters deleted from a Kargs are not
+// reclaimed until Reset. An Arena is not safe for concurrent use.
+type Arena struct {
+	chunks    [][]kargItem // All chunks allocated so far
+	chunkIdx  int          // Index of the chunk currently being handed out
+	itemIdx   int          // Index of the next free item in the current chunk
+	chunkSize int          // Number of items per chunk
+}
+
+// NewArena returns an empty Arena that allocates chunkSize parameters at a
+// time. If chunkSize is not positive, a default size is used.
+func NewArena(chunkSize int) *Arena {
+	if chunkSize <= 0 {
+		chunkSize = defaultArenaChunkSize
+	}
+	return &Arena{chunkSize: chunkSize}
+}
+
+// Reset makes all memory handed out by a available again. See Arena for the
+// lifetime rules that apply.
+func (a *Arena) Reset() {
+	for _, chunk := range a.chunks {
+		// Drop references to the old parameters so they can be
+		// garbage collected.
+		for i := range chunk {
+			chunk[i] = kargItem{}
+		}
+	}
+	a.chunkIdx = 0
+	a.itemIdx = 0
+}
+
+// newItem returns a pointer to a zeroed kargItem from the arena, allocating a
+// new chunk if all existing ones are used up.
+func (a *Arena) newItem() *kargItem {
+	if a.chunkIdx < len(a.chunks) && a.itemIdx == len(a.chunks[a.chunkIdx]) {
+		a.chunkIdx++
+		a.itemIdx = 0
+	}
+	if a.chunkIdx == len(a.chunks) {
+		a.chunks = append(a.chunks, make([]kargItem, a.chunkSize))
+	}
+	item := &a.chunks[a.chunkIdx][a.itemIdx]
+	a.itemIdx++
+	return item
+}
@@ -0,0 +1,87 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArena_newItem(t *testing.T) {
+	a := NewArena(2)
+
+	// Items are handed out from the same chunk until it is full
+	first := a.newItem()
+	second := a.newItem()
+	assert.Len(t, a.chunks, 1)
+	assert.NotSame(t, first, second)
+
+	// A new chunk is allocated once the current one is full
+	a.newItem()
+	assert.Len(t, a.chunks, 2)
+}
+
+func TestArena_Reset(t *testing.T) {
+	a := NewArena(2)
+	k := NewKargs([]byte("key1=val1 key2=val2 key3=val3"), WithArena(a))
+	assert.Len(t, a.chunks, 2)
+	first := k.list
+
+	a.Reset()
+	assert.Empty(t, first.karg.Raw)
+
+	// Chunks are reused after a reset instead of allocating new ones
+	k = NewKargs([]byte("key4 key5"), WithArena(a))
+	assert.Len(t, a.chunks, 2)
+	assert.Same(t, first, k.list)
+	assert.Equal(t, "key4 key5", k.String())
+}
+
+func TestNewArena_defaultChunkSize(t *testing.T) {
+	a := NewArena(0)
+	a.newItem()
+	assert.Len(t, a.chunks[0], defaultArenaChunkSize)
+}
+
+func TestNewKargs_withArena(t *testing.T) {
+	a := NewArena(4)
+	k := NewKargs([]byte("key1=val1 mod.key"), WithArena(a))
+	assert.Same(t, a, k.cfg.arena)
+	assert.Equal(t, 2, a.itemIdx)
+
+	// Parameters added later come from the arena, too
+	err := k.SetKarg("key2", "val2")
+	assert.NoError(t, err)
+	k.AppendKargs("key3")
+	assert.Equal(t, 4, a.itemIdx)
+	assert.Equal(t, "key1=val1 mod.key key2=val2 key3", k.String())
+	assert.Equal(t, "key", k.FlagsForModule("mod"))
+}
+
+func BenchmarkNewKargs(b *testing.B) {
+	line := []byte(benchCmdline)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewKargs(line)
+	}
+}
+
+func BenchmarkNewKargs_withASCII(b *testing.B) {
+	line := []byte(benchCmdline)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewKargs(line, WithASCII())
+	}
+}
+
+func BenchmarkNewKargs_withArena(b *testing.B) {
+	line := []byte(benchCmdline)
+	a := NewArena(0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewKargs(line, WithArena(a))
+		a.Reset()
+	}
+}
@@ -0,0 +1,108 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// BootSpec holds the three pieces needed to boot a kernel and renders them for
+// the common consumers of boot configuration.
+type BootSpec struct {
+	KernelPath  string   // Path or URL of the kernel image
+	InitrdPaths []string // Paths or URLs of the initrds, in load order
+	Kargs       *Kargs   // Kernel command line, may be nil
+}
+
+// BLSEntry returns the boot entry for b as a Boot Loader Specification type #1
+// entry, without a title.
+func (b BootSpec) BLSEntry() (string, error) {
+	if err := b.check(); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "linux %s\n", b.KernelPath)
+	for _, initrd := range b.InitrdPaths {
+		fmt.Fprintf(&sb, "initrd %s\n", initrd)
+	}
+	if cmdline := b.cmdline(); cmdline != "" {
+		fmt.Fprintf(&sb, "options %s\n", cmdline)
+	}
+	return sb.String(), nil
+}
+
+// IPXEScript returns the iPXE commands that boot b. Each initrd is also named
+// in the command line with initrd=, which the kernel's EFI stub needs to find
+// it.
+func (b BootSpec) IPXEScript() (string, error) {
+	if err := b.check(); err != nil {
+		return "", err
+	}
+	args := []string{"kernel", b.KernelPath}
+	for _, initrd := range b.InitrdPaths {
+		args = append(args, "initrd="+path.Base(initrd))
+	}
+	if cmdline := b.cmdline(); cmdline != "" {
+		args = append(args, cmdline)
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.Join(args, " ") + "\n")
+	for _, initrd := range b.InitrdPaths {
+		fmt.Fprintf(&sb, "initrd %s\n", initrd)
+	}
+	sb.WriteString("boot\n")
+	return sb.String(), nil
+}
+
+// KexecArgs returns the arguments to pass to kexec(8) to load b. Since kexec
+// only supports a single initrd, an error is returned if b has more than one.
+func (b BootSpec) KexecArgs() ([]string, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	if len(b.InitrdPaths) > 1 {
+		return nil, fmt.Errorf("kexec takes a single initrd, got %d: %w", len(b.InitrdPaths), ErrUnsupported)
+	}
+	args := []string{"-l", b.KernelPath}
+	if len(b.InitrdPaths) == 1 {
+		args = append(args, "--initrd="+b.InitrdPaths[0])
+	}
+	return append(args, "--command-line="+b.cmdline()), nil
+}
+
+// SyslinuxEntry returns the LINUX, INITRD, and APPEND lines of a syslinux
+// label booting b.
+func (b BootSpec) SyslinuxEntry() (string, error) {
+	if err := b.check(); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "LINUX %s\n", b.KernelPath)
+	if len(b.InitrdPaths) > 0 {
+		fmt.Fprintf(&sb, "INITRD %s\n", strings.Join(b.InitrdPaths, ","))
+	}
+	if cmdline := b.cmdline(); cmdline != "" {
+		fmt.Fprintf(&sb, "APPEND %s\n", cmdline)
+	}
+	return sb.String(), nil
+}
+
+// check returns an error if b lacks a kernel.
+func (b BootSpec) check() error {
+	if b.KernelPath == "" {
+		return fmt.Errorf("checking boot spec: %w", ErrMissingKernel)
+	}
+	return nil
+}
+
+// cmdline returns the kernel command line of b, which is empty if b has no
+// Kargs.
+func (b BootSpec) cmdline() string {
+	if b.Kargs == nil {
+		return ""
+	}
+	return b.Kargs.String()
+}
@@ -0,0 +1,78 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootSpec_BLSEntry(t *testing.T) {
+	b := BootSpec{
+		KernelPath:  "/vmlinuz-6.1",
+		InitrdPaths: []string{"/microcode.img", "/initramfs-6.1.img"},
+		Kargs:       NewKargs([]byte("root=/dev/sda1 quiet")),
+	}
+	entry, err := b.BLSEntry()
+	assert.NoError(t, err)
+	assert.Equal(t, "linux /vmlinuz-6.1\ninitrd /microcode.img\ninitrd /initramfs-6.1.img\noptions root=/dev/sda1 quiet\n", entry)
+
+	entry, err = BootSpec{KernelPath: "/vmlinuz"}.BLSEntry()
+	assert.NoError(t, err)
+	assert.Equal(t, "linux /vmlinuz\n", entry)
+}
+
+func TestBootSpec_IPXEScript(t *testing.T) {
+	b := BootSpec{
+		KernelPath:  "http://boot.example.tld/vmlinuz",
+		InitrdPaths: []string{"http://boot.example.tld/images/initrd.img"},
+		Kargs:       NewKargs([]byte("root=live:http://boot.example.tld/image.squashfs")),
+	}
+	script, err := b.IPXEScript()
+	assert.NoError(t, err)
+	assert.Equal(t, "kernel http://boot.example.tld/vmlinuz initrd=initrd.img root=live:http://boot.example.tld/image.squashfs\ninitrd http://boot.example.tld/images/initrd.img\nboot\n", script)
+}
+
+func TestBootSpec_KexecArgs(t *testing.T) {
+	b := BootSpec{
+		KernelPath:  "/boot/vmlinuz",
+		InitrdPaths: []string{"/boot/initrd.img"},
+		Kargs:       NewKargs([]byte(`root=/dev/sda1 init="/sbin/init --verbose"`)),
+	}
+	args, err := b.KexecArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-l", "/boot/vmlinuz", "--initrd=/boot/initrd.img", `--command-line=root=/dev/sda1 init="/sbin/init --verbose"`}, args)
+
+	args, err = BootSpec{KernelPath: "/boot/vmlinuz"}.KexecArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-l", "/boot/vmlinuz", "--command-line="}, args)
+
+	b.InitrdPaths = append(b.InitrdPaths, "/boot/extra.img")
+	_, err = b.KexecArgs()
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestBootSpec_SyslinuxEntry(t *testing.T) {
+	b := BootSpec{
+		KernelPath:  "/vmlinuz",
+		InitrdPaths: []string{"/microcode.img", "/initrd.img"},
+		Kargs:       NewKargs([]byte("root=/dev/sda1 quiet")),
+	}
+	entry, err := b.SyslinuxEntry()
+	assert.NoError(t, err)
+	assert.Equal(t, "LINUX /vmlinuz\nINITRD /microcode.img,/initrd.img\nAPPEND root=/dev/sda1 quiet\n", entry)
+}
+
+func TestBootSpec_missingKernel(t *testing.T) {
+	b := BootSpec{Kargs: NewKargs([]byte("quiet"))}
+	_, err := b.BLSEntry()
+	assert.ErrorIs(t, err, ErrMissingKernel)
+	_, err = b.IPXEScript()
+	assert.ErrorIs(t, err, ErrMissingKernel)
+	_, err = b.KexecArgs()
+	assert.ErrorIs(t, err, ErrMissingKernel)
+	_, err = b.SyslinuxEntry()
+	assert.ErrorIs(t, err, ErrMissingKernel)
+}
@@ -0,0 +1,50 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which buffers are not returned to
+// the pool, so that one huge render doesn't pin its memory indefinitely.
+const maxPooledBufferSize = 64 * 1024
+
+// bufferPool holds buffers reused between renders of Kargs.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer resets buf and returns it to the pool unless it grew too large.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// writeBuffer writes the raw form of each karg of k to buf, separated and
+// terminated as set in cfg.
+func (k *Kargs) writeBuffer(buf *bytes.Buffer, cfg formatConfig) {
+	if cfg.sorted {
+		k.writeSorted(buf, cfg)
+		return
+	}
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if llTracker != k.list {
+			buf.WriteString(cfg.separator)
+		}
+		buf.WriteString(llTracker.karg.Raw)
+	}
+	buf.WriteString(cfg.terminator)
+}
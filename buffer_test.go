@@ -0,0 +1,52 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutBuffer(t *testing.T) {
+	buf := getBuffer()
+	buf.WriteString("content")
+	putBuffer(buf)
+	assert.Zero(t, buf.Len())
+
+	// Oversized buffers are dropped rather than being reset for reuse
+	big := bytes.NewBufferString(strings.Repeat("x", maxPooledBufferSize+1))
+	putBuffer(big)
+	assert.NotZero(t, big.Len())
+}
+
+func TestKargs_String_withoutBufferPool(t *testing.T) {
+	k := NewKargs([]byte(benchCmdline), WithoutBufferPool())
+	assert.True(t, k.cfg.noBufferPool)
+	assert.Equal(t, benchCmdline, k.String())
+}
+
+func BenchmarkKargs_String(b *testing.B) {
+	k := NewKargs([]byte(benchCmdline))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Bypass the string cache to measure rendering
+		k.invalidate()
+		_ = k.String()
+	}
+}
+
+func BenchmarkKargs_String_withoutBufferPool(b *testing.B) {
+	k := NewKargs([]byte(benchCmdline), WithoutBufferPool())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Bypass the string cache to measure rendering
+		k.invalidate()
+		_ = k.String()
+	}
+}
@@ -0,0 +1,75 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MarshalCanonicalJSON returns k as canonical JSON, suitable for golden tests
+// and cache keys: the same kargs always produce the same bytes, regardless of
+// the Go version. The result is an array holding one object per karg in command
+// line order, each with the members canonical_key, key, raw, and value, e.g.
+//
+//	[{"canonical_key":"rd_break","key":"rd-break","raw":"rd-break","value":""}]
+//
+// Members are sorted by name and no insignificant whitespace is written.
+// Strings escape only quotation marks, backslashes, and control characters, as
+// in RFC 8785, using the short escapes \b, \t, \n, \f, and \r where possible and
+// lowercase \u00XX escapes otherwise. An error wrapping ErrUnsupported is
+// returned if any field of a karg isn't valid UTF-8.
+func (k *Kargs) MarshalCanonicalJSON() ([]byte, error) {
+	buf := []byte{'['}
+	for item := k.list; item != nil; item = item.next {
+		karg := item.karg
+		for _, field := range []string{karg.CanonicalKey, karg.Key, karg.Raw, karg.Value} {
+			if !utf8.ValidString(field) {
+				return nil, fmt.Errorf("encoding karg %s: invalid UTF-8: %w", karg.Raw, ErrUnsupported)
+			}
+		}
+		if item != k.list {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"canonical_key":`...)
+		buf = appendJSONString(buf, karg.CanonicalKey)
+		buf = append(buf, `,"key":`...)
+		buf = appendJSONString(buf, karg.Key)
+		buf = append(buf, `,"raw":`...)
+		buf = appendJSONString(buf, karg.Raw)
+		buf = append(buf, `,"value":`...)
+		buf = appendJSONString(buf, karg.Value)
+		buf = append(buf, '}')
+	}
+	return append(buf, ']'), nil
+}
+
+// appendJSONString appends s, which must be valid UTF-8, to buf as a JSON string
+// escaped as described in MarshalCanonicalJSON.
+func appendJSONString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c == '\b':
+			buf = append(buf, '\\', 'b')
+		case c == '\t':
+			buf = append(buf, '\\', 't')
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\f':
+			buf = append(buf, '\\', 'f')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
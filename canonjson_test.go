@@ -0,0 +1,50 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_MarshalCanonicalJSON(t *testing.T) {
+	k := NewKargs([]byte(`rd-break root=/dev/sda1 msg="<a & b>"`))
+	data, err := k.MarshalCanonicalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `[`+
+		`{"canonical_key":"rd_break","key":"rd-break","raw":"rd-break","value":""},`+
+		`{"canonical_key":"root","key":"root","raw":"root=/dev/sda1","value":"/dev/sda1"},`+
+		`{"canonical_key":"msg","key":"msg","raw":"msg=\"<a & b>\"","value":"<a & b>"}`+
+		`]`, string(data))
+
+	// The result is valid JSON.
+	var decoded []map[string]string
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "<a & b>", decoded[2]["value"])
+
+	data, err = NewKargsEmpty().MarshalCanonicalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(data))
+
+	_, err = NewKargs([]byte("k=\xff")).MarshalCanonicalJSON()
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestAppendJSONString(t *testing.T) {
+	tests := map[string]string{
+		"plain":        `"plain"`,
+		`q"b\`:         `"q\"b\\"`,
+		"\b\t\n\f\r":   `"\b\t\n\f\r"`,
+		"\x00\x1f\x7f": `"\u0000\u001f` + "\x7f" + `"`,
+		"ü/€":          `"ü/€"`,
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, string(appendJSONString(nil, in)))
+		var decoded string
+		assert.NoError(t, json.Unmarshal([]byte(want), &decoded))
+		assert.Equal(t, in, decoded)
+	}
+}
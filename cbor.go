@@ -0,0 +1,194 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+)
+
+// CBOR major types used by the encoding.
+const (
+	cborText  = 3
+	cborArray = 4
+)
+
+// MarshalCBOR returns a deterministic binary encoding of k in canonical CBOR
+// (RFC 8949, section 4.2.1), suitable for hashing, signing, and measuring. It is
+// normalized: it covers the canonical keys and dequoted values of the kargs in
+// order, so command lines this package considers equivalent encode to identical
+// bytes.
+//
+// The encoding is an array holding one array per karg, which in turn holds the
+// canonical key and, if the karg has a value, the value, all as text strings. An
+// error wrapping ErrUnsupported is returned if a key or value isn't valid UTF-8,
+// since it can't be encoded as a text string.
+func (k *Kargs) MarshalCBOR() ([]byte, error) {
+	buf := appendCBORHead(nil, cborArray, uint64(k.numParams))
+	for item := k.list; item != nil; item = item.next {
+		karg := item.karg
+		if !utf8.ValidString(karg.CanonicalKey) || !utf8.ValidString(karg.Value) {
+			return nil, fmt.Errorf("encoding karg %s: invalid UTF-8: %w", karg.Raw, ErrUnsupported)
+		}
+		if karg.Value == "" {
+			buf = appendCBORHead(buf, cborArray, 1)
+			buf = appendCBORText(buf, karg.CanonicalKey)
+			continue
+		}
+		buf = appendCBORHead(buf, cborArray, 2)
+		buf = appendCBORText(buf, karg.CanonicalKey)
+		buf = appendCBORText(buf, karg.Value)
+	}
+	return buf, nil
+}
+
+// UnmarshalCBOR replaces the kargs of k with those decoded from data, which must
+// be in the form produced by MarshalCBOR. Kargs are written with their canonical
+// keys and with values quoted by QuoteValue where needed. Since the encoding is
+// canonical, any other encoding of the same kargs, e.g. one using longer integer
+// forms, is rejected with an error wrapping ErrInvalidEncoding; k is left
+// unchanged in that case.
+func (k *Kargs) UnmarshalCBOR(data []byte) error {
+	d := cborDecoder{data: data}
+	n, err := d.head(cborArray)
+	if err != nil {
+		return err
+	}
+	var kargs []Karg
+	for i := uint64(0); i < n; i++ {
+		fields, err := d.head(cborArray)
+		if err != nil {
+			return err
+		}
+		if fields != 1 && fields != 2 {
+			return fmt.Errorf("decoding karg %d: %d fields: %w", i, fields, ErrInvalidEncoding)
+		}
+		key, err := d.text()
+		if err != nil {
+			return err
+		}
+		var value string
+		if fields == 2 {
+			if value, err = d.text(); err != nil {
+				return err
+			}
+		}
+		karg, err := decodedKarg(int(i), key, value, fields == 2)
+		if err != nil {
+			return err
+		}
+		kargs = append(kargs, karg)
+	}
+	if len(d.data) > 0 {
+		return fmt.Errorf("decoding kargs: %d trailing bytes: %w", len(d.data), ErrInvalidEncoding)
+	}
+	k.reset()
+	for _, karg := range kargs {
+		k.appendItem(karg)
+	}
+	return nil
+}
+
+// decodedKarg returns the karg decoded as the i-th one from an encoding holding
+// its canonical key and, if hasValue is true, its dequoted value, which must not
+// be empty then. The value is quoted by QuoteValue where needed.
+func decodedKarg(i int, key, value string, hasValue bool) (Karg, error) {
+	if err := checkKey(key); err != nil {
+		return Karg{}, fmt.Errorf("decoding karg %d: %w", i, err)
+	}
+	if key == "" || key != canonicalizeKey(key) {
+		return Karg{}, fmt.Errorf("decoding karg %d: key %q is not canonical: %w", i, key, ErrInvalidEncoding)
+	}
+	if hasValue && value == "" {
+		return Karg{}, fmt.Errorf("decoding karg %d: empty value: %w", i, ErrInvalidEncoding)
+	}
+	return quotedKarg(key, value), nil
+}
+
+// appendCBORHead appends the head of a data item of the given major type and
+// argument to buf, using the shortest possible form.
+func appendCBORHead(buf []byte, major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return append(buf, major|byte(arg))
+	case arg <= 0xff:
+		return append(buf, major|24, byte(arg))
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), arg)
+	}
+}
+
+// appendCBORText appends s as a text string to buf.
+func appendCBORText(buf []byte, s string) []byte {
+	return append(appendCBORHead(buf, cborText, uint64(len(s))), s...)
+}
+
+// cborDecoder decodes the subset of canonical CBOR produced by MarshalCBOR.
+type cborDecoder struct {
+	data []byte // Remaining input
+}
+
+// head consumes the head of the next data item, which must be of the major
+// type major and in shortest form, and returns its argument.
+func (d *cborDecoder) head(major byte) (uint64, error) {
+	if len(d.data) == 0 {
+		return 0, fmt.Errorf("decoding kargs: unexpected end of input: %w", ErrInvalidEncoding)
+	}
+	if d.data[0]>>5 != major {
+		return 0, fmt.Errorf("decoding kargs: major type %d, want %d: %w", d.data[0]>>5, major, ErrInvalidEncoding)
+	}
+	info := d.data[0] & 0x1f
+	d.data = d.data[1:]
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		// Reserved or indefinite length, neither of which is canonical
+		return 0, fmt.Errorf("decoding kargs: additional information %d: %w", info, ErrInvalidEncoding)
+	}
+	size := 1 << (info - 24)
+	if len(d.data) < size {
+		return 0, fmt.Errorf("decoding kargs: unexpected end of input: %w", ErrInvalidEncoding)
+	}
+	var arg, min uint64
+	switch size {
+	case 1:
+		arg, min = uint64(d.data[0]), 24
+	case 2:
+		arg, min = uint64(binary.BigEndian.Uint16(d.data)), 0x100
+	case 4:
+		arg, min = uint64(binary.BigEndian.Uint32(d.data)), 0x10000
+	case 8:
+		arg, min = binary.BigEndian.Uint64(d.data), 0x100000000
+	}
+	if arg < min {
+		return 0, fmt.Errorf("decoding kargs: argument %d not in shortest form: %w", arg, ErrInvalidEncoding)
+	}
+	d.data = d.data[size:]
+	return arg, nil
+}
+
+// text consumes the next data item, which must be a valid UTF-8 text string,
+// and returns it.
+func (d *cborDecoder) text() (string, error) {
+	n, err := d.head(cborText)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(len(d.data)) {
+		return "", fmt.Errorf("decoding kargs: unexpected end of input: %w", ErrInvalidEncoding)
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	if !utf8.ValidString(s) {
+		return "", fmt.Errorf("decoding kargs: invalid UTF-8: %w", ErrInvalidEncoding)
+	}
+	return s, nil
+}
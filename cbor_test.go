@@ -0,0 +1,88 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_MarshalCBOR(t *testing.T) {
+	k := NewKargs([]byte(`rd-break root='/dev/sda1'`))
+	data, err := k.MarshalCBOR()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x82,                                               // Array of 2 kargs
+		0x81, 0x68, 'r', 'd', '_', 'b', 'r', 'e', 'a', 'k', // [rd_break]
+		0x82, 0x64, 'r', 'o', 'o', 't', // [root,
+		0x69, '/', 'd', 'e', 'v', '/', 's', 'd', 'a', '1', // /dev/sda1]
+	}, data)
+
+	// Equivalent command lines encode identically.
+	other, err := NewKargs([]byte(`rd_break root=/dev/sda1`)).MarshalCBOR()
+	assert.NoError(t, err)
+	assert.Equal(t, data, other)
+
+	// Lengths use the shortest form.
+	data, err = NewKargs([]byte("k=" + strings.Repeat("x", 300))).MarshalCBOR()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x81, 0x82, 0x61, 'k', 0x79, 0x01, 0x2c}, data[:7])
+
+	data, err = NewKargsEmpty().MarshalCBOR()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x80}, data)
+
+	_, err = NewKargs([]byte("k=\xff")).MarshalCBOR()
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestKargs_UnmarshalCBOR(t *testing.T) {
+	k := NewKargs([]byte(`quiet rd-break init="/sbin/init --verbose" v='"quoted"' console=tty0 console=ttyS0`))
+	data, err := k.MarshalCBOR()
+	assert.NoError(t, err)
+
+	decoded := NewKargs([]byte("old=karg"))
+	assert.NoError(t, decoded.UnmarshalCBOR(data))
+	assert.Equal(t, `quiet rd_break init="/sbin/init --verbose" v="\"quoted\"" console=tty0 console=ttyS0`, decoded.String())
+	values, _ := decoded.GetKarg("v")
+	assert.Equal(t, []string{`"quoted"`}, values)
+	values, _ = decoded.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0"}, values)
+	assert.False(t, decoded.ContainsKarg("old"))
+
+	again, err := decoded.MarshalCBOR()
+	assert.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestKargs_UnmarshalCBOR_invalid(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":            {},
+		"not an array":     {0x61, 'k'},
+		"non-shortest":     {0x98, 0x01, 0x81, 0x61, 'k'},
+		"indefinite":       {0x9f, 0x81, 0x61, 'k', 0xff},
+		"too many fields":  {0x81, 0x83, 0x61, 'k', 0x61, 'v', 0x61, 'w'},
+		"no fields":        {0x81, 0x80},
+		"truncated":        {0x81, 0x82, 0x61, 'k', 0x63, 'v'},
+		"trailing":         {0x81, 0x81, 0x61, 'k', 0x00},
+		"invalid UTF-8":    {0x81, 0x81, 0x61, 0xff},
+		"non-canonical":    {0x81, 0x81, 0x63, 'a', '-', 'b'},
+		"empty value":      {0x81, 0x82, 0x61, 'k', 0x60},
+		"byte string":      {0x81, 0x81, 0x41, 'k'},
+		"empty key":        {0x81, 0x81, 0x60},
+		"huge array count": {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			k := NewKargs([]byte("quiet"))
+			assert.ErrorIs(t, k.UnmarshalCBOR(data), ErrInvalidEncoding)
+			assert.Equal(t, "quiet", k.String())
+		})
+	}
+
+	k := NewKargs([]byte("quiet"))
+	assert.ErrorIs(t, k.UnmarshalCBOR([]byte{0x81, 0x81, 0x63, 'a', ' ', 'b'}), ErrInvalidKey)
+}
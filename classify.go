@@ -0,0 +1,226 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "strings"
+
+// ClassKind identifies which part of the system consumes a parameter.
+type ClassKind int
+
+const (
+	// ClassUnknown is for tokens that are not well-formed parameters, such
+	// as quoted keys or the "--" separator.
+	ClassUnknown ClassKind = iota
+
+	// ClassKernel is for parameters handled by the core kernel, like root=
+	// or quiet.
+	ClassKernel
+
+	// ClassModule is for parameters of a kernel module or built-in kernel
+	// subsystem, written as module.param.
+	ClassModule
+
+	// ClassSystemd is for parameters read by systemd and udev, like
+	// systemd.unit= or rd.udev.log_level=.
+	ClassSystemd
+
+	// ClassDracut is for parameters read by dracut in the initramfs, like
+	// rd.break or netroot=.
+	ClassDracut
+
+	// ClassInit is for parameters the kernel does not know and hence passes
+	// on to init, as arguments if they have no value or as environment
+	// variables if they do.
+	ClassInit
+)
+
+// String returns the name of kind.
+func (kind ClassKind) String() string {
+	switch kind {
+	case ClassKernel:
+		return "kernel"
+	case ClassModule:
+		return "module"
+	case ClassSystemd:
+		return "systemd"
+	case ClassDracut:
+		return "dracut"
+	case ClassInit:
+		return "init"
+	default:
+		return "unknown"
+	}
+}
+
+// Class describes which part of the system consumes a parameter, as returned by
+// Classify.
+type Class struct {
+	Kind   ClassKind // Consumer of the parameter
+	Module string    // Canonical module name if Kind is ClassModule
+}
+
+// String returns the name of the kind of c, followed by the module name in
+// parentheses for module parameters.
+func (c Class) String() string {
+	if c.Kind == ClassModule {
+		return c.Kind.String() + "(" + c.Module + ")"
+	}
+	return c.Kind.String()
+}
+
+// kernelKeys holds the canonical keys of well-known parameters handled by the
+// core kernel.
+var kernelKeys = map[string]bool{
+	"acpi":                 true,
+	"apic":                 true,
+	"apparmor":             true,
+	"audit":                true,
+	"cgroup_disable":       true,
+	"cgroup_enable":        true,
+	"console":              true,
+	"consoleblank":         true,
+	"crashkernel":          true,
+	"debug":                true,
+	"default_hugepagesz":   true,
+	"earlycon":             true,
+	"earlyprintk":          true,
+	"efi":                  true,
+	"elevator":             true,
+	"hugepages":            true,
+	"hugepagesz":           true,
+	"ignore_loglevel":      true,
+	"init":                 true,
+	"initcall_debug":       true,
+	"initrd":               true,
+	"intel_iommu":          true,
+	"iommu":                true,
+	"ip":                   true,
+	"isolcpus":             true,
+	"kgdboc":               true,
+	"lockdown":             true,
+	"loglevel":             true,
+	"lsm":                  true,
+	"maxcpus":              true,
+	"mem":                  true,
+	"memmap":               true,
+	"mitigations":          true,
+	"module_blacklist":     true,
+	"nfsroot":              true,
+	"noapic":               true,
+	"nohz":                 true,
+	"nohz_full":            true,
+	"nokaslr":              true,
+	"nolapic":              true,
+	"nomodeset":            true,
+	"nosmp":                true,
+	"nosmt":                true,
+	"nr_cpus":              true,
+	"numa":                 true,
+	"panic":                true,
+	"pci":                  true,
+	"quiet":                true,
+	"rcu_nocbs":            true,
+	"rdinit":               true,
+	"reboot":               true,
+	"resume":               true,
+	"ro":                   true,
+	"root":                 true,
+	"rootdelay":            true,
+	"rootflags":            true,
+	"rootfstype":           true,
+	"rootwait":             true,
+	"rw":                   true,
+	"security":             true,
+	"selinux":              true,
+	"swiotlb":              true,
+	"sysrq_always_enabled": true,
+	"transparent_hugepage": true,
+	"vga":                  true,
+	"video":                true,
+}
+
+// dracutKeys holds the canonical keys of parameters read by dracut that are not
+// prefixed with rd.
+var dracutKeys = map[string]bool{
+	"BOOTIF":     true,
+	"bootdev":    true,
+	"ifname":     true,
+	"nameserver": true,
+	"netroot":    true,
+}
+
+// systemdPrefixes holds the canonical key prefixes of parameters read by
+// systemd and udev. They must be checked before the dracut rd. prefix.
+var systemdPrefixes = []string{"systemd.", "rd.systemd.", "udev.", "rd.udev."}
+
+// Classify returns which part of the system consumes karg, judging by its key.
+// Core kernel parameters are recognized from a list of well-known ones, so
+// rarer ones are reported as ClassInit, like the parameters the kernel really
+// does not know.
+//
+// Classify only looks at karg itself. Parameters following a "--" separator
+// are always passed to init, which only a Kargs knows about; see View.
+func Classify(karg Karg) Class {
+	key := karg.CanonicalKey
+	if key == "" || karg.Key == "--" || strings.ContainsAny(key[:1], `"'`) {
+		return Class{Kind: ClassUnknown}
+	}
+	for _, prefix := range systemdPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return Class{Kind: ClassSystemd}
+		}
+	}
+	if strings.HasPrefix(key, "rd.") || dracutKeys[key] {
+		return Class{Kind: ClassDracut}
+	}
+	if mod, ok := moduleName(key); ok {
+		return Class{Kind: ClassModule, Module: mod}
+	}
+	if kernelKeys[key] {
+		return Class{Kind: ClassKernel}
+	}
+	return Class{Kind: ClassInit}
+}
+
+// View returns a new Kargs containing the parameters of k that belong to class,
+// in their original order. If class is for module parameters and names no
+// module, the parameters of all modules are included. Parameters following a
+// "--" separator are passed to init by the kernel, so they are only included in
+// views for ClassInit; separators themselves are ClassUnknown. Whether a quoted
+// "--" or a "--" following the first one counts as a separator is set by
+// WithQuotedSeparator and WithRepeatedSeparators. The returned Kargs is a copy,
+// so modifying it does not affect k.
+func (k *Kargs) View(class Class) *Kargs {
+	afterSeparator := false
+	return k.filter(func(karg Karg) bool {
+		separator := k.cfg.isSeparator(karg, afterSeparator)
+		if afterSeparator && !separator {
+			return class.Kind == ClassInit
+		}
+		afterSeparator = afterSeparator || separator
+		c := Classify(karg)
+		if c.Kind != class.Kind {
+			return false
+		}
+		return class.Module == "" || c.Module == canonicalizeKey(class.Module)
+	})
+}
+
+// isSeparator returns whether karg separates kernel parameters from the
+// arguments passed to init, given whether a separator has been seen before.
+// A bare "--" always separates, unless it follows another separator and
+// repeated separators are taken literally. A "--" in double quotes only
+// separates if cfg says so.
+func (cfg parseConfig) isSeparator(karg Karg, afterSeparator bool) bool {
+	if afterSeparator && !cfg.repeatedSeparators {
+		return false
+	}
+	if karg.Raw != karg.Key {
+		return false
+	}
+	if karg.Key == "--" {
+		return true
+	}
+	return cfg.quotedSeparator && karg.Key == `"--"`
+}
@@ -0,0 +1,115 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	checks := []struct {
+		in   string
+		want Class
+	}{
+		{in: "root=/dev/sda1", want: Class{Kind: ClassKernel}},
+		{in: "nohz-full=1-3", want: Class{Kind: ClassKernel}},
+		{in: "nvme_core.multipath=Y", want: Class{Kind: ClassModule, Module: "nvme_core"}},
+		{in: "nvme-core.multipath=Y", want: Class{Kind: ClassModule, Module: "nvme_core"}},
+		{in: "systemd.unit=rescue.target", want: Class{Kind: ClassSystemd}},
+		{in: "rd.systemd.unit=emergency.target", want: Class{Kind: ClassSystemd}},
+		{in: "rd.udev.log_level=3", want: Class{Kind: ClassSystemd}},
+		{in: "rd.break", want: Class{Kind: ClassDracut}},
+		{in: "rd.neednet=1", want: Class{Kind: ClassDracut}},
+		{in: "BOOTIF=01-aa-bb-cc-dd-ee-ff", want: Class{Kind: ClassDracut}},
+		{in: "splash", want: Class{Kind: ClassInit}},
+		{in: "BOOT_IMAGE=/vmlinuz", want: Class{Kind: ClassInit}},
+		{in: `"key quotes"`, want: Class{Kind: ClassUnknown}},
+		{in: "--", want: Class{Kind: ClassUnknown}},
+		{in: "=val", want: Class{Kind: ClassUnknown}},
+	}
+	for _, check := range checks {
+		k := NewKargs([]byte(check.in))
+		assert.Equal(t, check.want, Classify(k.list.karg), "input %q", check.in)
+	}
+}
+
+func TestClass_String(t *testing.T) {
+	assert.Equal(t, "kernel", Class{Kind: ClassKernel}.String())
+	assert.Equal(t, "module(nvme_core)", Class{Kind: ClassModule, Module: "nvme_core"}.String())
+	assert.Equal(t, "unknown", ClassKind(42).String())
+}
+
+func TestKargs_View(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 systemd.unit=rescue.target nvme_core.multipath=Y rd.break splash i915.modeset=0 quiet -- single systemd.log_level=debug"))
+
+	checks := []struct {
+		class Class
+		want  string
+	}{
+		{class: Class{Kind: ClassKernel}, want: "root=/dev/sda1 quiet"},
+		{class: Class{Kind: ClassSystemd}, want: "systemd.unit=rescue.target"},
+		{class: Class{Kind: ClassModule}, want: "nvme_core.multipath=Y i915.modeset=0"},
+		{class: Class{Kind: ClassModule, Module: "nvme-core"}, want: "nvme_core.multipath=Y"},
+		{class: Class{Kind: ClassDracut}, want: "rd.break"},
+		{class: Class{Kind: ClassInit}, want: "splash single systemd.log_level=debug"},
+		{class: Class{Kind: ClassUnknown}, want: "--"},
+	}
+	for _, check := range checks {
+		assert.Equal(t, check.want, k.View(check.class).String(), "class %s", check.class)
+	}
+
+	// Modifying the view does not affect the original
+	view := k.View(Class{Kind: ClassKernel})
+	err := view.DeleteKarg("root")
+	assert.NoError(t, err)
+	assert.True(t, k.ContainsKarg("root"))
+}
+
+func TestKargs_View_separators(t *testing.T) {
+	const cmdline = `root=/dev/sda1 '--' "--" quiet -- single -- emergency`
+
+	checks := []struct {
+		name string
+		opts []ParseOption
+		kern string
+		init string
+		unkn string
+	}{
+		{
+			name: "default",
+			kern: "root=/dev/sda1 quiet",
+			init: "single -- emergency",
+			unkn: `'--' "--" --`,
+		},
+		{
+			name: "quoted separator",
+			opts: []ParseOption{WithQuotedSeparator()},
+			kern: "root=/dev/sda1",
+			init: "quiet -- single -- emergency",
+			unkn: `'--' "--"`,
+		},
+		{
+			name: "repeated separators",
+			opts: []ParseOption{WithRepeatedSeparators()},
+			kern: "root=/dev/sda1 quiet",
+			init: "single emergency",
+			unkn: `'--' "--" -- --`,
+		},
+		{
+			name: "both",
+			opts: []ParseOption{WithQuotedSeparator(), WithRepeatedSeparators()},
+			kern: "root=/dev/sda1",
+			init: "quiet single emergency",
+			unkn: `'--' "--" -- --`,
+		},
+	}
+	for _, check := range checks {
+		k := NewKargs([]byte(cmdline), check.opts...)
+		assert.Equal(t, check.init, k.View(Class{Kind: ClassInit}).String(), check.name)
+		assert.Equal(t, check.unkn, k.View(Class{Kind: ClassUnknown}).String(), check.name)
+		assert.Equal(t, check.kern, k.View(Class{Kind: ClassKernel}).String(), check.name)
+	}
+}
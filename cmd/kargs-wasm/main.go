@@ -0,0 +1,133 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+//go:build js && wasm
+
+// Command kargs-wasm exposes the parser of package kargs to JavaScript, so that
+// web UIs editing boot configuration handle command lines exactly like the Go
+// tools do. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o kargs.wasm ./cmd/kargs-wasm
+//
+// and load it with the wasm_exec.js shipped with Go, found in
+// $(go env GOROOT)/lib/wasm (misc/wasm before Go 1.24). Once running, it defines
+// a global kargs object whose functions all take a command line string and
+// return results without keeping any state:
+//
+//	kargs.parse(cmdline)              // [{key, canonicalKey, value, raw}, ...]
+//	kargs.check(cmdline)              // [{raw, start, end, code, message}, ...]
+//	kargs.get(cmdline, key)           // [value, ...], or null if key is unset
+//	kargs.set(cmdline, key, value)    // new command line
+//	kargs.append(cmdline, key, value) // new command line
+//	kargs.delete(cmdline, key)        // new command line
+//	kargs.quote(value)                // value quoted for use in a command line
+//
+// Functions that fail, e.g. because of an invalid key, return an Error object
+// instead of their result. They can't throw it, since an exception unwinding
+// through the Go frames would stop the program.
+package main
+
+import (
+	"syscall/js"
+
+	kargs "github.com/synackd/go-kargs"
+)
+
+func main() {
+	js.Global().Set("kargs", js.ValueOf(map[string]any{
+		"parse":  js.FuncOf(parse),
+		"check":  js.FuncOf(check),
+		"get":    js.FuncOf(get),
+		"set":    js.FuncOf(edit((*kargs.Kargs).SetKarg)),
+		"append": js.FuncOf(edit((*kargs.Kargs).AppendKarg)),
+		"delete": js.FuncOf(deleteKarg),
+		"quote":  js.FuncOf(quote),
+	}))
+	// Keep the functions callable.
+	select {}
+}
+
+// parse returns the kargs of the command line args[0].
+func parse(_ js.Value, args []js.Value) any {
+	k := kargs.NewKargs([]byte(stringArg(args, 0)))
+	list := make([]any, 0, k.Len())
+	for karg := range k.All() {
+		list = append(list, map[string]any{
+			"key":          karg.Key,
+			"canonicalKey": karg.CanonicalKey,
+			"value":        karg.Value,
+			"raw":          karg.Raw,
+		})
+	}
+	return list
+}
+
+// check returns the problems found in the command line args[0] by
+// kargs.ParseStrictAll.
+func check(_ js.Value, args []js.Value) any {
+	_, issues := kargs.ParseStrictAll([]byte(stringArg(args, 0)))
+	list := make([]any, 0, len(issues))
+	for _, issue := range issues {
+		list = append(list, map[string]any{
+			"raw":     issue.Raw,
+			"start":   issue.Start,
+			"end":     issue.End,
+			"code":    string(issue.Code()),
+			"message": issue.Err.Error(),
+		})
+	}
+	return list
+}
+
+// get returns the values of the key args[1] in the command line args[0].
+func get(_ js.Value, args []js.Value) any {
+	values, present := kargs.NewKargs([]byte(stringArg(args, 0))).GetKarg(stringArg(args, 1))
+	if !present {
+		return nil
+	}
+	list := make([]any, len(values))
+	for i, value := range values {
+		list[i] = value
+	}
+	return list
+}
+
+// edit returns a function applying fn with the key args[1] and value args[2] to
+// the command line args[0] and returning the result.
+func edit(fn func(k *kargs.Kargs, key, value string) error) func(js.Value, []js.Value) any {
+	return func(_ js.Value, args []js.Value) any {
+		k := kargs.NewKargs([]byte(stringArg(args, 0)))
+		if err := fn(k, stringArg(args, 1), stringArg(args, 2)); err != nil {
+			return jsError(err)
+		}
+		return k.String()
+	}
+}
+
+// deleteKarg deletes the key args[1] from the command line args[0] and returns
+// the result.
+func deleteKarg(_ js.Value, args []js.Value) any {
+	k := kargs.NewKargs([]byte(stringArg(args, 0)))
+	if err := k.DeleteKarg(stringArg(args, 1)); err != nil {
+		return jsError(err)
+	}
+	return k.String()
+}
+
+// quote returns args[0] quoted by kargs.QuoteValue.
+func quote(_ js.Value, args []js.Value) any {
+	return kargs.QuoteValue(stringArg(args, 0))
+}
+
+// stringArg returns args[i] as a string, or an empty string if it is missing.
+func stringArg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
+
+// jsError returns err as a JavaScript Error.
+func jsError(err error) any {
+	return js.Global().Get("Error").New(err.Error())
+}
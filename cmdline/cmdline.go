@@ -0,0 +1,167 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+// Package cmdline mirrors the API of u-root's pkg/cmdline on top of package
+// kargs, so that projects using u-root's package can switch to kargs by
+// changing their imports. Like the original, the package-level functions read
+// the command line of the running kernel from /proc/cmdline once, on first use.
+package cmdline
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	kargs "github.com/synackd/go-kargs"
+)
+
+// CmdLine is a parsed kernel command line.
+type CmdLine struct {
+	Raw   string            // Command line as read
+	AsMap map[string]string // Canonical keys mapped to dequoted values; the last occurrence wins
+	Err   error             // Error reading the command line, if any
+
+	k *kargs.Kargs
+}
+
+// procCmdline is the file the command line of the running kernel is read from.
+var procCmdline = "/proc/cmdline"
+
+var (
+	once    sync.Once
+	procCmd *CmdLine
+)
+
+// cmdLine returns the command line of the running kernel, reading it on first
+// use.
+func cmdLine() *CmdLine {
+	once.Do(func() {
+		procCmd = NewCmdLine()
+	})
+	return procCmd
+}
+
+// NewCmdLine reads and parses the command line of the running kernel. Errors
+// are recorded in the Err field of the result.
+func NewCmdLine() *CmdLine {
+	f, err := os.Open(procCmdline)
+	if err != nil {
+		return &CmdLine{Err: err, AsMap: map[string]string{}, k: kargs.NewKargsEmpty()}
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// parse reads a command line from r and parses it.
+func parse(r io.Reader) *CmdLine {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return &CmdLine{Err: fmt.Errorf("reading command line: %w", err), AsMap: map[string]string{}, k: kargs.NewKargsEmpty()}
+	}
+	line := strings.TrimRight(string(raw), "\n")
+	k := kargs.NewKargs([]byte(line))
+	return &CmdLine{Raw: line, AsMap: toMap(k), k: k}
+}
+
+// toMap maps the canonical keys of k to their dequoted values, with later
+// occurrences overriding earlier ones.
+func toMap(k *kargs.Kargs) map[string]string {
+	m := make(map[string]string, k.Len())
+	for karg := range k.All() {
+		m[karg.CanonicalKey] = karg.Value
+	}
+	return m
+}
+
+// FullCmdLine returns the command line of the running kernel as read.
+func FullCmdLine() string {
+	return cmdLine().Raw
+}
+
+// ContainsFlag reports whether the command line of the running kernel contains
+// flag.
+func ContainsFlag(flag string) bool {
+	return cmdLine().ContainsFlag(flag)
+}
+
+// Flag returns the value of flag on the command line of the running kernel and
+// whether it is present.
+func Flag(flag string) (string, bool) {
+	return cmdLine().Flag(flag)
+}
+
+// GetInitFlagMap returns the flags passed to init in uroot.initflags on the
+// command line of the running kernel.
+func GetInitFlagMap() map[string]string {
+	return cmdLine().GetInitFlagMap()
+}
+
+// GetUinitArgs returns the arguments passed to uinit in uroot.uinitargs on the
+// command line of the running kernel.
+func GetUinitArgs() []string {
+	return cmdLine().GetUinitArgs()
+}
+
+// Consoles returns the values of all console= flags on the command line of the
+// running kernel, in order.
+func Consoles() []string {
+	return cmdLine().Consoles()
+}
+
+// FlagsForModule returns the flags for the module identified by name on the
+// command line of the running kernel, ready to be passed to insmod.
+func FlagsForModule(name string) string {
+	return cmdLine().FlagsForModule(name)
+}
+
+// ContainsFlag reports whether c contains flag.
+func (c *CmdLine) ContainsFlag(flag string) bool {
+	return c.k.ContainsKarg(flag)
+}
+
+// Flag returns the value of flag and whether it is present. If flag occurs more
+// than once, the value of the last occurrence is returned.
+func (c *CmdLine) Flag(flag string) (string, bool) {
+	values, ok := c.k.GetKarg(flag)
+	if !ok {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetInitFlagMap returns the flags passed to init in uroot.initflags, which
+// holds a command line of its own.
+func (c *CmdLine) GetInitFlagMap() map[string]string {
+	initflags, _ := c.Flag("uroot.initflags")
+	return toMap(kargs.NewKargs([]byte(initflags)))
+}
+
+// GetUinitArgs returns the arguments passed to uinit in uroot.uinitargs, split
+// at whitespace outside of quotes, with quotes removed.
+func (c *CmdLine) GetUinitArgs() []string {
+	uinitargs, _ := c.Flag("uroot.uinitargs")
+	var args []string
+	kargs.Tokenize(uinitargs, func(t kargs.Token) error {
+		arg, err := kargs.UnquoteValue(t.Raw)
+		if err != nil {
+			arg = t.Raw
+		}
+		args = append(args, arg)
+		return nil
+	})
+	return args
+}
+
+// Consoles returns the values of all console= flags, in order.
+func (c *CmdLine) Consoles() []string {
+	consoles, _ := c.k.GetKarg("console")
+	return consoles
+}
+
+// FlagsForModule returns the flags for the module identified by name, ready to
+// be passed to insmod.
+func (c *CmdLine) FlagsForModule(name string) string {
+	return c.k.FlagsForModule(name)
+}
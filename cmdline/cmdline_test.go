@@ -0,0 +1,105 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package cmdline
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCmdline = `root=/dev/sda1 console=tty0 console=ttyS0,115200 rd.break quiet=1 quiet=0 ` +
+	`uroot.initflags="systemd test-flag=3" ` +
+	`uroot.uinitargs="-v --config 'a b'" usb-storage.quirks=0bc2:2320:u usb_storage.delay_use=2` + "\n"
+
+func TestParse(t *testing.T) {
+	c := parse(strings.NewReader(testCmdline))
+	if c.Err != nil {
+		t.Fatalf("unexpected error: %v", c.Err)
+	}
+	if c.Raw != strings.TrimSuffix(testCmdline, "\n") {
+		t.Errorf("Raw = %q", c.Raw)
+	}
+	for flag, want := range map[string]string{
+		"root":  "/dev/sda1",
+		"quiet": "0",
+	} {
+		if got := c.AsMap[flag]; got != want {
+			t.Errorf("AsMap[%q] = %q, want %q", flag, got, want)
+		}
+	}
+}
+
+func TestCmdLineFlag(t *testing.T) {
+	c := parse(strings.NewReader(testCmdline))
+	tests := []struct {
+		flag    string
+		want    string
+		present bool
+	}{
+		{flag: "root", want: "/dev/sda1", present: true},
+		{flag: "quiet", want: "0", present: true},
+		{flag: "rd.break", want: "", present: true},
+		{flag: "usb_storage.quirks", want: "0bc2:2320:u", present: true},
+		{flag: "nosuchflag", want: "", present: false},
+	}
+	for _, tt := range tests {
+		got, present := c.Flag(tt.flag)
+		if got != tt.want || present != tt.present {
+			t.Errorf("Flag(%q) = %q, %v, want %q, %v", tt.flag, got, present, tt.want, tt.present)
+		}
+		if contains := c.ContainsFlag(tt.flag); contains != tt.present {
+			t.Errorf("ContainsFlag(%q) = %v, want %v", tt.flag, contains, tt.present)
+		}
+	}
+}
+
+func TestCmdLineGetInitFlagMap(t *testing.T) {
+	c := parse(strings.NewReader(testCmdline))
+	want := map[string]string{
+		"systemd":   "",
+		"test_flag": "3",
+	}
+	if got := c.GetInitFlagMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetInitFlagMap() = %v, want %v", got, want)
+	}
+}
+
+func TestCmdLineGetUinitArgs(t *testing.T) {
+	c := parse(strings.NewReader(testCmdline))
+	want := []string{"-v", "--config", "a b"}
+	if got := c.GetUinitArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetUinitArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestCmdLineConsoles(t *testing.T) {
+	c := parse(strings.NewReader(testCmdline))
+	want := []string{"tty0", "ttyS0,115200"}
+	if got := c.Consoles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Consoles() = %q, want %q", got, want)
+	}
+}
+
+func TestCmdLineFlagsForModule(t *testing.T) {
+	c := parse(strings.NewReader(testCmdline))
+	want := "quirks=0bc2:2320:u delay_use=2"
+	if got := c.FlagsForModule("usb-storage"); got != want {
+		t.Errorf("FlagsForModule() = %q, want %q", got, want)
+	}
+}
+
+func TestNewCmdLine_missing(t *testing.T) {
+	defer func(path string) { procCmdline = path }(procCmdline)
+	procCmdline = "/nonexistent/cmdline"
+	c := NewCmdLine()
+	if !errors.Is(c.Err, fs.ErrNotExist) {
+		t.Errorf("Err = %v, want %v", c.Err, fs.ErrNotExist)
+	}
+	if c.ContainsFlag("root") {
+		t.Error("ContainsFlag(root) = true on empty command line")
+	}
+}
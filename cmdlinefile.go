@@ -0,0 +1,212 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// CmdlineFile is a hand-maintained file holding kernel command line
+// parameters, like /etc/kernel/cmdline or a file in a cmdline.d directory,
+// together with the comments in it. Parameters may span several lines. A #
+// at the start of a line or of a parameter, i.e. after whitespace and outside
+// of quotes, starts a comment reaching to the end of the line, so that
+// operators can annotate parameters:
+//
+//	# Serial console for the BMC
+//	console=ttyS0,115200n8 # keep in sync with the BIOS setting
+//	quiet
+//
+// The parameters are edited through Kargs; WriteTo then writes them back with
+// the comments in place. Comments are tied to the line of parameters they
+// annotate: full-line comments and blank lines to the line following them,
+// trailing comments to the line they end. Each parameter remembers the line it
+// was read from, wherever it is moved, and replacing its value, e.g. with
+// SetKarg, keeps it on its line. Lines are kept as long as any of their
+// parameters is left, and their comments are dropped along with the last one.
+// New parameters are written on the line of the parameter before them.
+// Comments before the first and after the last line holding parameters belong
+// to the file and are always kept.
+type CmdlineFile struct {
+	Kargs *Kargs // Parameters of the file
+
+	header  []string // Lines before the first parameter
+	trailer []string // Lines after the last parameter
+}
+
+// cmdlineLine holds the comments tied to a line of a CmdlineFile.
+type cmdlineLine struct {
+	before  []string // Comment and blank lines before the line
+	comment string   // Comment at the end of the line, including the #
+}
+
+// ReadCmdlineFile reads a CmdlineFile from r and parses its parameters like
+// ParseKargs with opts, as one command line, so limits apply to the whole file
+// and offsets count from its start. If parsing fails, e.g. because a limit is
+// exceeded, the parameters before the offending one are kept, and the error is
+// returned as a LineErrors holding the line it occurred in.
+func ReadCmdlineFile(r io.Reader, opts ...ParseOption) (*CmdlineFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading cmdline file: %w", err)
+	}
+	f := &CmdlineFile{Kargs: NewKargsEmpty(opts...)}
+	text := strings.TrimSuffix(string(data), "\n")
+	rawLines := strings.Split(text, "\n")
+	if text == "" {
+		rawLines = nil
+	}
+
+	// Parse all lines at once, with comments blanked out so that offsets
+	// stay the same.
+	var (
+		params     = []byte(text)
+		comments   = make([]string, len(rawLines))
+		lineStarts = make([]int, len(rawLines))
+		hasParams  = make([]bool, len(rawLines))
+		offset     int
+	)
+	for idx, raw := range rawLines {
+		lineStarts[idx] = offset
+		lineParams, comment := f.Kargs.cfg.splitComment(raw)
+		hasParams[idx] = strings.TrimSpace(lineParams) != ""
+		if comment != "" {
+			comments[idx] = strings.TrimRight(comment, " \t\r")
+			for i := offset + len(raw) - len(comment); i < offset+len(raw); i++ {
+				params[i] = ' '
+			}
+		}
+		offset += len(raw) + 1
+	}
+	lineOf := func(offset int) int {
+		idx, found := slices.BinarySearch(lineStarts, offset)
+		if !found {
+			idx--
+		}
+		return idx
+	}
+	lines := make([]*cmdlineLine, len(rawLines))
+	var errs LineErrors
+	err = f.Kargs.appendParsed(string(params), func(t Token, item *kargItem) {
+		idx := lineOf(t.Start)
+		if lines[idx] == nil {
+			lines[idx] = &cmdlineLine{comment: comments[idx]}
+		}
+		item.line = lines[idx]
+	})
+	if err != nil {
+		lineNo := 0
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			lineNo = lineOf(limitErr.Offset) + 1
+		}
+		errs = append(errs, &LineError{Line: lineNo, Err: err})
+	}
+
+	// Tie comment and blank lines to the line following them. Lines holding
+	// parameters that weren't parsed are dropped.
+	var pending []string
+	first := true
+	for idx, raw := range rawLines {
+		raw = strings.TrimRight(raw, " \t\r")
+		switch {
+		case lines[idx] != nil:
+			if first {
+				f.header, first = pending, false
+			} else {
+				lines[idx].before = pending
+			}
+			pending = nil
+		case !hasParams[idx]:
+			pending = append(pending, raw)
+		}
+	}
+	if f.Kargs.Len() == 0 {
+		f.header = pending
+	} else {
+		f.trailer = pending
+	}
+	if len(errs) > 0 {
+		return f, errs
+	}
+	return f, nil
+}
+
+// splitComment splits line into the part holding parameters and the comment
+// following it, if any, starting at the first token that starts with #.
+func (cfg parseConfig) splitComment(line string) (params, comment string) {
+	params = line
+	cfg.tokenize(line, func(t Token) error {
+		if strings.HasPrefix(t.Raw, "#") {
+			params, comment = strings.TrimRight(line[:t.Start], " \t"), line[t.Start:]
+			return errStop
+		}
+		return nil
+	})
+	return params, comment
+}
+
+// WriteTo writes f to w, with the current parameters of f.Kargs in command
+// line order and the comments tied to them in place. Parameters are separated
+// by a single space, and lines end with a newline. It implements io.WriterTo.
+func (f *CmdlineFile) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, f.String())
+	return int64(n), err
+}
+
+// String returns the contents of f as written by WriteTo.
+func (f *CmdlineFile) String() string {
+	var (
+		sb      strings.Builder
+		cur     *cmdlineLine // Line the last karg written belongs to
+		open    bool         // Whether a line has been started but not ended
+		started = make(map[*cmdlineLine]bool)
+		ended   = make(map[*cmdlineLine]bool)
+	)
+	endLine := func() {
+		if cur != nil && cur.comment != "" && !ended[cur] {
+			sb.WriteByte(' ')
+			sb.WriteString(cur.comment)
+		}
+		ended[cur] = true
+		sb.WriteByte('\n')
+		open = false
+	}
+
+	writeLines(&sb, f.header)
+	for item := f.Kargs.list; item != nil; item = item.next {
+		if line := item.line; line != nil && line != cur {
+			if open {
+				endLine()
+			}
+			if !started[line] {
+				writeLines(&sb, line.before)
+				started[line] = true
+			}
+			cur = line
+		}
+		if open {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(item.karg.Raw)
+		open = true
+	}
+	if open {
+		endLine()
+	}
+	writeLines(&sb, f.trailer)
+	return sb.String()
+}
+
+// writeLines writes each of lines to sb, followed by a newline.
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+}
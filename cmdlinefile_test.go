@@ -0,0 +1,119 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCmdlineFile = `# Managed by hand, see the runbook
+
+# Serial console for the BMC
+console=ttyS0,115200n8   # keep in sync with the BIOS setting
+root=/dev/sda1 ro
+  # Tracing
+trace_buf_size=1M init="/sbin/init --log #1" #debugging only
+quiet
+
+# end
+`
+
+func TestReadCmdlineFile(t *testing.T) {
+	f, err := ReadCmdlineFile(strings.NewReader(testCmdlineFile))
+	assert.NoError(t, err)
+	assert.Equal(t, `console=ttyS0,115200n8 root=/dev/sda1 ro trace_buf_size=1M init="/sbin/init --log #1" quiet`, f.Kargs.String())
+
+	// Unchanged files are written back as read, with whitespace normalized.
+	assert.Equal(t, strings.Replace(testCmdlineFile, "   #", " #", 1), f.String())
+
+	assert.NoError(t, f.Kargs.ReplaceAll("console", "tty0"))
+	assert.NoError(t, f.Kargs.DeleteKarg("trace_buf_size"))
+	assert.NoError(t, f.Kargs.DeleteKarg("quiet"))
+	assert.NoError(t, f.Kargs.AppendKarg("rd.break", ""))
+	var sb strings.Builder
+	n, err := f.WriteTo(&sb)
+	assert.NoError(t, err)
+	assert.Equal(t, `# Managed by hand, see the runbook
+
+# Serial console for the BMC
+console=tty0 # keep in sync with the BIOS setting
+root=/dev/sda1 ro
+  # Tracing
+init="/sbin/init --log #1" rd.break #debugging only
+
+# end
+`, sb.String())
+	assert.EqualValues(t, sb.Len(), n)
+}
+
+func TestReadCmdlineFile_edgeCases(t *testing.T) {
+	f, err := ReadCmdlineFile(strings.NewReader("# only comments\n\n"))
+	assert.NoError(t, err)
+	assert.Zero(t, f.Kargs.Len())
+	assert.Equal(t, "# only comments\n\n", f.String())
+	assert.NoError(t, f.Kargs.AppendKarg("quiet", ""))
+	assert.Equal(t, "# only comments\n\nquiet\n", f.String())
+
+	// Comments stay with their line, and go with its last karg.
+	f, err = ReadCmdlineFile(strings.NewReader("a b # ab\n# note\nc d # cd\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Kargs.DeleteKarg("a"))
+	assert.NoError(t, f.Kargs.PrependKarg("z", ""))
+	assert.NoError(t, f.Kargs.MoveKarg("d", 1))
+	assert.Equal(t, "z\n# note\nd # cd\nb # ab\nc\n", f.String())
+	assert.NoError(t, f.Kargs.DeleteKargs("b", "c", "d"))
+	assert.Equal(t, "z\n", f.String())
+
+}
+
+func TestReadCmdlineFile_deleteAnnotated(t *testing.T) {
+	f, err := ReadCmdlineFile(strings.NewReader(`# BMC consoles
+console=tty0 # local VGA, drop on headless
+# Serial for SOL
+console=ttyS0,115200 # must match BIOS
+quiet
+`))
+	assert.NoError(t, err)
+
+	// Comments stay with the parameters they annotate.
+	assert.NoError(t, f.Kargs.DeleteKargByValue("console", "tty0"))
+	assert.Equal(t, `# BMC consoles
+# Serial for SOL
+console=ttyS0,115200 # must match BIOS
+quiet
+`, f.String())
+
+	assert.NoError(t, f.Kargs.SetKargPosition("console", "ttyS1,115200", SetKeepLast))
+	assert.NoError(t, f.Kargs.MoveKarg("quiet", 0))
+	assert.Equal(t, `# BMC consoles
+quiet
+# Serial for SOL
+console=ttyS1,115200 # must match BIOS
+`, f.String())
+}
+
+func TestReadCmdlineFile_wholeFile(t *testing.T) {
+	// Limits apply to the whole file, not to each line.
+	f, err := ReadCmdlineFile(strings.NewReader("a\n# b\nb\nc\nd\n"), WithMaxParams(2))
+	var lineErrs LineErrors
+	if assert.ErrorAs(t, err, &lineErrs) {
+		assert.Equal(t, []int{4}, lineErrs.Lines())
+	}
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, "a b", f.Kargs.String())
+	assert.Equal(t, "a\n# b\nb\n", f.String())
+
+	// Offsets count from the start of the file.
+	var offsets []int
+	_, err = ReadCmdlineFile(strings.NewReader("a # x\n\n  b=1 c\n"), WithTrace(func(e TraceEvent) {
+		if e.Kind == TraceToken {
+			offsets = append(offsets, e.Offset)
+		}
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 9, 13}, offsets)
+}
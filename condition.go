@@ -0,0 +1,204 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Condition is a boolean expression over facts, i.e. a map of names to values
+// describing the machine, deciding whether a karg is included when resolving a
+// template with Resolve. Create it with ParseCondition.
+//
+// The expression consists of comparisons of a fact with a string, like
+// facts["gpu"] == "nvidia" or gpu != "none", and facts on their own, which are
+// true if they are set to a non-empty value. Facts can be written either as
+// facts["name"] or as name if it consists of letters, digits, and underscores.
+// Strings are double-quoted like in Go. Expressions can be combined with !, &&,
+// and || and grouped with parentheses; && binds tighter than ||.
+type Condition struct {
+	expr string
+	root condNode
+}
+
+// condNode is a node of the syntax tree of a Condition.
+type condNode interface {
+	eval(facts map[string]string) bool
+}
+
+type (
+	condNot struct{ x condNode }
+	condAnd struct{ x, y condNode }
+	condOr  struct{ x, y condNode }
+	condSet struct{ fact string }
+	condCmp struct {
+		fact, value string
+		negate      bool
+	}
+)
+
+func (n condNot) eval(facts map[string]string) bool { return !n.x.eval(facts) }
+func (n condAnd) eval(facts map[string]string) bool { return n.x.eval(facts) && n.y.eval(facts) }
+func (n condOr) eval(facts map[string]string) bool  { return n.x.eval(facts) || n.y.eval(facts) }
+func (n condSet) eval(facts map[string]string) bool { return facts[n.fact] != "" }
+func (n condCmp) eval(facts map[string]string) bool { return (facts[n.fact] == n.value) != n.negate }
+
+// ParseCondition parses expr as described in Condition. An error wrapping
+// ErrInvalidCondition is returned if expr is malformed.
+func ParseCondition(expr string) (*Condition, error) {
+	p := condParser{exprLexer{input: expr, what: "condition", err: ErrInvalidCondition}}
+	root, err := p.parseOr()
+	if err == nil && p.skipSpace() < len(p.input) {
+		err = p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Condition{expr: expr, root: root}, nil
+}
+
+// Eval returns whether c holds for facts.
+func (c *Condition) Eval(facts map[string]string) bool {
+	return c.root.eval(facts)
+}
+
+// String returns the expression c was parsed from.
+func (c *Condition) String() string {
+	return c.expr
+}
+
+// condParser is a recursive descent parser for conditions.
+type condParser struct {
+	exprLexer
+}
+
+func (p *condParser) parseOr() (condNode, error) {
+	x, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var y condNode
+		if y, err = p.parseAnd(); err == nil {
+			x = condOr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *condParser) parseAnd() (condNode, error) {
+	x, err := p.parseUnary()
+	for err == nil && p.accept("&&") {
+		var y condNode
+		if y, err = p.parseUnary(); err == nil {
+			x = condAnd{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *condParser) parseUnary() (condNode, error) {
+	switch {
+	case p.accept("!="):
+		return nil, p.errorf("unexpected !=")
+	case p.accept("!"):
+		x, err := p.parseUnary()
+		return condNot{x}, err
+	case p.accept("("):
+		x, err := p.parseOr()
+		if err == nil && !p.accept(")") {
+			err = p.errorf("missing )")
+		}
+		return x, err
+	}
+	fact, err := p.parseFact()
+	if err != nil {
+		return nil, err
+	}
+	negate := false
+	switch {
+	case p.accept("=="):
+	case p.accept("!="):
+		negate = true
+	default:
+		return condSet{fact}, nil
+	}
+	value, err := p.parseString()
+	return condCmp{fact: fact, value: value, negate: negate}, err
+}
+
+// parseFact parses a fact written as facts["name"] or name.
+func (p *condParser) parseFact() (string, error) {
+	if p.accept("facts[") {
+		name, err := p.parseString()
+		if err == nil && !p.accept("]") {
+			err = p.errorf("missing ]")
+		}
+		return name, err
+	}
+	name := p.scanWhile(isWordByte)
+	if name == "" {
+		return "", p.errorf("expected fact")
+	}
+	return name, nil
+}
+
+// parseString parses a double-quoted string.
+func (p *condParser) parseString() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) || p.input[p.pos] != '"' {
+		return "", p.errorf("expected string")
+	}
+	end := p.pos + 1
+	for end < len(p.input) && p.input[end] != '"' {
+		if p.input[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(p.input) {
+		return "", p.errorf("unterminated string")
+	}
+	s, err := strconv.Unquote(p.input[p.pos : end+1])
+	if err != nil {
+		return "", p.errorf("invalid string")
+	}
+	p.pos = end + 1
+	return s, nil
+}
+
+// Resolve returns a new Kargs holding the kargs of k whose condition, set with
+// SetCondition, holds for facts, along with all kargs without a condition, in
+// their original order. The result has no conditions, so it is the concrete
+// command line for the machine described by facts.
+func (k *Kargs) Resolve(facts map[string]string) *Kargs {
+	resolved := NewKargsEmpty()
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if llTracker.cond == nil || llTracker.cond.Eval(facts) {
+			resolved.appendItem(llTracker.karg)
+		}
+	}
+	return resolved
+}
+
+// SetCondition attaches the condition expr, as described in Condition, to all
+// occurrences of key, so that Resolve only includes them if it holds. An empty
+// expr removes the condition. Like the mark set by MarkOneShot, the condition is
+// kept in memory only and is lost if the karg is replaced. An error is
+// returned if key is not set or expr is malformed.
+func (k *Kargs) SetCondition(key, expr string) error {
+	occurrences := k.keyMap[canonicalizeKey(key)]
+	if len(occurrences) == 0 {
+		return fmt.Errorf("failed to set condition for key %s: %w", key, ErrNotExists)
+	}
+	var cond *Condition
+	if expr != "" {
+		var err error
+		if cond, err = ParseCondition(expr); err != nil {
+			return err
+		}
+	}
+	for _, ptr := range occurrences {
+		ptr.cond = cond
+	}
+	return nil
+}
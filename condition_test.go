@@ -0,0 +1,75 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCondition(t *testing.T) {
+	facts := map[string]string{"gpu": "nvidia", "arch": "x86_64", "role": ""}
+	tests := map[string]bool{
+		`facts["gpu"] == "nvidia"`:             true,
+		`facts["gpu"]=="amd"`:                  false,
+		`gpu != "amd"`:                         true,
+		`gpu`:                                  true,
+		`role`:                                 false,
+		`missing`:                              false,
+		`!missing`:                             true,
+		`gpu == "nvidia" && arch == "aarch64"`: false,
+		`gpu == "amd" || arch == "x86_64"`:     true,
+		`gpu == "amd" || arch == "x86_64" && role`:        false,
+		`(gpu == "amd" || arch == "x86_64") && !role`:     true,
+		`facts["with space"] == "a \"quoted\" value"`:     false,
+		`!(gpu == "nvidia") || facts["arch"] == "x86_64"`: true,
+	}
+	for expr, want := range tests {
+		cond, err := ParseCondition(expr)
+		if assert.NoError(t, err, expr) {
+			assert.Equal(t, want, cond.Eval(facts), expr)
+			assert.Equal(t, expr, cond.String())
+		}
+	}
+
+	for _, expr := range []string{
+		``,
+		`gpu ==`,
+		`gpu == nvidia`,
+		`gpu == "nvidia`,
+		`(gpu`,
+		`facts["gpu" == "x"`,
+		`gpu && `,
+		`gpu "x"`,
+		`!= "x"`,
+		`gpu = "x"`,
+	} {
+		_, err := ParseCondition(expr)
+		assert.ErrorIs(t, err, ErrInvalidCondition, expr)
+	}
+}
+
+func TestKargs_Resolve(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 nvidia-drm.modeset=1 rd.driver.blacklist=nouveau amdgpu.dc=1 quiet"))
+	assert.NoError(t, k.SetCondition("nvidia_drm.modeset", `facts["gpu"] == "nvidia"`))
+	assert.NoError(t, k.SetCondition("rd.driver.blacklist", `gpu == "nvidia"`))
+	assert.NoError(t, k.SetCondition("amdgpu.dc", `gpu == "amd"`))
+
+	assert.Equal(t, "root=/dev/sda1 nvidia-drm.modeset=1 rd.driver.blacklist=nouveau quiet", k.Resolve(map[string]string{"gpu": "nvidia"}).String())
+	assert.Equal(t, "root=/dev/sda1 amdgpu.dc=1 quiet", k.Resolve(map[string]string{"gpu": "amd"}).String())
+	assert.Equal(t, "root=/dev/sda1 quiet", k.Resolve(nil).String())
+
+	// Conditions are kept by Clone and can be removed.
+	clone := k.Clone()
+	assert.Equal(t, "root=/dev/sda1 quiet", clone.Resolve(nil).String())
+	assert.NoError(t, clone.SetCondition("amdgpu.dc", ""))
+	assert.Equal(t, "root=/dev/sda1 amdgpu.dc=1 quiet", clone.Resolve(nil).String())
+
+	// The template itself is unchanged.
+	assert.Equal(t, "root=/dev/sda1 nvidia-drm.modeset=1 rd.driver.blacklist=nouveau amdgpu.dc=1 quiet", k.String())
+
+	assert.ErrorIs(t, k.SetCondition("missing", "gpu"), ErrNotExists)
+	assert.ErrorIs(t, k.SetCondition("quiet", "gpu =="), ErrInvalidCondition)
+}
@@ -0,0 +1,99 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "fmt"
+
+// ConsistencyCheck verifies the internal invariants of k and returns an error
+// wrapping ErrInconsistent describing the first violation found, or nil if
+// there is none. It is meant for fuzzers, tests, and long-running services
+// that want to detect corruption early, e.g. after a sequence of mutations; a
+// violation is always a bug in this package. The invariants checked are:
+//
+//   - the list is doubly linked, i.e. the next item of each item has it as its
+//     previous item, and it has no cycles
+//   - the first and last pointers refer to the ends of the list
+//   - the parameter count matches the length of the list
+//   - the key map holds exactly the items of the list under their canonical
+//     keys, in list order, and has no empty entries
+//   - the module map does the same for the items of module parameters
+//
+// It takes time linear in the number of kargs.
+func (k *Kargs) ConsistencyCheck() error {
+	if k.list == nil || k.last == nil {
+		if k.list != k.last {
+			return inconsistent("list is empty but last item is set, or the reverse")
+		}
+	} else {
+		if k.list.prev != nil {
+			return inconsistent("first item %s has a previous item", k.list.karg.Raw)
+		}
+		if k.last.next != nil {
+			return inconsistent("last item %s has a next item", k.last.karg.Raw)
+		}
+	}
+
+	// Walk the list, bounding the walk by the parameter count so that a
+	// cycle shows as a mismatch instead of looping forever.
+	var (
+		count      int
+		tail       *kargItem
+		keyItems   = make(map[string][]*kargItem, len(k.keyMap))
+		moduleKeys = make(map[string][]*kargItem, len(k.moduleMap))
+	)
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if count++; count > k.numParams {
+			return inconsistent("list holds more than the %d kargs counted", k.numParams)
+		}
+		if next := llTracker.next; next != nil && next.prev != llTracker {
+			return inconsistent("item after %s does not link back to it", llTracker.karg.Raw)
+		}
+		key := llTracker.karg.CanonicalKey
+		keyItems[key] = append(keyItems[key], llTracker)
+		if mod, ok := moduleName(key); ok {
+			moduleKeys[mod] = append(moduleKeys[mod], llTracker)
+		}
+		tail = llTracker
+	}
+	if count != k.numParams {
+		return inconsistent("list holds %d kargs, but %d are counted", count, k.numParams)
+	}
+	if tail != k.last {
+		return inconsistent("last item is not the end of the list")
+	}
+
+	if err := checkIndex("key map", k.keyMap, keyItems); err != nil {
+		return err
+	}
+	return checkIndex("module map", k.moduleMap, moduleKeys)
+}
+
+// checkIndex returns an error if index, the key or module map named name,
+// doesn't hold exactly the items of want in the same order.
+func checkIndex(name string, index, want map[string][]*kargItem) error {
+	if len(index) != len(want) {
+		return inconsistent("%s has %d entries, want %d", name, len(index), len(want))
+	}
+	for key, items := range index {
+		wantItems, ok := want[key]
+		if !ok {
+			return inconsistent("%s has an entry for %s, which is not in the list", name, key)
+		}
+		if len(items) != len(wantItems) {
+			return inconsistent("%s has %d items for %s, want %d", name, len(items), key, len(wantItems))
+		}
+		for idx, item := range items {
+			if item != wantItems[idx] {
+				return inconsistent("%s item %d for %s is not in list order or not in the list", name, idx, key)
+			}
+		}
+	}
+	return nil
+}
+
+// inconsistent returns an error wrapping ErrInconsistent with a message
+// formatted from format and args.
+func inconsistent(format string, args ...any) error {
+	return fmt.Errorf("checking consistency: %s: %w", fmt.Sprintf(format, args...), ErrInconsistent)
+}
@@ -0,0 +1,91 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_ConsistencyCheck(t *testing.T) {
+	assert.NoError(t, NewKargsEmpty().ConsistencyCheck())
+	assert.NoError(t, NewKargs([]byte("quiet mod.a=1 console=tty0 mod.b console=ttyS0")).ConsistencyCheck())
+
+	for name, corrupt := range map[string]func(k *Kargs){
+		"count":      func(k *Kargs) { k.numParams++ },
+		"last":       func(k *Kargs) { k.last = k.list },
+		"prev":       func(k *Kargs) { k.list.next.prev = nil },
+		"cycle":      func(k *Kargs) { k.last.next = k.list; k.list.prev = k.last },
+		"key order":  func(k *Kargs) { items := k.keyMap["console"]; items[0], items[1] = items[1], items[0] },
+		"key empty":  func(k *Kargs) { k.keyMap["gone"] = nil },
+		"module":     func(k *Kargs) { delete(k.moduleMap, "mod") },
+		"stale item": func(k *Kargs) { k.keyMap["quiet"] = []*kargItem{{karg: k.list.karg}} },
+	} {
+		k := NewKargs([]byte("quiet mod.a=1 console=tty0 mod.b console=ttyS0"))
+		corrupt(k)
+		assert.ErrorIs(t, k.ConsistencyCheck(), ErrInconsistent, name)
+	}
+}
+
+func TestKargs_DeleteKargByValue_consistency(t *testing.T) {
+	k := NewKargs([]byte("console=tty0 console=tty1 console=ttyS0 quiet"))
+	assert.NoError(t, k.DeleteKargByValue("console", "ttyS0"))
+	assert.NoError(t, k.ConsistencyCheck())
+	values, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "tty1"}, values)
+
+	assert.NoError(t, k.DeleteKargByValue("quiet", ""))
+	assert.NoError(t, k.ConsistencyCheck())
+	assert.False(t, k.ContainsKarg("quiet"))
+}
+
+// TestKargs_ConsistencyCheck_stress applies a deterministic, seeded sequence of
+// random mutations and checks the invariants after each of them.
+func TestKargs_ConsistencyCheck_stress(t *testing.T) {
+	keys := []string{"a", "b-c", "b_c", "mod.x", "mod.y", "other.z"}
+	values := []string{"", "1", "2", "two words"}
+	rng := rand.New(rand.NewSource(1))
+	pick := func(s []string) string { return s[rng.Intn(len(s))] }
+
+	ops := []struct {
+		name string
+		fn   func(k *Kargs)
+	}{
+		{"AppendKarg", func(k *Kargs) { k.AppendKarg(pick(keys), pick(values)) }},
+		{"PrependKarg", func(k *Kargs) { k.PrependKarg(pick(keys), pick(values)) }},
+		{"SetKarg", func(k *Kargs) { k.SetKarg(pick(keys), pick(values)) }},
+		{"SetKargPosition", func(k *Kargs) { k.SetKargPosition(pick(keys), pick(values), SetPosition(rng.Intn(3))) }},
+		{"InsertKargAfter", func(k *Kargs) { k.InsertKargAfter(pick(keys), pick(keys), pick(values)) }},
+		{"InsertKargBefore", func(k *Kargs) { k.InsertKargBefore(pick(keys), pick(keys), pick(values)) }},
+		{"DeleteKarg", func(k *Kargs) { k.DeleteKarg(pick(keys)) }},
+		{"DeleteKargByValue", func(k *Kargs) { k.DeleteKargByValue(pick(keys), pick(values)) }},
+		{"DeleteAt", func(k *Kargs) { k.DeleteAt(rng.Intn(k.Len() + 1)) }},
+		{"TakeKarg", func(k *Kargs) { k.TakeKarg(pick(keys)) }},
+		{"MoveKarg", func(k *Kargs) { k.MoveKarg(pick(keys), rng.Intn(k.Len()+1)) }},
+		{"MoveKargAfter", func(k *Kargs) { k.MoveKargAfter(pick(keys), pick(keys)) }},
+		{"RenameKarg", func(k *Kargs) { k.RenameKarg(pick(keys), pick(keys)) }},
+		{"ReplaceAll", func(k *Kargs) { k.ReplaceAll(pick(keys), pick(values)) }},
+		{"ReplaceKargValue", func(k *Kargs) { k.ReplaceKargValue(pick(keys), pick(values), pick(values)) }},
+		{"Deduplicate", func(k *Kargs) { k.Deduplicate() }},
+		{"Sort", func(k *Kargs) { k.Sort() }},
+		{"Clear", func(k *Kargs) {
+			if rng.Intn(10) == 0 {
+				k.Clear()
+			}
+		}},
+	}
+
+	k := NewKargsEmpty()
+	for step := range 5000 {
+		op := ops[rng.Intn(len(ops))]
+		op.fn(k)
+		if err := k.ConsistencyCheck(); err != nil {
+			assert.NoError(t, err, fmt.Sprintf("step %d: %s: %s", step, op.name, k))
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// maxCorpusLine is the longest line ProcessCorpus accepts. It is far above the
+// command line size limits of the kernel.
+const maxCorpusLine = 1 << 20
+
+// corpusLine is a line of a corpus handed to a worker of ProcessCorpus.
+type corpusLine struct {
+	no   int
+	text string
+}
+
+// ProcessCorpus reads command lines from r, one per line, e.g. recorded samples
+// of /proc/cmdline, parses each of them like NewKargs, and calls fn with it and
+// its line number, counting from 1. Empty lines are skipped.
+//
+// fn is called from several goroutines at once, one per available CPU, so
+// lines are not processed in order and fn must be safe for concurrent use. All
+// lines are processed even if fn fails for some; the failures are returned as a
+// LineErrors ordered by line number. An error reading r stops reading and is
+// returned as well, joined with the failures of the lines read before.
+func ProcessCorpus(r io.Reader, fn func(lineNo int, k *Kargs) error) error {
+	return ProcessCorpusContext(context.Background(), r, fn)
+}
+
+// ProcessCorpusContext is like ProcessCorpus, but stops early once ctx is done:
+// no further lines are handed to fn, calls of fn already running are waited
+// for, and the error of ctx is returned, joined with the failures of the lines
+// processed before. A read from r that blocks is not interrupted; callers
+// reading from a network connection or pipe should also set a deadline on it or
+// close it.
+func ProcessCorpusContext(ctx context.Context, r io.Reader, fn func(lineNo int, k *Kargs) error) error {
+	lines := make(chan corpusLine)
+	var (
+		mu   sync.Mutex
+		errs LineErrors
+		wg   sync.WaitGroup
+	)
+	for range runtime.GOMAXPROCS(0) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := fn(line.no, NewKargs([]byte(line.text))); err != nil {
+					mu.Lock()
+					errs = append(errs, &LineError{Line: line.no, Err: err})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxCorpusLine)
+scan:
+	for lineNo := 1; ctx.Err() == nil && scanner.Scan(); lineNo++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		select {
+		case lines <- corpusLine{no: lineNo, text: text}:
+		case <-ctx.Done():
+			break scan
+		}
+	}
+	close(lines)
+	wg.Wait()
+
+	var err error
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		err = errs
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return errors.Join(fmt.Errorf("processing corpus: %w", ctxErr), err)
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return errors.Join(fmt.Errorf("reading corpus: %w", scanErr), err)
+	}
+	return err
+}
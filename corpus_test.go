@@ -0,0 +1,89 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessCorpus(t *testing.T) {
+	var corpus strings.Builder
+	for i := 1; i <= 1000; i++ {
+		if i%100 == 0 {
+			corpus.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(&corpus, "root=/dev/sda%d console=ttyS0 sample=%d\n", i%4, i)
+	}
+
+	var (
+		mu      sync.Mutex
+		samples = make(map[int]string)
+	)
+	errBad := errors.New("bad sample")
+	err := ProcessCorpus(strings.NewReader(corpus.String()), func(lineNo int, k *Kargs) error {
+		sample, _ := k.GetKarg("sample")
+		mu.Lock()
+		samples[lineNo] = sample[0]
+		mu.Unlock()
+		if lineNo%250 == 0 {
+			return errBad
+		}
+		return nil
+	})
+
+	assert.Len(t, samples, 990)
+	assert.Equal(t, "42", samples[42])
+	assert.ErrorIs(t, err, errBad)
+	var lineErrs LineErrors
+	if assert.ErrorAs(t, err, &lineErrs) {
+		assert.Equal(t, []int{250, 750}, lineErrs.Lines())
+	}
+	assert.ErrorContains(t, err, "line 250: bad sample")
+}
+
+func TestProcessCorpus_readError(t *testing.T) {
+	errRead := errors.New("disk on fire")
+	r := io.MultiReader(strings.NewReader("quiet\nroot=/dev/sda1\n"), iotest.ErrReader(errRead))
+	var processed atomic.Int32
+	err := ProcessCorpus(r, func(int, *Kargs) error {
+		processed.Add(1)
+		return nil
+	})
+	assert.ErrorIs(t, err, errRead)
+	assert.EqualValues(t, 2, processed.Load())
+
+	assert.NoError(t, ProcessCorpus(strings.NewReader(""), func(int, *Kargs) error { return nil }))
+}
+
+func TestProcessCorpusContext(t *testing.T) {
+	corpus := strings.Repeat("quiet\n", 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var processed atomic.Int32
+	err := ProcessCorpusContext(ctx, strings.NewReader(corpus), func(lineNo int, k *Kargs) error {
+		if processed.Add(1) == 10 {
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, processed.Load(), int32(1000))
+
+	err = ProcessCorpusContext(ctx, strings.NewReader(corpus), func(int, *Kargs) error {
+		t.Error("called after cancellation")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,160 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxCPUList bounds the CPU numbers ParseCPUList accepts, well above the
+// largest NR_CPUS the kernel can be configured with. CPUs are collected in a
+// bitmap of this size, so that a bogus list can't make ParseCPUList allocate
+// without bounds.
+const maxCPUList = 1 << 16
+
+// isolcpusFlags are the flags that may precede the CPU list of isolcpus=.
+var isolcpusFlags = []string{"nohz", "domain", "managed_irq"}
+
+// ParseCPUList parses s as a list of CPUs in the syntax of the kernel's
+// bitmap_parselist, as used by isolcpus=, nohz_full=, rcu_nocbs=, and
+// irqaffinity=, and returns the CPUs in ascending order without duplicates.
+// The list consists of comma-separated groups, each of which is a CPU number
+// N, a range N-M, or a range with a stride N-M:used/size, which selects the
+// first used CPUs of every size CPUs in the range, e.g. 0-11:2/4 selects 0, 1,
+// 4, 5, 8, and 9. An empty list is valid and selects no CPUs.
+//
+// The kernel also accepts N as the number of the last CPU, which depends on
+// the machine; like all other malformed lists and CPU numbers of 65536 and
+// above, it results in an error wrapping ErrInvalidValue.
+func ParseCPUList(s string) ([]int, error) {
+	var cpus []int
+	if s == "" {
+		return cpus, nil
+	}
+	var set [maxCPUList / 64]uint64
+	for _, group := range strings.Split(s, ",") {
+		if err := addCPUGroup(&set, group); err != nil {
+			return nil, fmt.Errorf("parsing CPU list %s: group %q: %v: %w", s, group, err, ErrInvalidValue)
+		}
+	}
+	for idx, word := range set {
+		for ; word != 0; word &= word - 1 {
+			cpus = append(cpus, idx*64+bits.TrailingZeros64(word))
+		}
+	}
+	return cpus, nil
+}
+
+// addCPUGroup adds the CPUs selected by group, a group of a CPU list, to set.
+func addCPUGroup(set *[maxCPUList / 64]uint64, group string) error {
+	rng, stride, hasStride := strings.Cut(group, ":")
+	first, last, isRange := strings.Cut(rng, "-")
+	start, err := parseCPU(first)
+	if err != nil {
+		return err
+	}
+	end := start
+	if isRange {
+		if end, err = parseCPU(last); err != nil {
+			return err
+		}
+	} else if hasStride {
+		return fmt.Errorf("stride without range")
+	}
+	if start > end {
+		return fmt.Errorf("range is reversed")
+	}
+	used, size := 1, 1
+	if hasStride {
+		usedStr, sizeStr, ok := strings.Cut(stride, "/")
+		if !ok {
+			return fmt.Errorf("stride without group size")
+		}
+		if used, err = parseCPU(usedStr); err != nil {
+			return err
+		}
+		if size, err = parseCPU(sizeStr); err != nil {
+			return err
+		}
+		if size == 0 || used > size {
+			return fmt.Errorf("invalid stride %d/%d", used, size)
+		}
+	}
+	for base := start; base <= end; base += size {
+		for cpu := base; cpu < base+used && cpu <= end; cpu++ {
+			set[cpu/64] |= 1 << (cpu % 64)
+		}
+	}
+	return nil
+}
+
+// parseCPU parses s as a decimal CPU number below maxCPUList.
+func parseCPU(s string) (int, error) {
+	if s == "" || strings.ContainsAny(s, "+-_") {
+		return 0, strconv.ErrSyntax
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, err.(*strconv.NumError).Err
+	}
+	if n >= maxCPUList {
+		return 0, strconv.ErrRange
+	}
+	return int(n), nil
+}
+
+// FormatCPUList returns cpus in the syntax of ParseCPUList, as the kernel
+// prints CPU lists: in ascending order, with runs of consecutive CPUs written
+// as ranges, e.g. 0-3,8,10-11. Duplicates are ignored. cpus is not modified.
+func FormatCPUList(cpus []int) string {
+	sorted := slices.Compact(slices.Sorted(slices.Values(cpus)))
+	var sb strings.Builder
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.Itoa(sorted[i]))
+		if j > i {
+			sb.WriteByte('-')
+			sb.WriteString(strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return sb.String()
+}
+
+// GetKargCPUList returns the value of the last occurrence of the karg
+// identified by key parsed by ParseCPUList, as well as whether key is set. For
+// isolcpus=, the flags that may precede the list, like in
+// isolcpus=nohz,domain,2-7, are skipped. An error wrapping ErrInvalidValue is
+// returned if the value is not a valid CPU list.
+func (k *Kargs) GetKargCPUList(key string) ([]int, bool, error) {
+	raw, present := k.lastValue(key)
+	if !present {
+		return nil, false, nil
+	}
+	list := raw
+	if canonicalizeKey(key) == "isolcpus" {
+		for {
+			flag, rest, _ := strings.Cut(list, ",")
+			if !slices.Contains(isolcpusFlags, flag) {
+				break
+			}
+			list = rest
+		}
+	}
+	cpus, err := ParseCPUList(list)
+	if err != nil {
+		return nil, true, fmt.Errorf("parsing value %s of key %s: %w", raw, key, err)
+	}
+	return cpus, true, nil
+}
@@ -0,0 +1,86 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for list, want := range map[string][]int{
+		"":                nil,
+		"3":               {3},
+		"1-4,7,9-11":      {1, 2, 3, 4, 7, 9, 10, 11},
+		"0-11:2/4":        {0, 1, 4, 5, 8, 9},
+		"9-11:2/4":        {9, 10},
+		"1-4,7,9-11:2/4":  {1, 2, 3, 4, 7, 9, 10},
+		"5,1-3,2":         {1, 2, 3, 5},
+		"0-65535:1/65535": {0, 65535},
+		"4-7:0/2":         nil,
+		"0-3:4/4":         {0, 1, 2, 3},
+	} {
+		cpus, err := ParseCPUList(list)
+		assert.NoError(t, err, list)
+		assert.Equal(t, want, cpus, list)
+	}
+
+	for _, list := range []string{"N", "1-N", "4-1", "1-", "-1", "+1", "1:1/2", "0-7:2", "0-7:3/2", "0-7:1/0", "65536", "1 2", "0x1", "1,,2", "1,"} {
+		_, err := ParseCPUList(list)
+		assert.ErrorIs(t, err, ErrInvalidValue, list)
+	}
+}
+
+func TestParseCPUList_repeatedRanges(t *testing.T) {
+	list := strings.Repeat("0-65535,", 500) + "0-65535"
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	cpus, err := ParseCPUList(list)
+	runtime.ReadMemStats(&after)
+	assert.NoError(t, err)
+	assert.Len(t, cpus, maxCPUList)
+	// The result itself takes 512 KiB; repeating the range mustn't add to it.
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(4<<20))
+}
+
+func TestFormatCPUList(t *testing.T) {
+	assert.Equal(t, "", FormatCPUList(nil))
+	assert.Equal(t, "3", FormatCPUList([]int{3}))
+	cpus := []int{11, 0, 1, 2, 3, 8, 10, 3}
+	assert.Equal(t, "0-3,8,10-11", FormatCPUList(cpus))
+	assert.Equal(t, []int{11, 0, 1, 2, 3, 8, 10, 3}, cpus)
+
+	parsed, err := ParseCPUList("1-4,7,9-11:2/4")
+	assert.NoError(t, err)
+	assert.Equal(t, "1-4,7,9-10", FormatCPUList(parsed))
+}
+
+func TestKargs_GetKargCPUList(t *testing.T) {
+	k := NewKargs([]byte("nohz_full=1-3 isolcpus=nohz,domain,managed_irq,2-7:2/4 rcu_nocbs= irqaffinity=0 irqaffinity=bogus nohz-full=4-5"))
+
+	cpus, present, err := k.GetKargCPUList("nohz_full")
+	assert.NoError(t, err)
+	assert.True(t, present)
+	assert.Equal(t, []int{4, 5}, cpus)
+
+	cpus, _, err = k.GetKargCPUList("isolcpus")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 6, 7}, cpus)
+
+	cpus, present, err = k.GetKargCPUList("rcu_nocbs")
+	assert.NoError(t, err)
+	assert.True(t, present)
+	assert.Empty(t, cpus)
+
+	_, present, err = k.GetKargCPUList("irqaffinity")
+	assert.True(t, present)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+
+	_, present, err = k.GetKargCPUList("missing")
+	assert.NoError(t, err)
+	assert.False(t, present)
+}
@@ -0,0 +1,73 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"unicode"
+)
+
+// ProvenanceDHCP is the provenance of kargs merged by MergeDHCP, as reported by
+// Provenance. It is also the owner MergeDHCP acts as with regard to claims.
+const ProvenanceDHCP = "dhcp"
+
+// MergeDHCP appends the kernel command line arguments found in payload, as
+// delivered in a DHCP option or PXE vendor string, to k and returns how many
+// were appended. Since payload comes from the network, it is sanitized first:
+// it is cut at the first NUL byte, which DHCP uses as padding, and parameters
+// containing control characters are dropped. Of the rest, only those for which
+// allow returns true and whose key isn't claimed by an owner other than
+// ProvenanceDHCP (see Claim) are appended as allow was shown them, unless the
+// key is already set to the same value. Merging stops at the limits k was
+// parsed with, see WithMaxParams and friends. A nil allow appends nothing:
+// parameters from the network must be allowed explicitly. Appended kargs have
+// the provenance ProvenanceDHCP.
+func (k *Kargs) MergeDHCP(payload []byte, allow func(Karg) bool) int {
+	if allow == nil {
+		return 0
+	}
+	if idx := bytes.IndexByte(payload, 0); idx != -1 {
+		payload = payload[:idx]
+	}
+	merged := 0
+	Tokenize(string(payload), func(t Token) error {
+		for _, c := range t.Raw {
+			if unicode.IsControl(c) {
+				return nil
+			}
+		}
+		karg := Karg{
+			CanonicalKey: t.CanonicalKey,
+			Key:          t.Key,
+			Raw:          t.Raw,
+			Value:        k.cfg.dequote(t.RawValue),
+		}
+		if !allow(karg) || k.checkOwner(karg.Key, ProvenanceDHCP) != nil || k.HasKargValue(karg.Key, karg.Value) {
+			return nil
+		}
+		if err := k.cfg.checkLimits(t, k.numParams); err != nil {
+			return err
+		}
+		k.appendItem(karg).source = ProvenanceDHCP
+		merged++
+		return nil
+	})
+	return merged
+}
+
+// Provenance returns the provenance of each occurrence of the karg identified
+// by key, in command line order, e.g. ProvenanceDHCP for kargs merged by
+// MergeDHCP. Kargs of unknown provenance, like those parsed from a command line
+// or set directly, have an empty provenance. Nil is returned if key is not set.
+func (k *Kargs) Provenance(key string) []string {
+	items := k.keyMap[canonicalizeKey(key)]
+	if len(items) == 0 {
+		return nil
+	}
+	sources := make([]string, len(items))
+	for idx, item := range items {
+		sources[idx] = item.source
+	}
+	return sources
+}
@@ -0,0 +1,62 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_MergeDHCP(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/nfs ip=dhcp"))
+
+	allow := func(karg Karg) bool {
+		return karg.CanonicalKey == "nfsroot" || karg.CanonicalKey == "console" || karg.CanonicalKey == "ip"
+	}
+	payload := []byte("nfsroot=10.0.0.1:/export console=ttyS0\x01 init=/bin/sh ip=dhcp console=tty0\x00\x00padding")
+	merged := k.MergeDHCP(payload, allow)
+	assert.Equal(t, 2, merged)
+	assert.Equal(t, "root=/dev/nfs ip=dhcp nfsroot=10.0.0.1:/export console=tty0", k.String())
+}
+
+func TestKargs_MergeDHCP_quoted(t *testing.T) {
+	k := NewKargs([]byte("quiet"), WithMaxParams(3))
+
+	var shown []string
+	allow := func(karg Karg) bool {
+		shown = append(shown, karg.Value)
+		return true
+	}
+	merged := k.MergeDHCP([]byte(`foo="a b" bar='c' quiet baz`), allow)
+	assert.Equal(t, 2, merged)
+	assert.Equal(t, []string{"a b", "c", "", ""}, shown)
+	vals, _ := k.GetKarg("foo")
+	assert.Equal(t, []string{"a b"}, vals)
+	vals, _ = k.GetKarg("bar")
+	assert.Equal(t, []string{"c"}, vals)
+	assert.Equal(t, `quiet foo="a b" bar='c'`, k.String())
+	assert.False(t, k.ContainsKarg("baz"))
+	assert.NoError(t, k.ConsistencyCheck())
+}
+
+func TestKargs_MergeDHCP_nilAllow(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/nfs"))
+	merged := k.MergeDHCP([]byte("init=/bin/sh rd.break module.sig_enforce=0"), nil)
+	assert.Zero(t, merged)
+	assert.Equal(t, "root=/dev/nfs", k.String())
+}
+
+func TestKargs_MergeDHCP_provenance(t *testing.T) {
+	k := NewKargs([]byte("console=tty0 root=/dev/nfs"))
+	assert.NoError(t, k.Claim("root", "installer"))
+	allowAll := func(Karg) bool { return true }
+	merged := k.MergeDHCP([]byte("console=ttyS0 root=/dev/sda1 a b=1\tc"), allowAll)
+	assert.Equal(t, 4, merged)
+	assert.Equal(t, "console=tty0 root=/dev/nfs console=ttyS0 a b=1 c", k.String())
+	assert.Equal(t, []string{"", ProvenanceDHCP}, k.Provenance("console"))
+	assert.Equal(t, []string{ProvenanceDHCP}, k.Clone().Provenance("b"))
+	assert.Equal(t, []string{""}, k.Provenance("root"))
+	assert.Nil(t, k.Provenance("missing"))
+}
@@ -0,0 +1,151 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "fmt"
+
+// ChangeOp is the kind of a Change.
+type ChangeOp int
+
+const (
+	// ChangeAdd adds a key that wasn't present.
+	ChangeAdd ChangeOp = iota
+
+	// ChangeDelete deletes all occurrences of a key.
+	ChangeDelete
+
+	// ChangeReplace replaces the values of a key.
+	ChangeReplace
+)
+
+// String returns the name of op.
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangeAdd:
+		return "add"
+	case ChangeDelete:
+		return "delete"
+	case ChangeReplace:
+		return "replace"
+	default:
+		return fmt.Sprintf("ChangeOp(%d)", int(op))
+	}
+}
+
+// Change is a single change of a KargsDiff, affecting all occurrences of a key.
+type Change struct {
+	Op     ChangeOp // Kind of change
+	Key    string   // Key as written
+	Values []string // New values for ChangeAdd and ChangeReplace
+}
+
+// KargsDiff is a set of changes turning one Kargs into another, as returned by
+// Diff. It can be recorded once and replayed onto any number of Kargs with
+// Apply.
+type KargsDiff []Change
+
+// Diff returns the changes that turn k into other. Kargs are compared by
+// canonical key like in Merge3, with values listed as described in Conflict. The
+// changes to keys of k come first, in command line order, followed by the keys
+// added by other in its order.
+func (k *Kargs) Diff(other *Kargs) KargsDiff {
+	var diff KargsDiff
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		key := llTracker.karg.CanonicalKey
+		if items := k.keyMap[key]; len(items) == 0 || items[0] != llTracker {
+			continue
+		}
+		switch otherItems := other.keyMap[key]; {
+		case len(otherItems) == 0:
+			diff = append(diff, Change{Op: ChangeDelete, Key: llTracker.karg.Key})
+		case !equalValues(k.values(key), other.values(key)):
+			diff = append(diff, Change{Op: ChangeReplace, Key: otherItems[0].karg.Key, Values: other.values(key)})
+		}
+	}
+	for llTracker := other.list; llTracker != nil; llTracker = llTracker.next {
+		key := llTracker.karg.CanonicalKey
+		if items := other.keyMap[key]; len(k.keyMap[key]) == 0 && len(items) > 0 && items[0] == llTracker {
+			diff = append(diff, Change{Op: ChangeAdd, Key: llTracker.karg.Key, Values: other.values(key)})
+		}
+	}
+	return diff
+}
+
+// Apply applies the changes of diff to k in order. Since diff may have been
+// computed against a different Kargs, changes are applied leniently: adding or
+// replacing a key sets its values, taking the place of its first occurrence if
+// it is present and appending it otherwise, and deleting a key that isn't
+// present does nothing. Values are quoted according to the QuoteMode of k.
+// Changes with an invalid key, a value the QuoteMode of k rejects, or an unknown
+// op are skipped and reported in a KeyErrors.
+func (k *Kargs) Apply(diff KargsDiff) error {
+	var errs KeyErrors
+	for _, change := range diff {
+		if err := checkKey(change.Key); err != nil {
+			errs = append(errs, &KeyError{Key: change.Key, Err: err})
+			continue
+		}
+		if change.Key == "" {
+			errs = append(errs, &KeyError{Key: change.Key, Err: fmt.Errorf("checking key: %w", ErrInvalidKey)})
+			continue
+		}
+		var err error
+		switch change.Op {
+		case ChangeAdd, ChangeReplace:
+			err = k.setValues(change.Key, change.Values)
+		case ChangeDelete:
+			err = k.setValues(change.Key, nil)
+		default:
+			err = fmt.Errorf("applying %s: %w", change.Op, ErrUnsupported)
+		}
+		if err != nil {
+			errs = append(errs, &KeyError{Key: change.Key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// setValues replaces all occurrences of key with one karg per value, placed
+// where the first occurrence was or at the end if there was none. No values
+// deletes the key. k is left unchanged if a value can't be quoted according to
+// its QuoteMode.
+func (k *Kargs) setValues(key string, vals []string) error {
+	newKargs := make([]Karg, len(vals))
+	for idx, val := range vals {
+		newKarg, err := k.cfg.makeKarg(key, val)
+		if err != nil {
+			return fmt.Errorf("failed to set key %s: %w", key, err)
+		}
+		newKargs[idx] = newKarg
+	}
+	old := k.keyMap[canonicalizeKey(key)]
+	var mark *kargItem
+	if len(old) > 0 {
+		mark = old[0]
+	}
+	for _, newKarg := range newKargs {
+		item := k.insertBefore(mark, newKarg)
+		if mark != nil {
+			item.line = mark.line
+		}
+	}
+	for _, item := range append([]*kargItem(nil), old...) {
+		if err := k.removeItem(item); err != nil {
+			return fmt.Errorf("failed to remove karg: %w", err)
+		}
+	}
+	return nil
+}
+
+// Preview runs fn on a copy of k and returns the changes fn made to it, without
+// modifying k, e.g. to tell what a change would do before making it. If fn
+// returns an error, it is returned along with the changes made up to then.
+func (k *Kargs) Preview(fn func(*Kargs) error) (KargsDiff, error) {
+	preview := k.Clone()
+	err := fn(preview)
+	return k.Diff(preview), err
+}
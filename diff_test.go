@@ -0,0 +1,109 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_Diff(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet rd-break splash"))
+	other := NewKargs([]byte("root=/dev/sda2 console=tty0 console=ttyS0 rd_break nomodeset usb-core.autosuspend=-1"))
+
+	assert.Equal(t, KargsDiff{
+		{Op: ChangeReplace, Key: "root", Values: []string{"/dev/sda2"}},
+		{Op: ChangeReplace, Key: "console", Values: []string{"tty0", "ttyS0"}},
+		{Op: ChangeDelete, Key: "quiet"},
+		{Op: ChangeDelete, Key: "splash"},
+		{Op: ChangeAdd, Key: "nomodeset", Values: []string{""}},
+		{Op: ChangeAdd, Key: "usb-core.autosuspend", Values: []string{"-1"}},
+	}, k.Diff(other))
+
+	assert.Empty(t, k.Diff(k.Clone()))
+}
+
+func TestKargs_Apply(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet rd-break splash"))
+	other := NewKargs([]byte("root=/dev/sda2 console=tty0 console=ttyS0 rd_break nomodeset usb-core.autosuspend=-1"))
+	diff := k.Diff(other)
+
+	assert.NoError(t, k.Apply(diff))
+	assert.Equal(t, "root=/dev/sda2 console=tty0 console=ttyS0 rd-break nomodeset usb-core.autosuspend=-1", k.String())
+	assert.Empty(t, k.Diff(other))
+	assert.Equal(t, []string{"usb_core"}, k.Modules())
+
+	// Diffs apply to unrelated kargs, too.
+	node := NewKargs([]byte("console=tty1 quiet ip=dhcp root=/dev/nvme0n1"))
+	assert.NoError(t, node.Apply(diff))
+	assert.Equal(t, "console=tty0 console=ttyS0 ip=dhcp root=/dev/sda2 nomodeset usb-core.autosuspend=-1", node.String())
+	assert.Equal(t, 6, node.Len())
+	values, _ := node.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0"}, values)
+}
+
+func TestKargs_Apply_quoteMode(t *testing.T) {
+	k := NewKargs([]byte("init=/sbin/init quiet"), WithQuoteMode(QuoteKernel))
+	err := k.Apply(KargsDiff{
+		{Op: ChangeReplace, Key: "init", Values: []string{"/sbin/init --debug"}},
+		{Op: ChangeAdd, Key: "msg", Values: []string{`say "hi"`}},
+	})
+	assert.ErrorIs(t, err, ErrUnquotable)
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"msg"}, keyErrs.Keys())
+	assert.Equal(t, `init="/sbin/init --debug" quiet`, k.String())
+	assert.Equal(t, []string{"init=/sbin/init --debug", "quiet"}, SplitLikeKernel(k.String()))
+}
+
+func TestKargs_Apply_invalid(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1"))
+	err := k.Apply(KargsDiff{
+		{Op: ChangeAdd, Key: "bad key", Values: []string{"x"}},
+		{Op: ChangeAdd, Key: "", Values: []string{"x"}},
+		{Op: ChangeOp(42), Key: "root"},
+		{Op: ChangeReplace, Key: "init", Values: []string{"/sbin/init --verbose"}},
+	})
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"bad key", "", "root"}, keyErrs.Keys())
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.Equal(t, `root=/dev/sda1 init="/sbin/init --verbose"`, k.String())
+}
+
+func TestChangeOp_String(t *testing.T) {
+	assert.Equal(t, "add", ChangeAdd.String())
+	assert.Equal(t, "delete", ChangeDelete.String())
+	assert.Equal(t, "replace", ChangeReplace.String())
+	assert.Equal(t, "ChangeOp(42)", ChangeOp(42).String())
+}
+
+func TestKargs_Preview(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 quiet"))
+
+	diff, err := k.Preview(func(preview *Kargs) error {
+		if err := preview.SetKarg("root", "/dev/sda2"); err != nil {
+			return err
+		}
+		return preview.DeleteKarg("quiet")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, KargsDiff{
+		{Op: ChangeReplace, Key: "root", Values: []string{"/dev/sda2"}},
+		{Op: ChangeDelete, Key: "quiet"},
+	}, diff)
+	assert.Equal(t, "root=/dev/sda1 quiet", k.String())
+
+	diff, err = k.Preview(func(preview *Kargs) error {
+		if err := preview.AppendKarg("splash", ""); err != nil {
+			return err
+		}
+		return preview.DeleteKarg("missing")
+	})
+	assert.ErrorIs(t, err, ErrNotExists)
+	assert.Equal(t, KargsDiff{{Op: ChangeAdd, Key: "splash", Values: []string{""}}}, diff)
+	assert.Equal(t, "root=/dev/sda1 quiet", k.String())
+}
@@ -0,0 +1,162 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// EnvelopeVersion is the version of the envelope format written by
+// MarshalEnvelope and MarshalEnvelopeJSON. It is only increased for changes
+// that older readers cannot handle; compatible additions are announced by
+// optional flags instead.
+const EnvelopeVersion = 1
+
+// envelopeMagic starts every binary envelope.
+var envelopeMagic = []byte("KENV")
+
+// envelopeRequiredFlags masks the flags of an envelope that readers must
+// understand. Flags outside of it are optional and ignored by readers that
+// don't know them. No flags are defined by version 1.
+const (
+	envelopeRequiredFlags uint64 = 0xffffffff
+	envelopeKnownFlags    uint64 = 0
+)
+
+// envelopeJSON is the JSON form of an envelope.
+type envelopeJSON struct {
+	Version uint64     `json:"version"`
+	Flags   uint64     `json:"flags"`
+	Payload [][]string `json:"payload"`
+}
+
+// MarshalEnvelope returns k encoded as by MarshalCBOR, wrapped in an envelope
+// recording the format version and flags, for storing serialized kargs for a
+// long time. The envelope consists of the magic bytes "KENV", the version, the
+// flags, and the length of the payload, each as an unsigned varint, followed by
+// the payload. Later versions may append further data, e.g. metadata or
+// provenance, after the payload, which UnmarshalEnvelope skips.
+//
+// Flags in the lower 32 bits change how an envelope must be read, so readers
+// reject envelopes with such flags unknown to them. Flags in the upper 32 bits
+// are optional and ignored by readers that don't know them.
+func (k *Kargs) MarshalEnvelope() ([]byte, error) {
+	payload, err := k.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	buf := append([]byte(nil), envelopeMagic...)
+	buf = binary.AppendUvarint(buf, EnvelopeVersion)
+	buf = binary.AppendUvarint(buf, 0)
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	return append(buf, payload...), nil
+}
+
+// UnmarshalEnvelope replaces the kargs of k with those decoded from data, which
+// must be in the form produced by MarshalEnvelope. Data following the payload is
+// ignored. An error wrapping ErrUnsupported is returned if the envelope has a
+// later version than EnvelopeVersion or sets unknown required flags, and one
+// wrapping ErrInvalidEncoding if it is malformed; k is left unchanged in both
+// cases.
+func (k *Kargs) UnmarshalEnvelope(data []byte) error {
+	if !bytes.HasPrefix(data, envelopeMagic) {
+		return fmt.Errorf("decoding envelope: missing magic: %w", ErrInvalidEncoding)
+	}
+	data = data[len(envelopeMagic):]
+	var header [3]uint64 // Version, flags, and payload length
+	for i := range header {
+		n, size := binary.Uvarint(data)
+		if size <= 0 {
+			return fmt.Errorf("decoding envelope: truncated header: %w", ErrInvalidEncoding)
+		}
+		header[i] = n
+		data = data[size:]
+	}
+	if err := checkEnvelope(header[0], header[1]); err != nil {
+		return err
+	}
+	if header[2] > uint64(len(data)) {
+		return fmt.Errorf("decoding envelope: payload of %d bytes truncated to %d: %w", header[2], len(data), ErrInvalidEncoding)
+	}
+	return k.UnmarshalCBOR(data[:header[2]])
+}
+
+// MarshalEnvelopeJSON returns k wrapped in a JSON envelope, the JSON
+// counterpart of MarshalEnvelope, e.g.
+//
+//	{"version":1,"flags":0,"payload":[["rd_break"],["root","/dev/sda1"]]}
+//
+// The payload holds one array per karg in command line order, holding its
+// canonical key and, if it has a value, its dequoted value, like the encoding of
+// MarshalCBOR. Later versions may add further members, which
+// UnmarshalEnvelopeJSON ignores. An error wrapping ErrUnsupported is returned if
+// a key or value isn't valid UTF-8.
+func (k *Kargs) MarshalEnvelopeJSON() ([]byte, error) {
+	env := envelopeJSON{Version: EnvelopeVersion, Payload: make([][]string, 0, k.numParams)}
+	for item := k.list; item != nil; item = item.next {
+		karg := item.karg
+		if !utf8.ValidString(karg.CanonicalKey) || !utf8.ValidString(karg.Value) {
+			return nil, fmt.Errorf("encoding karg %s: invalid UTF-8: %w", karg.Raw, ErrUnsupported)
+		}
+		fields := []string{karg.CanonicalKey}
+		if karg.Value != "" {
+			fields = append(fields, karg.Value)
+		}
+		env.Payload = append(env.Payload, fields)
+	}
+	return json.Marshal(env)
+}
+
+// UnmarshalEnvelopeJSON replaces the kargs of k with those decoded from data,
+// which must be in the form produced by MarshalEnvelopeJSON. Unknown members
+// are ignored. Errors are reported as by UnmarshalEnvelope; k is left unchanged
+// in case of an error.
+func (k *Kargs) UnmarshalEnvelopeJSON(data []byte) error {
+	var env envelopeJSON
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("decoding envelope: %v: %w", err, ErrInvalidEncoding)
+	}
+	if err := checkEnvelope(env.Version, env.Flags); err != nil {
+		return err
+	}
+	kargs := make([]Karg, 0, len(env.Payload))
+	for i, fields := range env.Payload {
+		if len(fields) != 1 && len(fields) != 2 {
+			return fmt.Errorf("decoding karg %d: %d fields: %w", i, len(fields), ErrInvalidEncoding)
+		}
+		var value string
+		if len(fields) == 2 {
+			value = fields[1]
+		}
+		karg, err := decodedKarg(i, fields[0], value, len(fields) == 2)
+		if err != nil {
+			return err
+		}
+		kargs = append(kargs, karg)
+	}
+	k.reset()
+	for _, karg := range kargs {
+		k.appendItem(karg)
+	}
+	return nil
+}
+
+// checkEnvelope returns an error if an envelope with version and flags cannot
+// be read.
+func checkEnvelope(version, flags uint64) error {
+	if version == 0 {
+		return fmt.Errorf("decoding envelope: version 0: %w", ErrInvalidEncoding)
+	}
+	if version > EnvelopeVersion {
+		return fmt.Errorf("decoding envelope: version %d: %w", version, ErrUnsupported)
+	}
+	if unknown := flags & envelopeRequiredFlags &^ envelopeKnownFlags; unknown != 0 {
+		return fmt.Errorf("decoding envelope: required flags %#x: %w", unknown, ErrUnsupported)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_MarshalEnvelope(t *testing.T) {
+	k := NewKargs([]byte(`rd-break root='/dev/sda1'`))
+	data, err := k.MarshalEnvelope()
+	assert.NoError(t, err)
+	payload, _ := k.MarshalCBOR()
+	assert.Equal(t, append([]byte{'K', 'E', 'N', 'V', 1, 0, byte(len(payload))}, payload...), data)
+
+	decoded := NewKargs([]byte("old=karg"))
+	assert.NoError(t, decoded.UnmarshalEnvelope(data))
+	assert.Equal(t, "rd_break root=/dev/sda1", decoded.String())
+
+	// Trailing data added by later versions is skipped.
+	assert.NoError(t, decoded.UnmarshalEnvelope(append(data, 0xa1, 0x61, 'm', 0x61, 'x')))
+	assert.Equal(t, "rd_break root=/dev/sda1", decoded.String())
+
+	// Unknown optional flags are ignored.
+	optional := binary.AppendUvarint([]byte{'K', 'E', 'N', 'V', 1}, 1<<40)
+	optional = append(optional, data[6:]...)
+	assert.NoError(t, decoded.UnmarshalEnvelope(optional))
+
+	for name, data := range map[string][]byte{
+		"version": append([]byte{'K', 'E', 'N', 'V', 2}, data[5:]...),
+		"flags":   append([]byte{'K', 'E', 'N', 'V', 1, 1}, data[6:]...),
+	} {
+		err := decoded.UnmarshalEnvelope(data)
+		assert.ErrorIs(t, err, ErrUnsupported, name)
+	}
+	for name, data := range map[string][]byte{
+		"magic":     append([]byte("KENW"), data[4:]...),
+		"header":    data[:5],
+		"truncated": data[:len(data)-1],
+		"version 0": append([]byte{'K', 'E', 'N', 'V', 0}, data[5:]...),
+	} {
+		err := decoded.UnmarshalEnvelope(data)
+		assert.ErrorIs(t, err, ErrInvalidEncoding, name)
+	}
+	assert.Equal(t, "rd_break root=/dev/sda1", decoded.String())
+}
+
+func TestKargs_MarshalEnvelopeJSON(t *testing.T) {
+	k := NewKargs([]byte(`rd-break root='/dev/sda1' init="/sbin/init --verbose"`))
+	data, err := k.MarshalEnvelopeJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":1,"flags":0,"payload":[["rd_break"],["root","/dev/sda1"],["init","/sbin/init --verbose"]]}`, string(data))
+
+	decoded := NewKargs([]byte("old=karg"))
+	assert.NoError(t, decoded.UnmarshalEnvelopeJSON(data))
+	assert.Equal(t, `rd_break root=/dev/sda1 init="/sbin/init --verbose"`, decoded.String())
+
+	// Unknown members and optional flags are ignored.
+	assert.NoError(t, decoded.UnmarshalEnvelopeJSON([]byte(`{"version":1,"flags":4294967296,"provenance":{"by":"ci"},"payload":[["quiet"]]}`)))
+	assert.Equal(t, "quiet", decoded.String())
+
+	err = decoded.UnmarshalEnvelopeJSON([]byte(`{"version":2,"payload":[]}`))
+	assert.ErrorIs(t, err, ErrUnsupported)
+	err = decoded.UnmarshalEnvelopeJSON([]byte(`{"version":1,"flags":2,"payload":[]}`))
+	assert.ErrorIs(t, err, ErrUnsupported)
+	for _, data := range []string{
+		`{"version":1,"payload":[["rd-break"]]}`,
+		`{"version":1,"payload":[["root",""]]}`,
+		`{"version":1,"payload":[[]]}`,
+		`{"payload":[]}`,
+		`[]`,
+	} {
+		err := decoded.UnmarshalEnvelopeJSON([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidEncoding, data)
+	}
+	assert.Equal(t, "quiet", decoded.String())
+}
@@ -0,0 +1,101 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"sort"
+	"strings"
+)
+
+// EqualOption changes how Equal compares two Kargs.
+type EqualOption func(*equalConfig)
+
+// equalConfig holds the settings applied by EqualOptions.
+type equalConfig struct {
+	ignoreOrder bool            // Compare kargs regardless of order
+	foldValues  bool            // Compare values case-insensitively
+	ignoreKeys  map[string]bool // Canonical keys left out of the comparison
+}
+
+// IgnoreOrder makes Equal compare kargs regardless of their order. Each karg
+// still has to occur as often in one Kargs as in the other.
+func IgnoreOrder() EqualOption {
+	return func(cfg *equalConfig) {
+		cfg.ignoreOrder = true
+	}
+}
+
+// FoldValues makes Equal compare values case-insensitively, like
+// strings.EqualFold. Keys are always compared exactly.
+func FoldValues() EqualOption {
+	return func(cfg *equalConfig) {
+		cfg.foldValues = true
+	}
+}
+
+// IgnoreKeys makes Equal leave out all occurrences of keys, e.g. parameters like
+// BOOT_IMAGE= that are expected to differ between otherwise equal command lines.
+func IgnoreKeys(keys ...string) EqualOption {
+	return func(cfg *equalConfig) {
+		if cfg.ignoreKeys == nil {
+			cfg.ignoreKeys = make(map[string]bool)
+		}
+		for _, key := range keys {
+			cfg.ignoreKeys[canonicalizeKey(key)] = true
+		}
+	}
+}
+
+// Equal reports whether k and other contain the same parameters in the same
+// order, comparing canonical keys and dequoted values like EqualString, so that
+// differences in spelling, quoting, or whitespace don't matter. opts can be used
+// to relax the comparison.
+func (k *Kargs) Equal(other *Kargs, opts ...EqualOption) bool {
+	var cfg equalConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	a, b := cfg.pairs(k), cfg.pairs(other)
+	if len(a) != len(b) {
+		return false
+	}
+	if cfg.ignoreOrder {
+		sortPairs(a)
+		sortPairs(b)
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// pairs returns the canonical key and value of each karg of k to compare.
+// Values are case-folded if they are compared case-insensitively.
+func (cfg equalConfig) pairs(k *Kargs) [][2]string {
+	pairs := make([][2]string, 0, k.numParams)
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		karg := llTracker.karg
+		if cfg.ignoreKeys[karg.CanonicalKey] {
+			continue
+		}
+		value := karg.Value
+		if cfg.foldValues {
+			value = strings.ToLower(strings.ToUpper(value))
+		}
+		pairs = append(pairs, [2]string{karg.CanonicalKey, value})
+	}
+	return pairs
+}
+
+// sortPairs sorts pairs by key, then by value.
+func sortPairs(pairs [][2]string) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+}
@@ -0,0 +1,37 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_Equal(t *testing.T) {
+	k := NewKargs([]byte(`foo-bar=1 baz msg="a b"`))
+
+	assert.True(t, k.Equal(NewKargs([]byte(`foo_bar=1  baz msg='a b'`))))
+	assert.True(t, k.Equal(k))
+	assert.False(t, k.Equal(NewKargs([]byte(`foo_bar=2 baz msg="a b"`))))
+	assert.False(t, k.Equal(NewKargs([]byte(`baz foo_bar=1 msg="a b"`))))
+	assert.False(t, k.Equal(NewKargs([]byte(`foo_bar=1 baz`))))
+	assert.False(t, k.Equal(NewKargs([]byte(`foo_bar=1 baz msg="a b" baz`))))
+	assert.True(t, NewKargsEmpty().Equal(NewKargsEmpty()))
+}
+
+func TestKargs_Equal_options(t *testing.T) {
+	k := NewKargs([]byte("BOOT_IMAGE=/vmlinuz-6.1 root=UUID=ABCD console=tty0 console=ttyS0"))
+	other := NewKargs([]byte("console=tty0 BOOT_IMAGE=/vmlinuz-6.2 console=ttyS0 root=UUID=abcd"))
+
+	assert.False(t, k.Equal(other))
+	assert.False(t, k.Equal(other, IgnoreOrder(), FoldValues()))
+	assert.False(t, k.Equal(other, IgnoreOrder(), IgnoreKeys("BOOT_IMAGE")))
+	assert.True(t, k.Equal(other, IgnoreOrder(), FoldValues(), IgnoreKeys("BOOT-IMAGE")))
+
+	// Ignoring the order keeps occurrence counts.
+	assert.False(t, k.Equal(NewKargs([]byte("BOOT_IMAGE=/vmlinuz-6.1 root=UUID=ABCD console=tty0 console=tty0 console=ttyS0")), IgnoreOrder()))
+	// The order of the same key still matters without IgnoreOrder.
+	assert.False(t, k.Equal(NewKargs([]byte("BOOT_IMAGE=/vmlinuz-6.1 root=UUID=ABCD console=ttyS0 console=tty0"))))
+}
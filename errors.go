@@ -3,10 +3,168 @@
 
 package kargs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
-	ErrInvalidKey = errors.New("key contains invalid characters")
-	ErrNilPtr     = errors.New("pointer is nil")
-	ErrNotExists  = errors.New("karg does not exist")
+	ErrBadSignature      = errors.New("signature is invalid")
+	ErrInconsistent      = errors.New("internal state is inconsistent")
+	ErrInvalidAddress    = errors.New("address is invalid")
+	ErrInvalidCondition  = errors.New("condition is invalid")
+	ErrInvalidEncoding   = errors.New("encoding is invalid")
+	ErrInvalidKey        = errors.New("key contains invalid characters")
+	ErrInvalidModule     = errors.New("module name is invalid")
+	ErrInvalidPolicy     = errors.New("policy is invalid")
+	ErrInvalidValue      = errors.New("value is invalid")
+	ErrKernelMismatch    = errors.New("kernel parses differently")
+	ErrLimitExceeded     = errors.New("limit exceeded")
+	ErrMissingKernel     = errors.New("kernel path is missing")
+	ErrNoNode            = errors.New("node does not exist")
+	ErrNilPtr            = errors.New("pointer is nil")
+	ErrNotExists         = errors.New("karg does not exist")
+	ErrOutOfRange        = errors.New("position is out of range")
+	ErrOwned             = errors.New("karg is owned by another writer")
+	ErrUnquotable        = errors.New("value cannot be quoted")
+	ErrUnsupported       = errors.New("not supported")
+	ErrUnterminatedQuote = errors.New("quote is not terminated")
 )
+
+// errStop is returned by internal Tokenize callbacks to stop tokenizing early.
+var errStop = errors.New("stop")
+
+// KeyError records the failure of an operation on a single key as part of a
+// bulk operation.
+type KeyError struct {
+	Key string // Key the operation failed for
+	Err error  // Reason for the failure
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("key %s: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the reason for the failure.
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// KeyErrors is returned by bulk operations that failed for some keys. It holds
+// one KeyError per failed key, in the order in which the keys were processed.
+// errors.Is and errors.As look at each of them.
+type KeyErrors []*KeyError
+
+func (e KeyErrors) Error() string {
+	msgs := make([]string, len(e))
+	for idx, keyErr := range e {
+		msgs[idx] = keyErr.Error()
+	}
+	return fmt.Sprintf("failed for %d key(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Keys returns the keys the operation failed for.
+func (e KeyErrors) Keys() []string {
+	keys := make([]string, len(e))
+	for idx, keyErr := range e {
+		keys[idx] = keyErr.Key
+	}
+	return keys
+}
+
+// Unwrap returns the individual errors.
+func (e KeyErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for idx, keyErr := range e {
+		errs[idx] = keyErr
+	}
+	return errs
+}
+
+// LineError records the failure to process a single line of a corpus read by
+// ProcessCorpus.
+type LineError struct {
+	Line int   // Number of the line, counting from 1
+	Err  error // Reason for the failure
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the reason for the failure.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LineErrors is returned by ProcessCorpus if processing failed for some lines.
+// It holds one LineError per failed line, ordered by line number. errors.Is
+// and errors.As look at each of them.
+type LineErrors []*LineError
+
+func (e LineErrors) Error() string {
+	msgs := make([]string, len(e))
+	for idx, lineErr := range e {
+		msgs[idx] = lineErr.Error()
+	}
+	return fmt.Sprintf("failed for %d line(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Lines returns the numbers of the lines processing failed for.
+func (e LineErrors) Lines() []int {
+	lines := make([]int, len(e))
+	for idx, lineErr := range e {
+		lines[idx] = lineErr.Line
+	}
+	return lines
+}
+
+// Unwrap returns the individual errors.
+func (e LineErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for idx, lineErr := range e {
+		errs[idx] = lineErr
+	}
+	return errs
+}
+
+// Limit identifies a parser limit in a LimitError.
+type Limit int
+
+const (
+	LimitParams      Limit = iota // Number of kargs, see WithMaxParams
+	LimitTokenLength              // Length of a karg, see WithMaxTokenLength
+	LimitKeyLength                // Length of a key, see WithMaxKeyLength
+)
+
+// String returns a description of l.
+func (l Limit) String() string {
+	switch l {
+	case LimitParams:
+		return "number of kargs"
+	case LimitTokenLength:
+		return "karg length"
+	case LimitKeyLength:
+		return "key length"
+	default:
+		return fmt.Sprintf("Limit(%d)", int(l))
+	}
+}
+
+// LimitError is returned when parsing stops because the command line exceeds a
+// limit set by a ParseOption.
+type LimitError struct {
+	Limit  Limit // Limit that was exceeded
+	Max    int   // Value of the limit
+	Offset int   // Byte offset of the offending karg in the command line
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s exceeds limit of %d at offset %d", e.Limit, e.Max, e.Offset)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
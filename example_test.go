@@ -9,6 +9,19 @@ import (
 	kargs "github.com/synackd/go-kargs"
 )
 
+func ExampleKargs_AppendKarg() {
+	k := kargs.NewKargs([]byte("console=tty0"))
+
+	err := k.AppendKarg("console", "ttyS0,115200n8")
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+	}
+	fmt.Println(k)
+
+	// Output:
+	// console=tty0 console=ttyS0,115200n8
+}
+
 func ExampleKargs_AppendKargs() {
 	cmdline := `key=val1`
 	k := kargs.NewKargs([]byte(cmdline))
@@ -76,6 +89,20 @@ func ExampleKargs_DeleteKargByValue() {
 	// key=val1 key=val3
 }
 
+func ExampleKargs_Format() {
+	k := kargs.NewKargs([]byte(`root=/dev/sda1 quiet console=ttyS0,115200n8`))
+	fmt.Printf("%q\n", k.Format(kargs.WithNewline()))
+	fmt.Printf("%q\n", k.Format(kargs.WithNUL()))
+	fmt.Print(k.Format(kargs.WithSeparator("\n"), kargs.WithNewline()))
+
+	// Output:
+	// "root=/dev/sda1 quiet console=ttyS0,115200n8\n"
+	// "root=/dev/sda1 quiet console=ttyS0,115200n8\x00"
+	// root=/dev/sda1
+	// quiet
+	// console=ttyS0,115200n8
+}
+
 func ExampleKargs_GetKarg() {
 	cmdline := `nomodeset console=tty0,115200n8 console=ttyS0,115200n8 root=live:https://example.tld/image.squashfs`
 	k := kargs.NewKargs([]byte(cmdline))
@@ -119,17 +146,32 @@ func ExampleKargs_SetKarg_createReplace() {
 }
 
 func ExampleKargs_SetKarg_replaceMultiple() {
-	cmdline := `console=tty0,115200n8 console=ttyS0,115200n8`
+	cmdline := `loglevel=3 quiet loglevel=7`
+	k := kargs.NewKargs([]byte(cmdline))
+
+	err := k.SetKarg("loglevel", "4")
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+	}
+	fmt.Println(k)
+
+	// Output:
+	// loglevel=4 quiet
+}
+
+func ExampleKargs_SetKarg_accumulating() {
+	cmdline := `console=tty0 console=ttyS0,9600`
 	k := kargs.NewKargs([]byte(cmdline))
 
-	err := k.SetKarg("console", "tty1,115200n8")
+	// console is accumulating, so only the occurrence for ttyS0 is replaced.
+	err := k.SetKarg("console", "ttyS0,115200n8")
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 	}
 	fmt.Println(k)
 
 	// Output:
-	// console=tty1,115200n8
+	// console=tty0 console=ttyS0,115200n8
 }
 
 func ExampleKargs_String() {
@@ -141,6 +183,19 @@ func ExampleKargs_String() {
 	// nomodeset root=live:https://example.tld/image.squashfs console=tty0,115200n8 console=ttyS0,115200n8 printk.devkmsg=ratelimit printk.time=1
 }
 
+func ExampleMakeKarg() {
+	karg, err := kargs.MakeKarg("root", "live:https://example.tld/image with spaces.squashfs")
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+	}
+	fmt.Println(karg.Raw)
+	fmt.Println(karg.Value)
+
+	// Output:
+	// root="live:https://example.tld/image with spaces.squashfs"
+	// live:https://example.tld/image with spaces.squashfs
+}
+
 func ExampleNewKargs() {
 	cmdline := `nomodeset root=live:https://example.tld/image.squashfs console=tty0,115200n8 console=ttyS0,115200n8 printk.devkmsg=ratelimit printk.time=1`
 
@@ -176,3 +231,34 @@ func ExampleNewKargsEmpty() {
 	// ""
 	// "console=tty0,115200n8"
 }
+
+func ExampleQuoteValue() {
+	quoted := kargs.QuoteValue(`say "hello world"`)
+	fmt.Println(quoted)
+
+	unquoted, err := kargs.UnquoteValue(quoted)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+	}
+	fmt.Println(unquoted)
+
+	// Output:
+	// "say \"hello world\""
+	// say "hello world"
+}
+
+func ExampleTokenize() {
+	cmdline := `root=/dev/sda1 quiet init="/sbin/init --verbose"`
+	err := kargs.Tokenize(cmdline, func(t kargs.Token) error {
+		fmt.Printf("%d-%d: key %q, value %q\n", t.Start, t.End, t.Key, t.Value)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+	}
+
+	// Output:
+	// 0-14: key "root", value "/dev/sda1"
+	// 15-20: key "quiet", value ""
+	// 21-48: key "init", value "/sbin/init --verbose"
+}
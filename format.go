@@ -0,0 +1,100 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"io"
+)
+
+// FormatOption changes how Format renders a Kargs.
+type FormatOption func(*formatConfig)
+
+// formatConfig holds the settings applied by FormatOptions.
+type formatConfig struct {
+	separator  string // Written between kargs
+	terminator string // Written after the last karg
+	sorted     bool   // Whether to write kargs in the order of Sort
+}
+
+// WithNUL terminates the rendered command line with a NUL byte, as expected
+// when passing it to kexec_file_load(2). It replaces any terminator set by an
+// earlier option.
+func WithNUL() FormatOption {
+	return func(cfg *formatConfig) {
+		cfg.terminator = "\x00"
+	}
+}
+
+// WithNewline terminates the rendered command line with a newline, as is
+// conventional for files like /etc/kernel/cmdline. It replaces any terminator
+// set by an earlier option.
+func WithNewline() FormatOption {
+	return func(cfg *formatConfig) {
+		cfg.terminator = "\n"
+	}
+}
+
+// WithSortedOutput writes the kargs in the order Sort would put them in,
+// without changing k.
+func WithSortedOutput() FormatOption {
+	return func(cfg *formatConfig) {
+		cfg.sorted = true
+	}
+}
+
+// WithSeparator separates kargs with sep instead of a single space, e.g. to
+// display one karg per line. Note that the result is only a valid command line
+// if sep consists of whitespace.
+func WithSeparator(sep string) FormatOption {
+	return func(cfg *formatConfig) {
+		cfg.separator = sep
+	}
+}
+
+// newFormatConfig applies opts on top of the default settings.
+func newFormatConfig(opts ...FormatOption) formatConfig {
+	cfg := formatConfig{separator: " "}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Format returns the karg list in string form like String, changed by opts.
+// Without options, it returns the same as String.
+func (k *Kargs) Format(opts ...FormatOption) string {
+	cfg := newFormatConfig(opts...)
+	if k.cfg.noBufferPool {
+		var buf bytes.Buffer
+		k.writeBuffer(&buf, cfg)
+		return buf.String()
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	k.writeBuffer(buf, cfg)
+	return buf.String()
+}
+
+// WriteFormatted writes the karg list in string form to w like Format, changed
+// by opts, e.g. WithNUL to hand it to kexec_file_load(2). It returns the number
+// of bytes written.
+func (k *Kargs) WriteFormatted(w io.Writer, opts ...FormatOption) (int64, error) {
+	cfg := newFormatConfig(opts...)
+	if k.cfg.noBufferPool {
+		var buf bytes.Buffer
+		k.writeBuffer(&buf, cfg)
+		return buf.WriteTo(w)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	k.writeBuffer(buf, cfg)
+	return buf.WriteTo(w)
+}
+
+// WriteTo writes the karg list in string form, as returned by String, to w. It
+// implements io.WriterTo; use WriteFormatted to change the form.
+func (k *Kargs) WriteTo(w io.Writer) (int64, error) {
+	return k.WriteFormatted(w)
+}
@@ -0,0 +1,65 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_Format(t *testing.T) {
+	k := NewKargs([]byte(`root=/dev/sda1 quiet init="/sbin/init --verbose"`))
+
+	checks := []struct {
+		opts []FormatOption
+		want string
+	}{
+		{opts: nil, want: `root=/dev/sda1 quiet init="/sbin/init --verbose"`},
+		{opts: []FormatOption{WithNewline()}, want: "root=/dev/sda1 quiet init=\"/sbin/init --verbose\"\n"},
+		{opts: []FormatOption{WithNUL()}, want: "root=/dev/sda1 quiet init=\"/sbin/init --verbose\"\x00"},
+		{opts: []FormatOption{WithSeparator("\n"), WithNewline()}, want: "root=/dev/sda1\nquiet\ninit=\"/sbin/init --verbose\"\n"},
+		{opts: []FormatOption{WithNewline(), WithNUL()}, want: "root=/dev/sda1 quiet init=\"/sbin/init --verbose\"\x00"},
+		{opts: []FormatOption{WithSortedOutput(), WithSeparator(",")}, want: `init="/sbin/init --verbose",quiet,root=/dev/sda1`},
+	}
+	for _, check := range checks {
+		assert.Equal(t, check.want, k.Format(check.opts...))
+	}
+
+	assert.Equal(t, "\n", NewKargsEmpty().Format(WithNewline()))
+	assert.Equal(t, "\n", NewKargsEmpty().Format(WithSortedOutput(), WithNewline()))
+
+	// Sorting leaves k itself unchanged
+	assert.Equal(t, `root=/dev/sda1 quiet init="/sbin/init --verbose"`, k.String())
+}
+
+func TestKargs_Format_withoutBufferPool(t *testing.T) {
+	k := NewKargs([]byte("key1 key2"), WithoutBufferPool())
+	assert.Equal(t, "key1,key2", k.Format(WithSeparator(",")))
+}
+
+func TestKargs_WriteTo(t *testing.T) {
+	k := NewKargs([]byte("key1 key2=val"))
+	var buf bytes.Buffer
+	n, err := k.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), n)
+	assert.Equal(t, "key1 key2=val", buf.String())
+}
+
+func TestKargs_WriteFormatted(t *testing.T) {
+	k := NewKargs([]byte(`root=/dev/sda1 init="/sbin/init --verbose"`))
+	var buf bytes.Buffer
+	n, err := k.WriteFormatted(&buf, WithNUL())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(43), n)
+	assert.Equal(t, "root=/dev/sda1 init=\"/sbin/init --verbose\"\x00", buf.String())
+
+	buf.Reset()
+	k = NewKargs([]byte("key1 key2"), WithoutBufferPool())
+	_, err = k.WriteFormatted(&buf, WithSeparator("\n"), WithNewline())
+	assert.NoError(t, err)
+	assert.Equal(t, "key1\nkey2\n", buf.String())
+}
@@ -16,6 +16,10 @@ package kargs
 
 import (
 	"fmt"
+	"iter"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -26,26 +30,110 @@ type Karg struct {
 	Value        string
 }
 
+// MakeKarg returns a fully populated Karg for key and value using the same
+// rules as SetKarg, i.e. value is quoted in raw form if needed and dequoted in
+// the Value field. An empty value produces a karg without a value. An error is
+// returned if key contains invalid characters.
+func MakeKarg(key, value string) (Karg, error) {
+	return newParseConfig().makeKarg(key, value)
+}
+
 func (k Karg) String() string {
 	return k.Raw
 }
 
-// Kargs provides a way to easily parse through kernel command line arguments
+// Kargs provides a way to easily parse through kernel command line arguments.
+// A Kargs is not safe for concurrent use; this includes calls to String, which
+// caches its result.
 type Kargs struct {
 	list      *kargItem              // Linked list of all kargs
 	last      *kargItem              // Pointer to last karg in linked list
 	keyMap    map[string][]*kargItem // Map of karg key to linked list item for faster reference
+	moduleMap map[string][]*kargItem // Map of module name to its kargs' linked list items
 	numParams int                    // Total kargs count
+	cfg       parseConfig            // Settings applied by ParseOptions
+	str       string                 // Cached string form, valid if strValid is set
+	strValid  bool                   // Whether str reflects the current kargs
+	claims    map[string]string      // Owner of each claimed canonical key or prefix
+	slab      []kargItem             // Items allocated ahead by parse, used up by newItem
 }
 
-// NewKargs returns a pointer to a Kargs struct parsed from line.
-func NewKargs(line []byte) *Kargs {
-	return parse(line)
+// NewKargs returns a pointer to a Kargs struct parsed from line. opts can be
+// used to change how line is parsed. If a limit set by opts is exceeded, the
+// kargs before the offending one are returned; use ParseKargs to find out.
+func NewKargs(line []byte, opts ...ParseOption) *Kargs {
+	k, _ := parse(line, opts...)
+	return k
 }
 
 // NewKargsEmpty is like NewKargs, but creates a new Kargs that is empty.
-func NewKargsEmpty() *Kargs {
-	return NewKargs([]byte{})
+func NewKargsEmpty(opts ...ParseOption) *Kargs {
+	return NewKargs([]byte{}, opts...)
+}
+
+// ParseKargs is like NewKargs, but returns an error if line exceeds a limit set
+// by opts, like WithMaxParams. The error is a *LimitError wrapping
+// ErrLimitExceeded. This is meant for parsing untrusted input, which should be
+// rejected rather than used partially.
+func ParseKargs(line []byte, opts ...ParseOption) (*Kargs, error) {
+	k, err := parse(line, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// AddKargValue appends key with value like AppendKarg, unless key already occurs
+// with that value, so that e.g. ensuring console=ttyS0,115200 is set can be
+// repeated without adding it twice or touching other console= occurrences.
+// Values are compared after dequoting, and an empty value matches key without a
+// value. An error is returned if key or value are invalid.
+func (k *Kargs) AddKargValue(key, value string) error {
+	if k.HasKargValue(key, value) {
+		return nil
+	}
+	return k.AppendKarg(key, value)
+}
+
+// All returns an iterator over all kargs of k in command line order, e.g. for
+// use in a range loop. The kargs are read from k as the iteration proceeds, so
+// k must not be modified during it.
+func (k *Kargs) All() iter.Seq[Karg] {
+	return func(yield func(Karg) bool) {
+		for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+			if !yield(llTracker.karg) {
+				return
+			}
+		}
+	}
+}
+
+// AllItems is like All, but the iterator also yields the position of each
+// karg, counting from 0.
+func (k *Kargs) AllItems() iter.Seq2[int, Karg] {
+	return func(yield func(int, Karg) bool) {
+		idx := 0
+		for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+			if !yield(idx, llTracker.karg) {
+				return
+			}
+			idx++
+		}
+	}
+}
+
+// AppendKarg adds key with value to the end of the kernel command line argument
+// list, even if key already exists, so that it occurs once more. This is meant
+// for parameters like console= that legitimately appear multiple times; use
+// SetKarg to have a key occur only once. An empty value adds key without a
+// value. An error is returned if key or value are invalid.
+func (k *Kargs) AppendKarg(key, value string) error {
+	newKarg, err := k.cfg.makeKarg(key, value)
+	if err != nil {
+		return err
+	}
+	k.appendItem(newKarg)
+	return nil
 }
 
 // AppendKargs parses line into kernel command line arguments and appends them
@@ -66,28 +154,53 @@ func (k *Kargs) AppendKargs(line string) {
 		}
 
 		// Value does not exist yet, append key with new value
-		newKarg := Karg{
+		k.appendItem(Karg{
 			Key:          key,
 			CanonicalKey: canonicalKey,
 			Value:        value,
 			Raw:          flag,
-		}
-		newKargItem := &kargItem{
-			karg: newKarg,
-			prev: k.last,
-		}
-		if k.list == nil {
-			k.list = newKargItem
-			k.last = k.list
-		} else {
-			k.last.next = newKargItem
-			k.last = newKargItem
-		}
-		k.keyMap[canonicalKey] = append(k.keyMap[canonicalKey], newKargItem)
-		k.numParams++
+		})
 	})
 }
 
+// At returns the karg at position i of k, counting from 0 in command line order.
+// An error wrapping ErrOutOfRange is returned if there is no such karg.
+func (k *Kargs) At(i int) (Karg, error) {
+	item := k.itemAt(i)
+	if item == nil {
+		return Karg{}, fmt.Errorf("failed to get karg at position %d: %w", i, ErrOutOfRange)
+	}
+	return item.karg, nil
+}
+
+// Clear removes all kargs from k, leaving it empty but keeping its settings and
+// claims (see Claim), so that a long-lived Kargs can be reused instead of
+// allocating a new one with NewKargsEmpty.
+func (k *Kargs) Clear() {
+	k.reset()
+}
+
+// Clone returns a deep copy of k, with the same kargs in the same order and the
+// same settings and claims (see Claim), so that modifying either one never
+// affects the other. If k allocates from an Arena, so does the copy.
+func (k *Kargs) Clone() *Kargs {
+	clone := &Kargs{
+		keyMap:    make(map[string][]*kargItem, len(k.keyMap)),
+		moduleMap: make(map[string][]*kargItem, len(k.moduleMap)),
+		cfg:       k.cfg,
+	}
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		item := clone.appendItem(llTracker.karg)
+		item.oneShot = llTracker.oneShot
+		item.cond = llTracker.cond
+		item.source = llTracker.source
+	}
+	for claim, owner := range k.claims {
+		clone.claimFor(claim, owner)
+	}
+	return clone
+}
+
 // ContainsKarg verifies that the kernel command line argument identified by key
 // has been set, whether it has a value or not.
 func (k *Kargs) ContainsKarg(key string) bool {
@@ -95,15 +208,71 @@ func (k *Kargs) ContainsKarg(key string) bool {
 	return present
 }
 
+// ContainsExactKey is like ContainsKarg, but only reports whether key has been
+// set with exactly the given spelling, i.e. 'var_name' and 'var-name' are not
+// considered equivalent.
+func (k *Kargs) ContainsExactKey(key string) bool {
+	for _, ptr := range k.keyMap[canonicalizeKey(key)] {
+		if ptr.karg.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of occurrences of the kernel command line argument
+// identified by key, or 0 if it is not set.
+func (k *Kargs) Count(key string) int {
+	return len(k.keyMap[canonicalizeKey(key)])
+}
+
+// Deduplicate removes redundant occurrences of parameters from k and returns how
+// many were removed. For parameters registered as accumulating (see
+// RegisterAccumulating), only occurrences repeating an earlier value are
+// removed; for all others, every occurrence but the last one is removed, since
+// the kernel only uses the last one.
+func (k *Kargs) Deduplicate() (int, error) {
+	kept := make(map[*kargItem]bool)
+	for _, item := range k.effectiveItems(true) {
+		kept[item] = true
+	}
+	removed := 0
+	for llTracker := k.list; llTracker != nil; {
+		next := llTracker.next
+		if !kept[llTracker] {
+			if err := k.removeItem(llTracker); err != nil {
+				return removed, fmt.Errorf("failed to remove duplicate of key %s: %w", llTracker.karg.Key, err)
+			}
+			removed++
+		}
+		llTracker = next
+	}
+	return removed, nil
+}
+
+// DeleteAt deletes the karg at position i of k, counting from 0 in command line
+// order. Unlike DeleteKargByValue, it can tell apart occurrences of a key with
+// identical values. An error wrapping ErrOutOfRange is returned if there is no
+// such karg.
+func (k *Kargs) DeleteAt(i int) error {
+	item := k.itemAt(i)
+	if item == nil {
+		return fmt.Errorf("failed to delete karg at position %d: %w", i, ErrOutOfRange)
+	}
+	return k.removeItem(item)
+}
+
 // DeleteKarg deletes all instances of key in the kernel command line argument
 // list, returning an error if it was not found or a removal error occurs.
 func (k *Kargs) DeleteKarg(key string) error {
 	canonicalKey := canonicalizeKey(key)
-	if _, exists := k.keyMap[key]; exists {
+	if _, exists := k.keyMap[canonicalKey]; exists {
 		for _, ptr := range k.keyMap[canonicalKey] {
-			if err := remove(ptr); err != nil {
+			if err := k.unlink(ptr); err != nil {
 				return fmt.Errorf("failed to delete key %s with value %s: %w", key, ptr.karg.Value, err)
 			} else {
+				k.invalidate()
+				k.unindexModule(ptr)
 				k.numParams--
 			}
 		}
@@ -115,63 +284,173 @@ func (k *Kargs) DeleteKarg(key string) error {
 	return nil
 }
 
+// DeleteKargs deletes all instances of each of keys, like DeleteKarg. It keeps
+// going when a key fails to be deleted, e.g. because it doesn't exist, and
+// returns a KeyErrors holding the failures, so errors.Is(err, ErrNotExists)
+// reports whether any key was missing and KeyErrors.Keys lists them. nil is
+// returned if all keys were deleted.
+func (k *Kargs) DeleteKargs(keys ...string) error {
+	var errs KeyErrors
+	for _, key := range keys {
+		if err := k.DeleteKarg(key); err != nil {
+			errs = append(errs, &KeyError{Key: key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// DeleteKargsRegexp deletes every karg whose canonical key matches re and
+// returns how many were deleted. re is not anchored implicitly, so e.g.
+// regexp.MustCompile(`^(ip|BOOTIF)$`) is needed to delete only ip= and BOOTIF=
+// rather than every key containing ip.
+func (k *Kargs) DeleteKargsRegexp(re *regexp.Regexp) (int, error) {
+	deleted := 0
+	for llTracker := k.list; llTracker != nil; {
+		next := llTracker.next
+		if re.MatchString(llTracker.karg.CanonicalKey) {
+			if err := k.removeItem(llTracker); err != nil {
+				return deleted, fmt.Errorf("failed to delete key %s: %w", llTracker.karg.Key, err)
+			}
+			deleted++
+		}
+		llTracker = next
+	}
+	return deleted, nil
+}
+
 // DeleteKarByValue only deletes the instance of key that has value of value.
 func (k *Kargs) DeleteKargByValue(key, value string) error {
 	canonicalKey := canonicalizeKey(key)
-	if _, exists := k.keyMap[key]; exists {
-		for idx, ptr := range k.keyMap[canonicalKey] {
-			if value == ptr.karg.Value {
-				if err := remove(ptr); err != nil {
-					return fmt.Errorf("failed to delete key %s with value %s: %w", key, ptr.karg.Value, err)
-				}
-				if len(k.keyMap[canonicalKey]) == 1 {
-					k.keyMap[canonicalKey] = []*kargItem{}
-				} else if idx == len(k.keyMap[canonicalKey])-1 {
-					l := len(k.keyMap[canonicalKey]) - 1
-					k.keyMap[canonicalKey] = k.keyMap[canonicalKey][:l-1]
-				} else if idx == 0 {
-					k.keyMap[canonicalKey] = k.keyMap[canonicalKey][1:]
-				} else {
-					k.keyMap[canonicalKey] = append(k.keyMap[canonicalKey][:idx], k.keyMap[canonicalKey][(idx+1):]...)
-				}
-				k.numParams--
-				return nil
+	items, exists := k.keyMap[canonicalKey]
+	if !exists {
+		return fmt.Errorf("failed to delete key %s: %w", key, ErrNotExists)
+	}
+	for _, ptr := range items {
+		if value == ptr.karg.Value {
+			if err := k.removeItem(ptr); err != nil {
+				return fmt.Errorf("failed to delete key %s with value %s: %w", key, ptr.karg.Value, err)
 			}
+			return nil
 		}
-	} else {
-		return fmt.Errorf("failed to delete key %s: %w", key, ErrNotExists)
 	}
-
 	return fmt.Errorf("could not find value %s for key %s: %w", value, key, ErrNotExists)
 }
 
+// DuplicateKeys returns the canonical keys of all kernel command line arguments
+// that occur more than once, in the order of their first occurrence. Parameters
+// registered as accumulating (see IsAccumulating) are included as well, since
+// whether their duplicates are intended is up to the caller.
+func (k *Kargs) DuplicateKeys() []string {
+	var keys []string
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		occurrences := k.keyMap[llTracker.karg.CanonicalKey]
+		if len(occurrences) > 1 && occurrences[0] == llTracker {
+			keys = append(keys, llTracker.karg.CanonicalKey)
+		}
+	}
+	return keys
+}
+
+// Effective returns a new Kargs containing the parameters of k as the kernel
+// applies them: if a key occurs more than once, only its last occurrence is
+// kept, except for parameters registered as accumulating (see
+// RegisterAccumulating), like console= and initrd=, whose occurrences all take
+// effect. The parameters keep their relative order.
+func (k *Kargs) Effective() *Kargs {
+	effective := NewKargsEmpty()
+	for _, item := range k.effectiveItems(false) {
+		effective.appendItem(item.karg)
+	}
+	return effective
+}
+
+// EqualString reports whether cmdline contains the same parameters as k, in the
+// same order, comparing canonical keys and dequoted values. Differences in
+// spelling, quoting, or whitespace therefore don't matter. cmdline is compared
+// while it is tokenized, stopping at the first difference, without building a
+// Kargs from it.
+func (k *Kargs) EqualString(cmdline string) bool {
+	item := k.list
+	err := Tokenize(cmdline, func(t Token) error {
+		if item == nil || item.karg.CanonicalKey != t.CanonicalKey || item.karg.Value != k.cfg.dequote(t.RawValue) {
+			return errStop
+		}
+		item = item.next
+		return nil
+	})
+	return err == nil && item == nil
+}
+
+// Filter returns a new Kargs containing only the kargs of k for which keep
+// returns true, in their original order. The returned Kargs is a copy, so
+// modifying it does not affect k.
+func (k *Kargs) Filter(keep func(Karg) bool) *Kargs {
+	return k.filter(keep)
+}
+
+// FindValueSubstring returns the kargs of k whose dequoted value contains
+// substr, in command line order, e.g. to find every parameter referring to a
+// given host or path. The comparison is case-sensitive; see
+// FindValueSubstringFold. Kargs without a value never match.
+func (k *Kargs) FindValueSubstring(substr string) []Karg {
+	return k.findValues(func(value string) bool {
+		return strings.Contains(value, substr)
+	})
+}
+
+// FindValueSubstringFold is like FindValueSubstring, but ignores case, so that
+// e.g. host names are found however they are spelled.
+func (k *Kargs) FindValueSubstringFold(substr string) []Karg {
+	substr = strings.ToLower(substr)
+	return k.findValues(func(value string) bool {
+		return strings.Contains(strings.ToLower(value), substr)
+	})
+}
+
+// findValues returns the kargs of k that have a value for which match returns
+// true, in command line order.
+func (k *Kargs) findValues(match func(value string) bool) []Karg {
+	var found []Karg
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if llTracker.karg.Value != "" && match(llTracker.karg.Value) {
+			found = append(found, llTracker.karg)
+		}
+	}
+	return found
+}
+
 // FlagsForModule gets all flags for a designated module and returns them as a
 // space-seperated string designed to be passed to insmod. Note that similarly
 // to flags, module names with - and _ are treated the same.
 func (k *Kargs) FlagsForModule(name string) string {
-	var ret string
-	flagsAdded := make(map[string]bool) // Ensures duplicate flags aren't both added
 	// Module flags come as moduleName.flag in /proc/cmdline
 	prefix := canonicalizeKey(name) + "."
-	first := true
-	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
-		canonicalFlag := canonicalizeKey(llTracker.karg.Key)
-		if !flagsAdded[canonicalFlag] && strings.HasPrefix(canonicalFlag, prefix) {
-			flagsAdded[canonicalFlag] = true
-			if !first {
-				ret += " "
-			} else {
-				first = false
-			}
-			// They are passed to insmod space seperated as flag=val
-			if llTracker.karg.Value == "" {
-				ret += strings.TrimPrefix(canonicalFlag, prefix)
-			} else {
-				ret += strings.TrimPrefix(canonicalFlag, prefix) + "=" + llTracker.karg.Value
-			}
+	mod, _ := moduleName(prefix)
+	var sb strings.Builder
+	for _, item := range k.moduleMap[mod] {
+		canonicalFlag := item.karg.CanonicalKey
+		if !strings.HasPrefix(canonicalFlag, prefix) {
+			continue
+		}
+		// Only the first occurrence of a flag is passed, which is the
+		// first item stored for its key.
+		if occurrences := k.keyMap[canonicalFlag]; len(occurrences) > 0 && occurrences[0] != item {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		// They are passed to insmod space seperated as flag=val
+		sb.WriteString(canonicalFlag[len(prefix):])
+		if item.karg.Value != "" {
+			sb.WriteByte('=')
+			sb.WriteString(item.karg.Value)
 		}
 	}
-	return ret
+	return sb.String()
 }
 
 // GetKarg returns the value list of the karg identified by key, as well as
@@ -186,30 +465,575 @@ func (k *Kargs) GetKarg(key string) ([]string, bool) {
 	return vals, present
 }
 
+// GetKargJoined returns the dequoted values of the karg identified by key joined
+// by sep, in command line order, as well as whether it was set. Occurrences
+// without a value contribute an empty string.
+func (k *Kargs) GetKargJoined(key, sep string) (string, bool) {
+	vals, present := k.GetKarg(key)
+	return strings.Join(vals, sep), present
+}
+
+// GetKargCSV returns the dequoted values of the karg identified by key, each
+// split on commas into its fields, in command line order, as well as whether it
+// was set, e.g. [[ttyS0 115200n8]] for console=ttyS0,115200n8. Commas within
+// double quotes inside a value don't split it, and the quotes are removed from
+// the field, so that a,"b,c" yields [a b,c]. An unterminated quote extends to
+// the end of the value. Occurrences without a value contribute an empty list.
+func (k *Kargs) GetKargCSV(key string) ([][]string, bool) {
+	vals, present := k.GetKarg(key)
+	if !present {
+		return nil, false
+	}
+	fields := make([][]string, len(vals))
+	for idx, val := range vals {
+		fields[idx] = splitCSV(val)
+	}
+	return fields, true
+}
+
+// splitCSV splits value on commas outside of double quotes, removing the
+// quotes. An empty value yields an empty list.
+func splitCSV(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	var (
+		fields  []string
+		field   strings.Builder
+		inQuote bool
+	)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"':
+			inQuote = !inQuote
+		case c == ',' && !inQuote:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// GetKargsByPrefix returns all kargs of k whose canonical key starts with
+// prefix, e.g. "rd.net." or "systemd.", in command line order. Like in keys, -
+// and _ in prefix are equivalent. Unlike FlagsForModule, prefix may span any
+// number of dot-separated parts.
+func (k *Kargs) GetKargsByPrefix(prefix string) []Karg {
+	prefix = canonicalizeKey(prefix)
+	var kargs []Karg
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if strings.HasPrefix(llTracker.karg.CanonicalKey, prefix) {
+			kargs = append(kargs, llTracker.karg)
+		}
+	}
+	return kargs
+}
+
+// GetKargsGlob returns all kargs of k whose canonical key matches the shell
+// glob pattern, e.g. "rd.*" or "systemd.log_*", in command line order. The
+// syntax is that of path.Match, and - and _ in pattern are equivalent like in
+// keys. An error wrapping path.ErrBadPattern is returned if pattern is
+// malformed.
+func (k *Kargs) GetKargsGlob(pattern string) ([]Karg, error) {
+	pattern, err := globPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var kargs []Karg
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if matched, _ := path.Match(pattern, llTracker.karg.CanonicalKey); matched {
+			kargs = append(kargs, llTracker.karg)
+		}
+	}
+	return kargs, nil
+}
+
+// HasKargValue reports whether key occurs in k with value, comparing canonical
+// keys and dequoted values. An empty value matches occurrences of key without a
+// value.
+func (k *Kargs) HasKargValue(key, value string) bool {
+	for _, ptr := range k.keyMap[canonicalizeKey(key)] {
+		if ptr.karg.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// InsertKargAfter adds key with value right after the last occurrence of
+// anchorKey, following the rules of AppendKarg. This places the new karg at a
+// specific position, which matters for order-sensitive parameters like console=,
+// where the last one wins. An error wrapping ErrNotExists is returned if
+// anchorKey isn't present, and one wrapping ErrInvalidKey if key is invalid.
+func (k *Kargs) InsertKargAfter(anchorKey, key, value string) error {
+	anchors := k.keyMap[canonicalizeKey(anchorKey)]
+	if len(anchors) == 0 {
+		return fmt.Errorf("failed to insert key %s after %s: %w", key, anchorKey, ErrNotExists)
+	}
+	newKarg, err := k.cfg.makeKarg(key, value)
+	if err != nil {
+		return err
+	}
+	k.insertBefore(anchors[len(anchors)-1].next, newKarg)
+	return nil
+}
+
+// InsertKargBefore adds key with value right before the first occurrence of
+// anchorKey, like InsertKargAfter.
+func (k *Kargs) InsertKargBefore(anchorKey, key, value string) error {
+	anchors := k.keyMap[canonicalizeKey(anchorKey)]
+	if len(anchors) == 0 {
+		return fmt.Errorf("failed to insert key %s before %s: %w", key, anchorKey, ErrNotExists)
+	}
+	newKarg, err := k.cfg.makeKarg(key, value)
+	if err != nil {
+		return err
+	}
+	k.insertBefore(anchors[0], newKarg)
+	return nil
+}
+
+// Kargs returns a snapshot of all kargs of k in command line order. The slice
+// is a copy, so modifying it does not affect k and later changes to k are not
+// reflected in it.
+func (k *Kargs) Kargs() []Karg {
+	kargs := make([]Karg, 0, k.numParams)
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		kargs = append(kargs, llTracker.karg)
+	}
+	return kargs
+}
+
+// Keys returns the keys of all kargs in command line order, as they were
+// written. Keys occurring more than once are returned once per occurrence; see
+// UniqueKeys for a deduplicated list.
+func (k *Kargs) Keys() []string {
+	keys := make([]string, 0, k.numParams)
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		keys = append(keys, llTracker.karg.Key)
+	}
+	return keys
+}
+
+// KeysMatching returns the keys of k whose canonical form matches the shell
+// glob pattern as described at GetKargsGlob, in command line order and once per
+// key like UniqueKeys. An error wrapping path.ErrBadPattern is returned if
+// pattern is malformed.
+func (k *Kargs) KeysMatching(pattern string) ([]string, error) {
+	pattern, err := globPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, key := range k.UniqueKeys() {
+		if matched, _ := path.Match(pattern, canonicalizeKey(key)); matched {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// globPattern returns the canonical form of the glob pattern, in which hyphens
+// outside of character classes are replaced by underscores, or an error if it is
+// malformed.
+func globPattern(pattern string) (string, error) {
+	canonical := []byte(pattern)
+	inClass := false
+	for i := 0; i < len(canonical); i++ {
+		switch c := canonical[i]; {
+		case c == '\\':
+			i++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '-' && !inClass:
+			canonical[i] = '_'
+		}
+	}
+	pattern = string(canonical)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("invalid key pattern %s: %w", pattern, err)
+	}
+	return pattern, nil
+}
+
+// Len returns the total number of kargs in k, counting each occurrence of a
+// key.
+func (k *Kargs) Len() int {
+	return k.numParams
+}
+
+// LoadModuleOptions returns the flags for the module identified by name as a
+// space-separated string ready to be passed to insmod or modprobe. Unlike
+// FlagsForModule, values containing whitespace are double-quoted so that the
+// kernel keeps them whole. An error is returned if name is not a valid module
+// name.
+func (k *Kargs) LoadModuleOptions(name string) (string, error) {
+	opts, err := k.moduleOptions(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(opts, " "), nil
+}
+
+// ModuleView returns a new Kargs containing only the parameters of the module
+// identified by name, in their original order. Like with FlagsForModule,
+// module names with - and _ are treated the same. The returned Kargs is a
+// copy, so modifying it does not affect k.
+func (k *Kargs) ModuleView(name string) *Kargs {
+	view := NewKargsEmpty()
+	for _, item := range k.moduleMap[canonicalizeKey(name)] {
+		view.appendItem(item.karg)
+	}
+	return view
+}
+
+// Modules returns the canonical names of all modules that have parameters set,
+// sorted alphabetically.
+func (k *Kargs) Modules() []string {
+	mods := make([]string, 0, len(k.moduleMap))
+	for mod := range k.moduleMap {
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	return mods
+}
+
+// MoveKarg moves all occurrences of key, keeping their order and raw form, so
+// that the first of them is at position, counting from 0, among the other
+// kargs; e.g. position 0 moves them to the front and Len()-Count(key) to the
+// end. An error wrapping ErrNotExists is returned if key isn't present, and one
+// wrapping ErrOutOfRange if position is out of range.
+func (k *Kargs) MoveKarg(key string, position int) error {
+	items := k.keyMap[canonicalizeKey(key)]
+	if len(items) == 0 {
+		return fmt.Errorf("failed to move key %s: %w", key, ErrNotExists)
+	}
+	if position < 0 || position > k.numParams-len(items) {
+		return fmt.Errorf("failed to move key %s to position %d: %w", key, position, ErrOutOfRange)
+	}
+	k.moveItems(items, func() *kargItem {
+		mark := k.list
+		for i := 0; i < position; i++ {
+			mark = mark.next
+		}
+		return mark
+	})
+	return nil
+}
+
+// MoveKargAfter moves all occurrences of key, keeping their order and raw
+// form, right after the last occurrence of anchorKey. Moving a key after itself
+// does nothing. An error wrapping ErrNotExists is returned if either key isn't
+// present.
+func (k *Kargs) MoveKargAfter(key, anchorKey string) error {
+	items := k.keyMap[canonicalizeKey(key)]
+	anchors := k.keyMap[canonicalizeKey(anchorKey)]
+	if len(items) == 0 || len(anchors) == 0 {
+		return fmt.Errorf("failed to move key %s after %s: %w", key, anchorKey, ErrNotExists)
+	}
+	if canonicalizeKey(key) == canonicalizeKey(anchorKey) {
+		return nil
+	}
+	k.moveItems(items, func() *kargItem {
+		return anchors[len(anchors)-1].next
+	})
+	return nil
+}
+
+// PrependKarg adds key with value to the front of the kernel command line
+// argument list, following the rules of AppendKarg. This is meant for early
+// boot parameters like earlycon= or rdinit= that conventionally come first.
+func (k *Kargs) PrependKarg(key, value string) error {
+	newKarg, err := k.cfg.makeKarg(key, value)
+	if err != nil {
+		return err
+	}
+	k.insertBefore(k.list, newKarg)
+	return nil
+}
+
+// RenameKarg changes the key of all occurrences of oldKey to newKey, keeping
+// their values, exactly as they were written, and their positions. If newKey is
+// already present, the renamed kargs become additional occurrences of it. An
+// error wrapping ErrNotExists is returned if oldKey isn't present, and one
+// wrapping ErrInvalidKey if newKey is invalid.
+func (k *Kargs) RenameKarg(oldKey, newKey string) error {
+	if err := checkKey(newKey); err != nil {
+		return fmt.Errorf("failed to rename key %s: %w", oldKey, err)
+	}
+	if newKey == "" {
+		return fmt.Errorf("failed to rename key %s to empty key: %w", oldKey, ErrInvalidKey)
+	}
+	oldCanonicalKey := canonicalizeKey(oldKey)
+	items := k.keyMap[oldCanonicalKey]
+	if len(items) == 0 {
+		return fmt.Errorf("failed to rename key %s: %w", oldKey, ErrNotExists)
+	}
+	newCanonicalKey := canonicalizeKey(newKey)
+	delete(k.keyMap, oldCanonicalKey)
+	for _, item := range items {
+		k.unindexModule(item)
+		karg := item.karg
+		if strings.HasPrefix(karg.Raw, karg.Key) {
+			karg.Raw = newKey + karg.Raw[len(karg.Key):]
+		} else {
+			karg.Raw = quotedKarg(newKey, karg.Value).Raw
+		}
+		karg.Key = newKey
+		karg.CanonicalKey = newCanonicalKey
+		item.karg = karg
+		k.keyMap[newCanonicalKey] = insertInOrder(k.keyMap[newCanonicalKey], item)
+		if mod, ok := moduleName(newCanonicalKey); ok {
+			k.moduleMap[mod] = insertInOrder(k.moduleMap[mod], item)
+		}
+	}
+	k.invalidate()
+	return nil
+}
+
+// ReplaceAll sets the value of every occurrence of key to value, keeping the
+// number and positions of the occurrences as well as the spelling of their keys.
+// Unlike SetKarg, which collapses a key to a single occurrence, this is meant
+// for parameters that legitimately repeat. An error is returned if key is not
+// set or value cannot be quoted according to the QuoteMode of k.
+func (k *Kargs) ReplaceAll(key, value string) error {
+	canonicalKey := canonicalizeKey(key)
+	occurrences := k.keyMap[canonicalKey]
+	if len(occurrences) == 0 {
+		return fmt.Errorf("failed to replace key %s: %w", key, ErrNotExists)
+	}
+	newKargs := make([]Karg, len(occurrences))
+	for idx, ptr := range occurrences {
+		newKarg, err := k.cfg.makeKarg(ptr.karg.Key, value)
+		if err != nil {
+			return fmt.Errorf("failed to replace key %s: %w", key, err)
+		}
+		newKargs[idx] = newKarg
+	}
+	for idx, ptr := range occurrences {
+		ptr.karg = newKargs[idx]
+	}
+	k.invalidate()
+	return nil
+}
+
+// ReplaceKargValue sets the value of the first occurrence of key whose value is
+// oldValue to newValue, keeping its position and the spelling of its key. Other
+// occurrences of key are left alone, so this can change e.g. a single console=
+// among several. An error wrapping ErrNotExists is returned if no occurrence of
+// key has oldValue, and one is returned if newValue cannot be quoted according
+// to the QuoteMode of k.
+func (k *Kargs) ReplaceKargValue(key, oldValue, newValue string) error {
+	for _, ptr := range k.keyMap[canonicalizeKey(key)] {
+		if ptr.karg.Value != oldValue {
+			continue
+		}
+		newKarg, err := k.cfg.makeKarg(ptr.karg.Key, newValue)
+		if err != nil {
+			return fmt.Errorf("failed to replace value %s of key %s: %w", oldValue, key, err)
+		}
+		ptr.karg = newKarg
+		k.invalidate()
+		return nil
+	}
+	return fmt.Errorf("could not find value %s for key %s: %w", oldValue, key, ErrNotExists)
+}
+
+// RewriteValues calls rewrite with the key and dequoted value of each karg of k,
+// in command line order, and replaces the value with the one returned if
+// rewrite returns true, quoting it according to the QuoteMode of k. It returns
+// the number of values replaced. Values rejected by the QuoteMode are left
+// unchanged and not counted. An empty value is passed for kargs without one,
+// and returning an empty value leaves the key without a value.
+func (k *Kargs) RewriteValues(rewrite func(key, value string) (string, bool)) int {
+	return k.rewriteValues(rewrite, nil)
+}
+
+// rewriteValues implements RewriteValues, calling rewritten, if not nil, with
+// the old and the new karg of each value it replaced.
+func (k *Kargs) rewriteValues(rewrite func(key, value string) (string, bool), rewritten func(oldKarg, newKarg Karg)) int {
+	count := 0
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		newValue, ok := rewrite(llTracker.karg.Key, llTracker.karg.Value)
+		if !ok {
+			continue
+		}
+		newKarg, err := k.cfg.makeKarg(llTracker.karg.Key, newValue)
+		if err != nil {
+			continue
+		}
+		if rewritten != nil {
+			rewritten(llTracker.karg, newKarg)
+		}
+		llTracker.karg = newKarg
+		count++
+	}
+	if count > 0 {
+		k.invalidate()
+	}
+	return count
+}
+
+// SetPosition selects where SetKarg leaves a key that already exists, since
+// bootloaders and conventions differ in where they expect it. Select it with
+// WithSetPosition or pass it to SetKargPosition.
+type SetPosition int
+
+const (
+	// SetKeepFirst sets the value at the position of the first occurrence
+	// of the key. This is the default.
+	SetKeepFirst SetPosition = iota
+
+	// SetKeepLast sets the value at the position of the last occurrence of
+	// the key, which is the one the kernel uses.
+	SetKeepLast
+
+	// SetMoveToEnd removes all occurrences of the key and appends it to the
+	// end of the command line, like a bootloader appending a parameter
+	// would.
+	SetMoveToEnd
+)
+
+// String returns the name of pos.
+func (pos SetPosition) String() string {
+	switch pos {
+	case SetKeepFirst:
+		return "keep-first"
+	case SetKeepLast:
+		return "keep-last"
+	case SetMoveToEnd:
+		return "move-to-end"
+	default:
+		return fmt.Sprintf("SetPosition(%d)", int(pos))
+	}
+}
+
 // SetKarg sets key to value.
 //
 // If the key doesn't exist, it is added. If the key exists, its value is set to
 // the new value. If the key exists with multiple values, all of the values are
 // removed and the first occurrence of the key has its value set to the new
-// value.
+// value, unless k was created with a different SetPosition, see WithSetPosition.
+// The value is quoted according to the QuoteMode of k, which may reject it.
+//
+// Keys registered as accumulating (see IsAccumulating) are not collapsed, since
+// each of their occurrences takes effect. Instead, only the occurrences
+// matching value are replaced: those whose value is the same up to the first
+// ',', '=', or ':', e.g. the device of console=ttyS0,115200, the variable of
+// systemd.setenv=FOO=1, or the connector of video=HDMI-A-1:1024x768. The first
+// of them takes the new value and the others are removed. If no occurrence
+// matches, the karg is added after the last occurrence of the key. The
+// SetPosition of k picks the occurrence as for other keys: SetKeepLast keeps the
+// last matching occurrence instead of the first, and SetMoveToEnd removes all
+// of them and appends the karg to the end, whether one matched or not.
 func (k *Kargs) SetKarg(key, value string) error {
-	if err := checkKey(key); err != nil {
-		return fmt.Errorf("key check failed: %w", err)
+	if IsAccumulating(key) {
+		return k.setAccumulating(key, value, k.cfg.setPosition)
 	}
-	canonicalKey := canonicalizeKey(key)
-	newKarg := Karg{
-		Key:          enquote(key),
-		CanonicalKey: canonicalKey,
-		Value:        dequote(value),
+	return k.SetKargPosition(key, value, k.cfg.setPosition)
+}
+
+// setAccumulating sets key, an accumulating key, to value as described in
+// SetKarg, placing it according to pos.
+func (k *Kargs) setAccumulating(key, value string, pos SetPosition) error {
+	newKarg, err := k.cfg.makeKarg(key, value)
+	if err != nil {
+		return err
 	}
-	if value == "" {
-		newKarg.Raw = enquote(key)
-	} else {
-		newKarg.Raw = fmt.Sprintf("%s=%s", key, enquote(value))
+	items := k.keyMap[newKarg.CanonicalKey]
+	var mark *kargItem
+	if len(items) > 0 {
+		mark = items[len(items)-1].next
+	}
+	id := accumulatingID(newKarg.Value)
+	var matches []*kargItem
+	for _, item := range items {
+		if accumulatingID(item.karg.Value) == id {
+			matches = append(matches, item)
+		}
+	}
+	var kept *kargItem
+	switch {
+	case len(matches) == 0 || pos == SetMoveToEnd:
+	case pos == SetKeepLast:
+		kept = matches[len(matches)-1]
+	default:
+		kept = matches[0]
+	}
+	for _, item := range matches {
+		if item == kept {
+			continue
+		}
+		if err := k.removeItem(item); err != nil {
+			return fmt.Errorf("failed to remove karg: %w", err)
+		}
+	}
+	switch {
+	case kept != nil:
+		kept.karg = newKarg
+		k.invalidate()
+	case pos == SetMoveToEnd:
+		k.appendItem(newKarg)
+	default:
+		k.insertBefore(mark, newKarg)
+	}
+	return nil
+}
+
+// accumulatingID returns the part of value, the value of an accumulating key,
+// that identifies what it applies to, as described in SetKarg.
+func accumulatingID(value string) string {
+	if idx := strings.IndexAny(value, ",=:"); idx >= 0 {
+		return value[:idx]
+	}
+	return value
+}
+
+// SetKargPosition is like SetKarg, but places the surviving occurrence of an
+// existing key according to pos instead of the SetPosition of k. It collapses
+// accumulating keys like all others.
+func (k *Kargs) SetKargPosition(key, value string, pos SetPosition) error {
+	newKarg, err := k.cfg.makeKarg(key, value)
+	if err != nil {
+		return err
 	}
-	newKargItem := &kargItem{
-		karg: newKarg,
+	items := k.keyMap[newKarg.CanonicalKey]
+	switch pos {
+	case SetKeepFirst:
+		return k.setKargFirst(newKarg)
+	case SetKeepLast, SetMoveToEnd:
+		if len(items) == 0 {
+			return k.setKargFirst(newKarg)
+		}
+		var (
+			mark *kargItem
+			line *cmdlineLine
+		)
+		if pos == SetKeepLast {
+			mark, line = items[len(items)-1].next, items[len(items)-1].line
+		}
+		for _, item := range items {
+			if err := k.removeItem(item); err != nil {
+				return fmt.Errorf("failed to remove karg: %w", err)
+			}
+		}
+		k.insertBefore(mark, newKarg).line = line
+		return nil
+	default:
+		return fmt.Errorf("failed to set key %s: %v: %w", key, pos, ErrUnsupported)
 	}
+}
+
+// setKargFirst sets newKarg as described in SetKarg, keeping the position of
+// the first occurrence of its key.
+func (k *Kargs) setKargFirst(newKarg Karg) error {
+	canonicalKey := newKarg.CanonicalKey
+	newKargItem := k.newItem(newKarg)
+	k.invalidate()
 	if ptrList, exists := k.keyMap[canonicalKey]; exists {
 		// Karg already exists with one or more values. Set the first
 		// value to the new one and remove all of the others.
@@ -227,12 +1051,14 @@ func (k *Kargs) SetKarg(key, value string) error {
 				if err := replace(ptr, newKargItem); err != nil {
 					return fmt.Errorf("failed to replace existing karg value: %w", err)
 				}
+				k.reindexModule(ptr, newKargItem)
 				k.keyMap[canonicalKey][pidx] = newKargItem
 				k.keyMap[canonicalKey] = []*kargItem{newKargItem}
 			} else {
-				if err := remove(ptr); err != nil {
+				if err := k.unlink(ptr); err != nil {
 					return fmt.Errorf("failed to remove karg: %w", err)
 				}
+				k.unindexModule(ptr)
 				k.numParams--
 			}
 		}
@@ -240,6 +1066,7 @@ func (k *Kargs) SetKarg(key, value string) error {
 		// Karg is new. Append it to the end of the list and set the
 		// last pointer to it.
 		k.keyMap[canonicalKey] = []*kargItem{newKargItem}
+		k.indexModule(newKargItem)
 		if k.list == nil {
 			k.list = newKargItem
 			k.last = k.list
@@ -254,12 +1081,101 @@ func (k *Kargs) SetKarg(key, value string) error {
 	return nil
 }
 
+// SetKargs sets each key of kargs to its value like SetKarg. Since maps are
+// unordered, keys are applied in alphabetical order, which is the order in
+// which new keys are added. Keys that fail do not stop the others from being
+// set; if any fail, an error of type KeyErrors describing them is returned.
+func (k *Kargs) SetKargs(kargs map[string]string) error {
+	keys := make([]string, 0, len(kargs))
+	for key := range kargs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs KeyErrors
+	for _, key := range keys {
+		if err := k.SetKarg(key, kargs[key]); err != nil {
+			errs = append(errs, &KeyError{Key: key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SetKargsOrdered is like SetKargs, but sets the Key of each karg to its Value
+// in the order given, so that new keys are added in that order. Other fields
+// of the kargs are ignored, so kargs made by MakeKarg are set as they are.
+func (k *Kargs) SetKargsOrdered(kargs []Karg) error {
+	var errs KeyErrors
+	for _, karg := range kargs {
+		if err := k.SetKarg(karg.Key, karg.Value); err != nil {
+			errs = append(errs, &KeyError{Key: karg.Key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 // String returns the karg list in string form, ready to be used as a kernel
-// command line argument string.
+// command line argument string. The result is cached until k is modified, so
+// repeated calls in between are cheap.
 func (k *Kargs) String() string {
-	var s []string
+	if k.strValid {
+		return k.str
+	}
+	k.str = k.Format()
+	k.strValid = true
+	return k.str
+}
+
+// TakeKarg returns the values of the karg identified by key like GetKarg and
+// removes all its occurrences from k, so that a parameter meant to be consumed
+// once, e.g. rd.break, is not passed on in the string form of k. An error is
+// returned if a removal error occurs.
+func (k *Kargs) TakeKarg(key string) ([]string, bool, error) {
+	canonicalKey := canonicalizeKey(key)
+	items, present := k.keyMap[canonicalKey]
+	if !present {
+		return nil, false, nil
+	}
+	var vals []string
+	for _, ptr := range items {
+		if err := k.unlink(ptr); err != nil {
+			return nil, true, fmt.Errorf("failed to take key %s with value %s: %w", key, ptr.karg.Value, err)
+		}
+		k.invalidate()
+		vals = append(vals, ptr.karg.Value)
+		k.unindexModule(ptr)
+		k.numParams--
+	}
+	delete(k.keyMap, canonicalKey)
+	return vals, true, nil
+}
+
+// ToMap returns the kargs of k as a map of canonical keys to their dequoted
+// values, in command line order. Occurrences without a value contribute an empty
+// string. The map is a copy, so modifying it does not affect k.
+func (k *Kargs) ToMap() map[string][]string {
+	m := make(map[string][]string, len(k.keyMap))
 	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
-		s = append(s, llTracker.karg.String())
+		key := llTracker.karg.CanonicalKey
+		m[key] = append(m[key], llTracker.karg.Value)
+	}
+	return m
+}
+
+// UniqueKeys returns the keys of all kargs in command line order like Keys, but
+// only lists each key at its first occurrence. Keys that differ only in - and _
+// are the same key; the spelling of the first occurrence is returned.
+func (k *Kargs) UniqueKeys() []string {
+	keys := make([]string, 0, len(k.keyMap))
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if items := k.keyMap[llTracker.karg.CanonicalKey]; len(items) > 0 && items[0] == llTracker {
+			keys = append(keys, llTracker.karg.Key)
+		}
 	}
-	return strings.Join(s, " ")
+	return keys
 }
@@ -4,11 +4,87 @@
 package kargs
 
 import (
+	"path"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// benchCmdline is a command line with a realistic mix of core and module
+// parameters used by benchmarks.
+const benchCmdline = `BOOT_IMAGE=/vmlinuz root=live:https://example.tld/image.squashfs ro console=tty0,115200n8 console=ttyS0,115200n8 nomodeset printk.devkmsg=ratelimit printk.time=1 nvme_core.multipath=Y nvme_core.io_timeout=4294967295 i915.modeset=0 rd.neednet=1 rd.shell ip=dhcp systemd.unified_cgroup_hierarchy=1 mitigations=auto,nosmt crashkernel=512M quiet`
+
+func TestKargs_AddKargValue(t *testing.T) {
+	k := NewKargs([]byte(`console=tty0 quiet msg="a b"`))
+
+	assert.NoError(t, k.AddKargValue("console", "ttyS0,115200"))
+	assert.NoError(t, k.AddKargValue("console", "ttyS0,115200"))
+	assert.NoError(t, k.AddKargValue("console", "tty0"))
+	assert.NoError(t, k.AddKargValue("quiet", ""))
+	assert.NoError(t, k.AddKargValue("msg", "a b"))
+	assert.NoError(t, k.AddKargValue("splash", ""))
+	assert.Equal(t, `console=tty0 quiet msg="a b" console=ttyS0,115200 splash`, k.String())
+	assert.Equal(t, 5, k.Len())
+
+	assert.ErrorIs(t, k.AddKargValue("invalid key", "val"), ErrInvalidKey)
+	assert.Equal(t, 5, k.Len())
+}
+
+func TestKargs_All(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 rd-break console=tty0 quiet"))
+
+	var keys []string
+	for karg := range k.All() {
+		if karg.Key == "quiet" {
+			break
+		}
+		keys = append(keys, karg.Key)
+	}
+	assert.Equal(t, []string{"root", "rd-break", "console"}, keys)
+
+	for range NewKargsEmpty().All() {
+		t.Fatal("empty Kargs yielded a karg")
+	}
+}
+
+func TestKargs_AllItems(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 rd-break console=tty0 quiet"))
+
+	var positions []int
+	var values []string
+	for idx, karg := range k.AllItems() {
+		positions = append(positions, idx)
+		values = append(values, karg.Value)
+		if idx == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2}, positions)
+	assert.Equal(t, []string{"/dev/sda1", "", "tty0"}, values)
+}
+
+func TestKargs_AppendKarg(t *testing.T) {
+	k := NewKargs([]byte("console=tty0 quiet"))
+
+	err := k.AppendKarg("console", "ttyS0,115200n8")
+	assert.NoError(t, err)
+	err = k.AppendKarg("console", "tty0")
+	assert.NoError(t, err)
+	err = k.AppendKarg("splash", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, k.numParams)
+	assert.Len(t, k.keyMap, 3)
+	assert.Equal(t, "console=tty0 quiet console=ttyS0,115200n8 console=tty0 splash", k.String())
+	vals, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0,115200n8", "tty0"}, vals)
+
+	err = k.AppendKarg("invalid key", "val")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.Equal(t, 5, k.numParams)
+}
+
 func TestKargs_AppendKargs_existingVal(t *testing.T) {
 	k := NewKargs([]byte(`key=val1 key=val2 key=val3`))
 
@@ -62,12 +138,161 @@ func TestKargs_AppendKargs_novalToVal(t *testing.T) {
 	assert.Equal(t, []string{"", "val"}, vals)
 }
 
+func TestKargs_At(t *testing.T) {
+	k := NewKargs([]byte(`root=/dev/sda1 quiet msg="a b"`))
+
+	karg, err := k.At(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "root", karg.Key)
+	karg, err = k.At(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "a b", karg.Value)
+
+	_, err = k.At(3)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = k.At(-1)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = NewKargsEmpty().At(0)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+}
+
+func TestKargs_Clear(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 usbcore.autosuspend=-1"), WithQuoteMode(QuoteAlways))
+	assert.Equal(t, "root=/dev/sda1 console=tty0 usbcore.autosuspend=-1", k.String())
+
+	k.Clear()
+	assert.Equal(t, 0, k.Len())
+	assert.Empty(t, k.String())
+	assert.Empty(t, k.Modules())
+	assert.False(t, k.ContainsKarg("root"))
+
+	assert.NoError(t, k.SetKarg("init", "/sbin/init"))
+	assert.NoError(t, k.AppendKarg("usbcore.autosuspend", "-1"))
+	assert.Equal(t, `init="/sbin/init" usbcore.autosuspend="-1"`, k.String())
+	assert.Equal(t, []string{"usbcore"}, k.Modules())
+}
+
+func TestKargs_Clone(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 usbcore.autosuspend=-1 console=ttyS0"), WithQuoteMode(QuoteAlways))
+	assert.NoError(t, k.MarkOneShot("console"))
+
+	clone := k.Clone()
+	assert.Equal(t, k.String(), clone.String())
+	assert.Equal(t, k.Len(), clone.Len())
+	assert.Equal(t, k.Modules(), clone.Modules())
+
+	assert.NoError(t, clone.SetKarg("root", "/dev/sda2"))
+	assert.NoError(t, clone.DeleteKarg("usbcore.autosuspend"))
+	assert.Equal(t, `root="/dev/sda2" console=tty0 console=ttyS0`, clone.String())
+	assert.Equal(t, "root=/dev/sda1 console=tty0 usbcore.autosuspend=-1 console=ttyS0", k.String())
+	assert.Equal(t, []string{"usbcore"}, k.Modules())
+
+	consumed, err := clone.ConsumeOneShot()
+	assert.NoError(t, err)
+	assert.Len(t, consumed, 2)
+	assert.Equal(t, `root="/dev/sda2"`, clone.String())
+	assert.Equal(t, 4, k.Len())
+}
+
 func TestKargs_ContainsKarg(t *testing.T) {
 	k := NewKargs([]byte("test1"))
 	assert.True(t, k.ContainsKarg("test1"))
 	assert.False(t, k.ContainsKarg("test2"))
 }
 
+func TestKargs_ContainsExactKey(t *testing.T) {
+	k := NewKargs([]byte("with-dashes with_both with-both"))
+	assert.True(t, k.ContainsExactKey("with-dashes"))
+	assert.False(t, k.ContainsExactKey("with_dashes"))
+	assert.True(t, k.ContainsExactKey("with_both"))
+	assert.True(t, k.ContainsExactKey("with-both"))
+	assert.False(t, k.ContainsExactKey("nonexistent"))
+}
+
+func TestKargs_Count(t *testing.T) {
+	k := NewKargs([]byte("noval key=val1 with-dashes key=val2 with_dashes=val"))
+	assert.Equal(t, 1, k.Count("noval"))
+	assert.Equal(t, 2, k.Count("key"))
+	assert.Equal(t, 2, k.Count("with-dashes"))
+	assert.Equal(t, 0, k.Count("nonexistent"))
+}
+
+func TestKargs_Deduplicate(t *testing.T) {
+	k := NewKargs([]byte("console=tty0 root=a console=ttyS0 quiet console=tty0 root=b quiet initrd=x"))
+
+	removed, err := k.Deduplicate()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	assert.Equal(t, "console=tty0 console=ttyS0 root=b quiet initrd=x", k.String())
+	assert.Equal(t, 5, k.numParams)
+	vals, _ := k.GetKarg("root")
+	assert.Equal(t, []string{"b"}, vals)
+	vals, _ = k.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0"}, vals)
+
+	// Nothing left to remove
+	removed, err = k.Deduplicate()
+	assert.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestKargs_DeleteAt(t *testing.T) {
+	k := NewKargs([]byte("console=ttyS0 root=/dev/sda1 console=ttyS0 usbcore.autosuspend=-1 console=ttyS0"))
+
+	assert.NoError(t, k.DeleteAt(2))
+	assert.Equal(t, "console=ttyS0 root=/dev/sda1 usbcore.autosuspend=-1 console=ttyS0", k.String())
+	assert.Equal(t, 2, k.Count("console"))
+
+	assert.NoError(t, k.DeleteAt(3))
+	assert.NoError(t, k.DeleteAt(0))
+	assert.Equal(t, "root=/dev/sda1 usbcore.autosuspend=-1", k.String())
+	assert.False(t, k.ContainsKarg("console"))
+
+	assert.NoError(t, k.DeleteAt(1))
+	assert.Empty(t, k.Modules())
+	assert.Equal(t, 1, k.Len())
+
+	assert.ErrorIs(t, k.DeleteAt(1), ErrOutOfRange)
+	assert.ErrorIs(t, k.DeleteAt(-1), ErrOutOfRange)
+	assert.Equal(t, "root=/dev/sda1", k.String())
+}
+
+func TestKargs_DeleteKarg_dashes(t *testing.T) {
+	// Keys can be deleted by either spelling
+	for _, key := range []string{"with-dashes", "with_dashes"} {
+		k := NewKargs([]byte("noval with-dashes=val"))
+		err := k.DeleteKarg(key)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, k.numParams)
+		assert.False(t, k.ContainsKarg("with-dashes"))
+		assert.Equal(t, "noval", k.String())
+	}
+}
+
+func TestKargs_DeleteKarg_firstAndLast(t *testing.T) {
+	k := NewKargs([]byte("first middle last"))
+
+	err := k.DeleteKarg("first")
+	assert.NoError(t, err)
+	assert.Equal(t, "middle last", k.String())
+
+	err = k.DeleteKarg("last")
+	assert.NoError(t, err)
+	assert.Equal(t, "middle", k.String())
+
+	// Appending after deleting the last karg links to the new last karg
+	k.AppendKargs("new")
+	assert.Equal(t, "middle new", k.String())
+
+	err = k.DeleteKarg("middle")
+	assert.NoError(t, err)
+	err = k.DeleteKarg("new")
+	assert.NoError(t, err)
+	assert.Nil(t, k.list)
+	assert.Nil(t, k.last)
+	assert.Empty(t, k.String())
+}
+
 func TestKargs_DeleteKarg_noValue(t *testing.T) {
 	k := NewKargs([]byte("noval key=val"))
 
@@ -100,6 +325,17 @@ func TestKargs_DeleteKarg_nonexistent(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestKargs_DeleteKargByValue_dashes(t *testing.T) {
+	k := NewKargs([]byte("with-dashes=val1 with_dashes=val2"))
+
+	err := k.DeleteKargByValue("with-dashes", "val1")
+	assert.NoError(t, err)
+	err = k.DeleteKargByValue("with-dashes", "val2")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, k.numParams)
+	assert.Empty(t, k.String())
+}
+
 func TestKargs_DeleteKargByValue_existingValue(t *testing.T) {
 	k := NewKargs([]byte("key=val1 key=val2 key=val3"))
 
@@ -129,6 +365,112 @@ func TestKargs_DeleteKargByValue_nonexistentKey(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestKargs_DeleteKargs(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet console=ttyS0 rd-break"))
+
+	assert.NoError(t, k.DeleteKargs("console", "rd_break"))
+	assert.Equal(t, "root=/dev/sda1 quiet", k.String())
+	assert.Equal(t, 2, k.numParams)
+
+	err := k.DeleteKargs("missing", "quiet", "gone")
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"missing", "gone"}, keyErrs.Keys())
+	assert.ErrorIs(t, err, ErrNotExists)
+	assert.Equal(t, "root=/dev/sda1", k.String())
+
+	assert.NoError(t, k.DeleteKargs())
+}
+
+func TestKargs_DeleteKargsRegexp(t *testing.T) {
+	k := NewKargs([]byte("BOOTIF=01-52-54-00-12-34-56 root=/dev/sda1 ip=dhcp rd.neednet=1 ip=eth1:dhcp skip=1 usbcore.autosuspend=-1"))
+
+	n, err := k.DeleteKargsRegexp(regexp.MustCompile(`^(ip|BOOTIF)$`))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "root=/dev/sda1 rd.neednet=1 skip=1 usbcore.autosuspend=-1", k.String())
+
+	n, err = k.DeleteKargsRegexp(regexp.MustCompile(`^(rd|usbcore)\.`))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "root=/dev/sda1 skip=1", k.String())
+	assert.Empty(t, k.Modules())
+	assert.Equal(t, 2, k.Len())
+
+	n, err = k.DeleteKargsRegexp(regexp.MustCompile(`^nomatch$`))
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestKargs_DuplicateKeys(t *testing.T) {
+	k := NewKargs([]byte("root=a console=tty0 quiet root-x root=b console=ttyS0 root_x"))
+	assert.Equal(t, []string{"root", "console", "root_x"}, k.DuplicateKeys())
+
+	k = NewKargs([]byte("root=a quiet"))
+	assert.Empty(t, k.DuplicateKeys())
+}
+
+func TestKargs_Effective(t *testing.T) {
+	k := NewKargs([]byte("console=tty0 root=/dev/sda1 quiet loglevel=3 console=ttyS0 root=/dev/sda2 initrd=a initrd=b loglevel=7 log-level=1"))
+
+	e := k.Effective()
+	assert.Equal(t, "console=tty0 quiet console=ttyS0 root=/dev/sda2 initrd=a initrd=b loglevel=7 log-level=1", e.String())
+	vals, _ := e.GetKarg("root")
+	assert.Equal(t, []string{"/dev/sda2"}, vals)
+	vals, _ = e.GetKarg("log_level")
+	assert.Equal(t, []string{"1"}, vals)
+
+	// The original is unaffected
+	assert.Equal(t, 10, k.numParams)
+
+	assert.Empty(t, NewKargsEmpty().Effective().String())
+}
+
+func TestKargs_EqualString(t *testing.T) {
+	k := NewKargs([]byte(`foo-bar=1 baz quoted="a b"`))
+
+	assert.True(t, k.EqualString(`foo-bar=1 baz quoted="a b"`))
+	assert.True(t, k.EqualString(`  foo_bar=1   baz quoted='a b' `))
+	assert.False(t, k.EqualString(`foo-bar=1 baz`))
+	assert.False(t, k.EqualString(`foo-bar=1 baz quoted="a b" extra`))
+	assert.False(t, k.EqualString(`baz foo-bar=1 quoted="a b"`))
+	assert.False(t, k.EqualString(`foo-bar=2 baz quoted="a b"`))
+
+	assert.True(t, NewKargsEmpty().EqualString(""))
+	assert.False(t, NewKargsEmpty().EqualString("key"))
+}
+
+func TestKargs_Filter(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 debug console=tty0 initcall_debug rd.debug quiet"))
+
+	prod := k.Filter(func(karg Karg) bool {
+		return !strings.Contains(karg.CanonicalKey, "debug")
+	})
+	assert.Equal(t, "root=/dev/sda1 console=tty0 quiet", prod.String())
+	assert.Equal(t, 3, prod.Len())
+
+	assert.NoError(t, prod.DeleteKarg("quiet"))
+	assert.Equal(t, "root=/dev/sda1 debug console=tty0 initcall_debug rd.debug quiet", k.String())
+
+	assert.Empty(t, k.Filter(func(Karg) bool { return false }).String())
+}
+
+func TestKargs_FindValueSubstring(t *testing.T) {
+	k := NewKargs([]byte(`root=live:http://Images.example.tld/rootfs.squashfs inst.repo=http://images.example.tld/repo rd.neednet=1 "images" console=ttyS0`))
+
+	found := k.FindValueSubstring("images.example.tld")
+	assert.Len(t, found, 1)
+	assert.Equal(t, "inst.repo", found[0].Key)
+
+	found = k.FindValueSubstringFold("IMAGES.example.tld")
+	assert.Len(t, found, 2)
+	assert.Equal(t, "root", found[0].Key)
+	assert.Equal(t, "inst.repo", found[1].Key)
+
+	assert.Empty(t, k.FindValueSubstring("nosuchhost"))
+	assert.Empty(t, k.FindValueSubstringFold("images.example.tld/none"))
+}
+
 func TestKargs_FlagsForModule_existing(t *testing.T) {
 	k := NewKargs([]byte("mod.key1 diffmod diffmod.k1 diffmod.k2=v1 mod.key2=val"))
 
@@ -137,6 +479,25 @@ func TestKargs_FlagsForModule_existing(t *testing.T) {
 	assert.Equal(t, "key1 key2=val", mods)
 }
 
+func TestKargs_FlagsForModule_duplicates(t *testing.T) {
+	k := NewKargs([]byte("mod.key=val1 mod-a.key mod.key=val2 mod.other"))
+
+	// Only the first occurrence of a flag is passed
+	mods := k.FlagsForModule("mod")
+	assert.Equal(t, "key=val1 other", mods)
+}
+
+func TestKargs_FlagsForModule_afterMutation(t *testing.T) {
+	k := NewKargs([]byte("mod.key1 mod.key2=val"))
+
+	err := k.SetKarg("mod.key1", "new")
+	assert.NoError(t, err)
+	k.AppendKargs("mod.key3")
+	err = k.DeleteKarg("mod.key2")
+	assert.NoError(t, err)
+	assert.Equal(t, "key1=new key3", k.FlagsForModule("mod"))
+}
+
 func TestKargs_FlagsForModule_nonexistent(t *testing.T) {
 	k := NewKargs([]byte("mod.key1 diffmod diffmod.k1 diffmod.k2=v1 mod.key2=val"))
 
@@ -166,6 +527,397 @@ func TestKargs_GetKarg(t *testing.T) {
 	assert.Equal(t, "val2", multkey[2])
 }
 
+func TestKargs_GetKargJoined(t *testing.T) {
+	k := NewKargs([]byte(`console=tty0 modprobe.blacklist=nouveau console="ttyS0,115200" quiet`))
+
+	joined, present := k.GetKargJoined("console", " ")
+	assert.True(t, present)
+	assert.Equal(t, "tty0 ttyS0,115200", joined)
+
+	joined, present = k.GetKargJoined("modprobe.blacklist", ",")
+	assert.True(t, present)
+	assert.Equal(t, "nouveau", joined)
+
+	joined, present = k.GetKargJoined("quiet", ",")
+	assert.True(t, present)
+	assert.Empty(t, joined)
+
+	joined, present = k.GetKargJoined("missing", ",")
+	assert.False(t, present)
+	assert.Empty(t, joined)
+}
+
+func TestKargs_GetKargCSV(t *testing.T) {
+	k := NewKargs([]byte(`console=tty0 console=ttyS0,115200n8 video=HDMI-A-1:1920x1080@60,margin_left=0 quiet opts='a,"b,c",,d' open=x,"y,z`))
+
+	fields, present := k.GetKargCSV("console")
+	assert.True(t, present)
+	assert.Equal(t, [][]string{{"tty0"}, {"ttyS0", "115200n8"}}, fields)
+
+	fields, _ = k.GetKargCSV("video")
+	assert.Equal(t, [][]string{{"HDMI-A-1:1920x1080@60", "margin_left=0"}}, fields)
+
+	fields, _ = k.GetKargCSV("opts")
+	assert.Equal(t, [][]string{{"a", "b,c", "", "d"}}, fields)
+
+	fields, _ = k.GetKargCSV("open")
+	assert.Equal(t, [][]string{{"x", "y,z"}}, fields)
+
+	fields, present = k.GetKargCSV("quiet")
+	assert.True(t, present)
+	assert.Equal(t, [][]string{{}}, fields)
+
+	fields, present = k.GetKargCSV("missing")
+	assert.False(t, present)
+	assert.Nil(t, fields)
+}
+
+func TestKargs_GetKargsByPrefix(t *testing.T) {
+	k := NewKargs([]byte("rd.net.timeout.dhcp=10 root=/dev/sda1 rd.neednet=1 rd.net.timeout-carrier=5 rd.net.dhcp.retry=3"))
+
+	assert.Equal(t, []string{"rd.net.timeout.dhcp=10", "rd.net.timeout-carrier=5", "rd.net.dhcp.retry=3"}, rawOf(k.GetKargsByPrefix("rd.net.")))
+	assert.Equal(t, []string{"rd.net.timeout-carrier=5"}, rawOf(k.GetKargsByPrefix("rd.net.timeout_")))
+	assert.Len(t, k.GetKargsByPrefix("rd."), 4)
+	assert.Len(t, k.GetKargsByPrefix(""), 5)
+	assert.Empty(t, k.GetKargsByPrefix("systemd."))
+}
+
+func TestKargs_GetKargsGlob(t *testing.T) {
+	k := NewKargs([]byte("rd.break root=/dev/sda1 systemd.unit=rescue.target rd.neednet=1 systemd-log_level=debug rd.break=pre-mount"))
+
+	kargs, err := k.GetKargsGlob("rd.*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rd.break", "rd.neednet=1", "rd.break=pre-mount"}, rawOf(kargs))
+
+	kargs, err = k.GetKargsGlob("systemd-*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"systemd-log_level=debug"}, rawOf(kargs))
+
+	kargs, err = k.GetKargsGlob("[q-s]oot")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"root=/dev/sda1"}, rawOf(kargs))
+
+	kargs, err = k.GetKargsGlob("nomatch*")
+	assert.NoError(t, err)
+	assert.Empty(t, kargs)
+
+	_, err = k.GetKargsGlob("rd.[")
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
+
+// rawOf returns the raw form of each of kargs.
+func rawOf(kargs []Karg) []string {
+	raws := make([]string, len(kargs))
+	for idx, karg := range kargs {
+		raws[idx] = karg.Raw
+	}
+	return raws
+}
+
+func TestKargs_HasKargValue(t *testing.T) {
+	k := NewKargs([]byte(`console=tty0 rd-break msg="a b" console=ttyS0`))
+
+	assert.True(t, k.HasKargValue("console", "ttyS0"))
+	assert.True(t, k.HasKargValue("console", "tty0"))
+	assert.True(t, k.HasKargValue("rd_break", ""))
+	assert.True(t, k.HasKargValue("msg", "a b"))
+	assert.False(t, k.HasKargValue("console", "ttyS1"))
+	assert.False(t, k.HasKargValue("msg", `"a b"`))
+	assert.False(t, k.HasKargValue("missing", ""))
+}
+
+func TestKargs_InsertKargAfter(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet console=ttyS0"))
+
+	assert.NoError(t, k.InsertKargAfter("console", "console", "ttyS1"))
+	assert.NoError(t, k.InsertKargAfter("root", "usb-core.autosuspend", "-1"))
+	assert.NoError(t, k.InsertKargAfter("console", "init", "/sbin/init --verbose"))
+	assert.Equal(t, `root=/dev/sda1 usb-core.autosuspend=-1 console=tty0 quiet console=ttyS0 console=ttyS1 init="/sbin/init --verbose"`, k.String())
+	assert.Equal(t, 7, k.Len())
+
+	values, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0", "ttyS1"}, values)
+	assert.Equal(t, "autosuspend=-1", k.FlagsForModule("usb_core"))
+
+	assert.ErrorIs(t, k.InsertKargAfter("missing", "splash", ""), ErrNotExists)
+	assert.ErrorIs(t, k.InsertKargAfter("root", "bad key", ""), ErrInvalidKey)
+}
+
+func TestKargs_InsertKargBefore(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet console=ttyS0"))
+
+	assert.NoError(t, k.InsertKargBefore("console", "console", "ttyS1"))
+	assert.NoError(t, k.InsertKargBefore("root", "rd-break", ""))
+	assert.Equal(t, "rd-break root=/dev/sda1 console=ttyS1 console=tty0 quiet console=ttyS0", k.String())
+
+	values, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"ttyS1", "tty0", "ttyS0"}, values)
+	assert.Equal(t, []string{"rd-break", "root", "console", "console", "quiet", "console"}, k.Keys())
+	assert.NoError(t, k.DeleteKarg("rd_break"))
+	assert.Equal(t, "root=/dev/sda1 console=ttyS1 console=tty0 quiet console=ttyS0", k.String())
+
+	assert.ErrorIs(t, k.InsertKargBefore("missing", "splash", ""), ErrNotExists)
+}
+
+func TestKargs_Kargs(t *testing.T) {
+	k := NewKargs([]byte(`rd-break root=/dev/sda1 msg='a b'`))
+	kargs := k.Kargs()
+	assert.Equal(t, []Karg{
+		{CanonicalKey: "rd_break", Key: "rd-break", Raw: "rd-break"},
+		{CanonicalKey: "root", Key: "root", Raw: "root=/dev/sda1", Value: "/dev/sda1"},
+		{CanonicalKey: "msg", Key: "msg", Raw: "msg='a b'", Value: "a b"},
+	}, kargs)
+
+	kargs[0].Value = "changed"
+	assert.NoError(t, k.DeleteKarg("root"))
+	assert.Equal(t, "changed", kargs[0].Value)
+	assert.Len(t, kargs, 3)
+	assert.Equal(t, `rd-break msg='a b'`, k.String())
+
+	assert.Empty(t, NewKargsEmpty().Kargs())
+}
+
+func TestKargs_Keys(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 rd-break console=ttyS0 rd_break quiet"))
+	assert.Equal(t, []string{"root", "console", "rd-break", "console", "rd_break", "quiet"}, k.Keys())
+
+	assert.NoError(t, k.DeleteKarg("root"))
+	assert.Equal(t, []string{"console", "rd-break", "console", "rd_break", "quiet"}, k.Keys())
+
+	assert.Empty(t, NewKargsEmpty().Keys())
+}
+
+func TestKargs_KeysMatching(t *testing.T) {
+	k := NewKargs([]byte("rd.break root=/dev/sda1 systemd.unit=rescue.target rd.neednet=1 systemd-log_level=debug rd.break=pre-mount"))
+
+	keys, err := k.KeysMatching("rd.*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rd.break", "rd.neednet"}, keys)
+
+	keys, err = k.KeysMatching("systemd_*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"systemd-log_level"}, keys)
+
+	keys, err = k.KeysMatching("*")
+	assert.NoError(t, err)
+	assert.Equal(t, k.UniqueKeys(), keys)
+
+	_, err = k.KeysMatching("[")
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
+
+func TestKargs_Len(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 console=ttyS0 quiet"))
+	assert.Equal(t, 4, k.Len())
+
+	assert.NoError(t, k.DeleteKarg("console"))
+	assert.Equal(t, 2, k.Len())
+
+	assert.Equal(t, 0, NewKargsEmpty().Len())
+}
+
+func TestKargs_LoadModuleOptions(t *testing.T) {
+	k := NewKargs([]byte(`mod.key1 mod-a.k mod.key-2="a b" mod.key1=dup mod.key3=val`))
+
+	opts, err := k.LoadModuleOptions("mod")
+	assert.NoError(t, err)
+	assert.Equal(t, `key1 key_2="a b" key3=val`, opts)
+
+	// Module without flags
+	opts, err = k.LoadModuleOptions("nonexistent")
+	assert.NoError(t, err)
+	assert.Empty(t, opts)
+
+	// Invalid module names
+	for _, name := range []string{"", "mod.key", "mod=val", "mod a"} {
+		_, err = k.LoadModuleOptions(name)
+		assert.ErrorIs(t, err, ErrInvalidModule)
+	}
+}
+
+func TestKargs_ModuleView(t *testing.T) {
+	k := NewKargs([]byte("mod.key1 diffmod.k1 mod-a.k mod.key2=val mod_a.k2"))
+
+	view := k.ModuleView("mod")
+	assert.Equal(t, 2, view.numParams)
+	assert.Equal(t, "mod.key1 mod.key2=val", view.String())
+
+	// Module names with - and _ are treated the same
+	view = k.ModuleView("mod-a")
+	assert.Equal(t, "mod-a.k mod_a.k2", view.String())
+
+	// Modifying the view does not affect the original
+	err := view.SetKarg("mod_a.k", "val")
+	assert.NoError(t, err)
+	assert.Equal(t, "mod.key1 diffmod.k1 mod-a.k mod.key2=val mod_a.k2", k.String())
+
+	view = k.ModuleView("nonexistent")
+	assert.Equal(t, 0, view.numParams)
+}
+
+func TestKargs_Modules(t *testing.T) {
+	k := NewKargs([]byte("mod.key1 nomod diffmod.k1 mod-a.k mod.key2=val"))
+	assert.Equal(t, []string{"diffmod", "mod", "mod_a"}, k.Modules())
+
+	err := k.DeleteKarg("diffmod.k1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mod", "mod_a"}, k.Modules())
+
+	assert.Empty(t, NewKargsEmpty().Modules())
+}
+
+func TestKargs_MoveKarg(t *testing.T) {
+	k := NewKargs([]byte(`root=/dev/sda1 console=tty0 usbcore.a=1 quiet console='ttyS0' usbcore.b=2`))
+
+	assert.NoError(t, k.MoveKarg("console", 0))
+	assert.Equal(t, `console=tty0 console='ttyS0' root=/dev/sda1 usbcore.a=1 quiet usbcore.b=2`, k.String())
+
+	assert.NoError(t, k.MoveKarg("usbcore.a", 5))
+	assert.Equal(t, `console=tty0 console='ttyS0' root=/dev/sda1 quiet usbcore.b=2 usbcore.a=1`, k.String())
+	assert.Equal(t, "b=2 a=1", k.FlagsForModule("usbcore"))
+
+	assert.NoError(t, k.MoveKarg("console", 4))
+	assert.Equal(t, `root=/dev/sda1 quiet usbcore.b=2 usbcore.a=1 console=tty0 console='ttyS0'`, k.String())
+	values, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0"}, values)
+
+	assert.ErrorIs(t, k.MoveKarg("console", 5), ErrOutOfRange)
+	assert.ErrorIs(t, k.MoveKarg("console", -1), ErrOutOfRange)
+	assert.ErrorIs(t, k.MoveKarg("missing", 0), ErrNotExists)
+	assert.Equal(t, 6, k.Len())
+}
+
+func TestKargs_MoveKargAfter(t *testing.T) {
+	k := NewKargs([]byte("earlycon root=/dev/sda1 console=tty0 quiet console=ttyS0 rdinit=/init"))
+
+	assert.NoError(t, k.MoveKargAfter("rdinit", "earlycon"))
+	assert.NoError(t, k.MoveKargAfter("root", "console"))
+	assert.NoError(t, k.MoveKargAfter("earlycon", "root"))
+	assert.NoError(t, k.MoveKargAfter("quiet", "quiet"))
+	assert.Equal(t, "rdinit=/init console=tty0 quiet console=ttyS0 root=/dev/sda1 earlycon", k.String())
+	assert.Equal(t, []string{"rdinit", "console", "quiet", "console", "root", "earlycon"}, k.Keys())
+
+	assert.ErrorIs(t, k.MoveKargAfter("missing", "root"), ErrNotExists)
+	assert.ErrorIs(t, k.MoveKargAfter("root", "missing"), ErrNotExists)
+}
+
+func TestKargs_PrependKarg(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0"))
+
+	assert.NoError(t, k.PrependKarg("console", "ttyS0"))
+	assert.NoError(t, k.PrependKarg("earlycon", ""))
+	assert.Equal(t, "earlycon console=ttyS0 root=/dev/sda1 console=tty0", k.String())
+	values, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"ttyS0", "tty0"}, values)
+
+	k = NewKargsEmpty()
+	assert.NoError(t, k.PrependKarg("rdinit", "/init"))
+	assert.NoError(t, k.AppendKarg("quiet", ""))
+	assert.Equal(t, "rdinit=/init quiet", k.String())
+	assert.Equal(t, 2, k.Len())
+
+	assert.ErrorIs(t, k.PrependKarg("bad key", ""), ErrInvalidKey)
+}
+
+func TestKargs_RenameKarg(t *testing.T) {
+	k := NewKargs([]byte(`root=/dev/sda1 netdev=eth0 msg='a b' quiet netdev=eth1 net.ifnames=0`))
+
+	assert.NoError(t, k.RenameKarg("netdev", "net.ifnames"))
+	assert.Equal(t, `root=/dev/sda1 net.ifnames=eth0 msg='a b' quiet net.ifnames=eth1 net.ifnames=0`, k.String())
+	values, _ := k.GetKarg("net.ifnames")
+	assert.Equal(t, []string{"eth0", "eth1", "0"}, values)
+	assert.False(t, k.ContainsKarg("netdev"))
+	assert.Equal(t, []string{"net"}, k.Modules())
+	assert.Equal(t, 6, k.Len())
+
+	assert.NoError(t, k.RenameKarg("msg", "message"))
+	assert.NoError(t, k.RenameKarg("quiet", "silent"))
+	assert.Equal(t, `root=/dev/sda1 net.ifnames=eth0 message='a b' silent net.ifnames=eth1 net.ifnames=0`, k.String())
+	values, _ = k.GetKarg("message")
+	assert.Equal(t, []string{"a b"}, values)
+
+	assert.NoError(t, k.RenameKarg("net.ifnames", "ifnames"))
+	assert.Empty(t, k.Modules())
+	assert.NoError(t, k.DeleteKarg("ifnames"))
+	assert.Equal(t, `root=/dev/sda1 message='a b' silent`, k.String())
+
+	assert.ErrorIs(t, k.RenameKarg("missing", "other"), ErrNotExists)
+	assert.ErrorIs(t, k.RenameKarg("root", "bad key"), ErrInvalidKey)
+	assert.ErrorIs(t, k.RenameKarg("root", ""), ErrInvalidKey)
+}
+
+func TestKargs_ReplaceAll(t *testing.T) {
+	k := NewKargs([]byte("console=tty0 quiet console=ttyS0,115200 with-dashes with_dashes=val"))
+
+	err := k.ReplaceAll("console", "ttyS1")
+	assert.NoError(t, err)
+	assert.Equal(t, "console=ttyS1 quiet console=ttyS1 with-dashes with_dashes=val", k.String())
+	assert.Equal(t, 5, k.numParams)
+	vals, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"ttyS1", "ttyS1"}, vals)
+
+	// Each occurrence keeps its spelling
+	err = k.ReplaceAll("with-dashes", "a b")
+	assert.NoError(t, err)
+	assert.Equal(t, `console=ttyS1 quiet console=ttyS1 with-dashes="a b" with_dashes="a b"`, k.String())
+
+	err = k.ReplaceAll("nonexistent", "val")
+	assert.ErrorIs(t, err, ErrNotExists)
+}
+
+func TestKargs_ReplaceAll_quoteMode(t *testing.T) {
+	k := NewKargs([]byte("key=a key=b"), WithQuoteMode(QuoteNever))
+
+	// Nothing is replaced if the value is rejected
+	err := k.ReplaceAll("key", "with spaces")
+	assert.ErrorIs(t, err, ErrUnquotable)
+	assert.Equal(t, "key=a key=b", k.String())
+}
+
+func TestKargs_ReplaceKargValue(t *testing.T) {
+	k := NewKargs([]byte(`console=tty0 root=/dev/sda1 console=ttyS0,9600 console=ttyS0,9600 msg="a b"`))
+
+	assert.NoError(t, k.ReplaceKargValue("console", "ttyS0,9600", "ttyS0,115200"))
+	assert.Equal(t, `console=tty0 root=/dev/sda1 console=ttyS0,115200 console=ttyS0,9600 msg="a b"`, k.String())
+	values, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0,115200", "ttyS0,9600"}, values)
+
+	assert.NoError(t, k.ReplaceKargValue("msg", "a b", "c d"))
+	assert.NoError(t, k.ReplaceKargValue("root", "/dev/sda1", ""))
+	assert.Equal(t, `console=tty0 root console=ttyS0,115200 console=ttyS0,9600 msg="c d"`, k.String())
+
+	assert.ErrorIs(t, k.ReplaceKargValue("console", "ttyS1", "ttyS2"), ErrNotExists)
+	assert.ErrorIs(t, k.ReplaceKargValue("missing", "", "x"), ErrNotExists)
+
+	k = NewKargs([]byte("console=tty0"), WithQuoteMode(QuoteNever))
+	assert.ErrorIs(t, k.ReplaceKargValue("console", "tty0", "a b"), ErrUnquotable)
+	assert.Equal(t, "console=tty0", k.String())
+}
+
+func TestKargs_RewriteValues(t *testing.T) {
+	k := NewKargs([]byte(`root=live:http://old.example.tld/rootfs.squashfs quiet inst.repo=http://old.example.tld/repo fetch="http://old.example.tld/a b" console=tty0`))
+
+	n := k.RewriteValues(func(key, value string) (string, bool) {
+		if !strings.Contains(value, "old.example.tld") {
+			return "", false
+		}
+		return strings.ReplaceAll(value, "old.example.tld", "new.example.tld"), true
+	})
+	assert.Equal(t, 3, n)
+	assert.Equal(t, `root=live:http://new.example.tld/rootfs.squashfs quiet inst.repo=http://new.example.tld/repo fetch="http://new.example.tld/a b" console=tty0`, k.String())
+	values, _ := k.GetKarg("fetch")
+	assert.Equal(t, []string{"http://new.example.tld/a b"}, values)
+
+	assert.Zero(t, k.RewriteValues(func(string, string) (string, bool) { return "", false }))
+
+	k = NewKargs([]byte("console=tty0 init=/sbin/init"), WithQuoteMode(QuoteNever))
+	n = k.RewriteValues(func(key, value string) (string, bool) {
+		return value + " --verbose", key == "init"
+	})
+	assert.Zero(t, n)
+	assert.Equal(t, "console=tty0 init=/sbin/init", k.String())
+}
+
 func TestKargs_SetKarg_createReplace(t *testing.T) {
 	// Test simple creation and replacement
 	k := NewKargsEmpty()
@@ -214,12 +966,274 @@ func TestKargs_SetKarg_replaceMultiple(t *testing.T) {
 	assert.Equal(t, []string{""}, vals)
 }
 
+func TestKargs_SetKarg_replaceLast(t *testing.T) {
+	k := NewKargs([]byte("key=val1 key=val2"))
+
+	err := k.SetKarg("key", "val3")
+	assert.NoError(t, err)
+	k.AppendKargs("other")
+	assert.Equal(t, "key=val3 other", k.String())
+}
+
+func TestKargs_SetKarg_quoteMode(t *testing.T) {
+	k := NewKargsEmpty(WithQuoteMode(QuoteKernel))
+
+	err := k.SetKarg("key", `a "b"`)
+	assert.ErrorIs(t, err, ErrUnquotable)
+	assert.False(t, k.ContainsKarg("key"))
+
+	err = k.SetKarg("key", `a b`)
+	assert.NoError(t, err)
+	vals, _ := k.GetKarg("key")
+	assert.Equal(t, []string{"a b"}, vals)
+	assert.Equal(t, `key="a b"`, k.String())
+}
+
+func TestKargs_SetKargPosition(t *testing.T) {
+	const cmdline = "loglevel=3 quiet mod.a=1 loglevel=7 root=/dev/sda1"
+	for _, tc := range []struct {
+		pos  SetPosition
+		want string
+	}{
+		{SetKeepFirst, "loglevel=4 quiet mod.a=1 root=/dev/sda1"},
+		{SetKeepLast, "quiet mod.a=1 loglevel=4 root=/dev/sda1"},
+		{SetMoveToEnd, "quiet mod.a=1 root=/dev/sda1 loglevel=4"},
+	} {
+		k := NewKargs([]byte(cmdline))
+		assert.NoError(t, k.SetKargPosition("loglevel", "4", tc.pos), tc.pos.String())
+		assert.Equal(t, tc.want, k.String(), tc.pos.String())
+		assert.NoError(t, k.ConsistencyCheck(), tc.pos.String())
+
+		// The option makes SetKarg behave the same.
+		k = NewKargs([]byte(cmdline), WithSetPosition(tc.pos))
+		assert.NoError(t, k.SetKarg("loglevel", "4"), tc.pos.String())
+		assert.Equal(t, tc.want, k.String(), tc.pos.String())
+
+		// New keys are appended regardless.
+		assert.NoError(t, k.SetKargPosition("new", "x", tc.pos))
+		assert.Equal(t, tc.want+" new=x", k.String(), tc.pos.String())
+	}
+
+	k := NewKargs([]byte("mod.a=1 mod.b=2 mod.a=3"))
+	assert.NoError(t, k.SetKargPosition("mod.a", "4", SetKeepLast))
+	assert.Equal(t, "mod.b=2 mod.a=4", k.String())
+	assert.Equal(t, []string{"mod.b=2", "mod.a=4"}, rawOf(k.ModuleView("mod").Kargs()))
+
+	err := k.SetKargPosition("mod.a", "5", SetPosition(42))
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.Equal(t, "SetPosition(42)", SetPosition(42).String())
+}
+
+func TestKargs_SetKarg_accumulating(t *testing.T) {
+	k := NewKargs([]byte("console=tty0 console=ttyS0,9600 quiet console=ttyS0,115200 " +
+		"systemd.setenv=A=1 systemd.setenv=B=2 video=HDMI-A-1:800x600 root=/dev/sda1"))
+
+	// Matching occurrences are replaced by the first of them.
+	assert.NoError(t, k.SetKarg("console", "ttyS0,115200n8"))
+	assert.NoError(t, k.SetKarg("systemd.setenv", "B=3"))
+	assert.NoError(t, k.SetKarg("video", "HDMI-A-1:1024x768"))
+	assert.Equal(t, "console=tty0 console=ttyS0,115200n8 quiet "+
+		"systemd.setenv=A=1 systemd.setenv=B=3 video=HDMI-A-1:1024x768 root=/dev/sda1", k.String())
+
+	// Other values are added after the last occurrence.
+	assert.NoError(t, k.SetKarg("console", "ttyS1"))
+	assert.NoError(t, k.SetKarg("initrd", "/boot/initrd.img"))
+	assert.Equal(t, "console=tty0 console=ttyS0,115200n8 console=ttyS1 quiet "+
+		"systemd.setenv=A=1 systemd.setenv=B=3 video=HDMI-A-1:1024x768 root=/dev/sda1 initrd=/boot/initrd.img", k.String())
+	assert.NoError(t, k.ConsistencyCheck())
+
+	// The SetPosition picks the matching occurrence that is kept.
+	line := "console=ttyS0,9600 console=tty0 console=ttyS0,115200 quiet"
+	k = NewKargs([]byte(line), WithSetPosition(SetKeepLast))
+	assert.NoError(t, k.SetKarg("console", "ttyS0,57600"))
+	assert.NoError(t, k.SetKarg("console", "ttyS1"))
+	assert.Equal(t, "console=tty0 console=ttyS0,57600 console=ttyS1 quiet", k.String())
+	k = NewKargs([]byte(line), WithSetPosition(SetMoveToEnd))
+	assert.NoError(t, k.SetKarg("console", "ttyS0,57600"))
+	assert.NoError(t, k.SetKarg("console", "ttyS1"))
+	assert.Equal(t, "console=tty0 quiet console=ttyS0,57600 console=ttyS1", k.String())
+	assert.NoError(t, k.ConsistencyCheck())
+
+	// Unregistered keys collapse again.
+	k = NewKargs([]byte("console=tty0 console=ttyS0,115200n8 console=ttyS1 quiet " +
+		"systemd.setenv=A=1 systemd.setenv=B=3 video=HDMI-A-1:1024x768 root=/dev/sda1 initrd=/boot/initrd.img"))
+	UnregisterAccumulating("console")
+	defer RegisterAccumulating("console")
+	assert.NoError(t, k.SetKarg("console", "ttyS2"))
+	assert.Equal(t, "console=ttyS2 quiet "+
+		"systemd.setenv=A=1 systemd.setenv=B=3 video=HDMI-A-1:1024x768 root=/dev/sda1 initrd=/boot/initrd.img", k.String())
+}
+
+func TestKargs_SetKargs(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 loglevel=3 loglevel=7"))
+
+	err := k.SetKargs(map[string]string{
+		"root":     "/dev/sda2",
+		"quiet":    "",
+		"loglevel": "4",
+		"init":     "/sbin/init",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda2 loglevel=4 init=/sbin/init quiet", k.String())
+
+	err = k.SetKargs(map[string]string{
+		"bad key":  "val",
+		"good":     "val",
+		"bad-key2": "with spaces",
+	})
+	assert.Error(t, err)
+	assert.True(t, k.ContainsKarg("good"))
+	assert.False(t, k.ContainsKarg("bad key"))
+}
+
+func TestKargs_SetKargs_errors(t *testing.T) {
+	k := NewKargsEmpty(WithQuoteMode(QuoteNever))
+
+	err := k.SetKargs(map[string]string{
+		"bad key": "val",
+		"good":    "val",
+		"spaces":  "with spaces",
+	})
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"bad key", "spaces"}, keyErrs.Keys())
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.ErrorIs(t, err, ErrUnquotable)
+	assert.Equal(t, "good=val", k.String())
+}
+
+func TestKargs_SetKargsOrdered(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1"))
+
+	spaced, err := MakeKarg("init", "/sbin/init --verbose")
+	assert.NoError(t, err)
+	err = k.SetKargsOrdered([]Karg{
+		{Key: "quiet"},
+		{Key: "root", Value: "/dev/sda2"},
+		spaced,
+		{Key: "bad key", Value: "val"},
+		{Key: "console", Value: "ttyS0"},
+	})
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"bad key"}, keyErrs.Keys())
+	assert.Equal(t, `root=/dev/sda2 quiet init="/sbin/init --verbose" console=ttyS0`, k.String())
+}
+
 func TestKargs_String(t *testing.T) {
 	cmdline := `nomodeset root=live:https://example.tld/image.squashfs console=tty0,115200n8 console=ttyS0,115200n8 printk.devkmsg=ratelimit printk.time=1`
 	k := NewKargs([]byte(cmdline))
 	assert.Equal(t, cmdline, k.String())
 }
 
+func TestKargs_String_cache(t *testing.T) {
+	k := NewKargs([]byte("key1=val1 key2=val2 key3"))
+	assert.False(t, k.strValid)
+	assert.Equal(t, "key1=val1 key2=val2 key3", k.String())
+	assert.True(t, k.strValid)
+
+	// Every modification invalidates the cache
+	k.AppendKargs("key4")
+	assert.False(t, k.strValid)
+	assert.Equal(t, "key1=val1 key2=val2 key3 key4", k.String())
+
+	err := k.SetKarg("key1", "new")
+	assert.NoError(t, err)
+	assert.False(t, k.strValid)
+	assert.Equal(t, "key1=new key2=val2 key3 key4", k.String())
+
+	err = k.DeleteKargByValue("key2", "val2")
+	assert.NoError(t, err)
+	assert.False(t, k.strValid)
+	assert.Equal(t, "key1=new key3 key4", k.String())
+
+	err = k.DeleteKarg("key3")
+	assert.NoError(t, err)
+	assert.False(t, k.strValid)
+	assert.Equal(t, "key1=new key4", k.String())
+}
+
+func TestKargs_TakeKarg(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 rd.break console=tty0 usbcore.autosuspend=-1 console=ttyS0"))
+
+	vals, present, err := k.TakeKarg("rd.break")
+	assert.NoError(t, err)
+	assert.True(t, present)
+	assert.Equal(t, []string{""}, vals)
+	assert.False(t, k.ContainsKarg("rd.break"))
+
+	vals, present, err = k.TakeKarg("console")
+	assert.NoError(t, err)
+	assert.True(t, present)
+	assert.Equal(t, []string{"tty0", "ttyS0"}, vals)
+
+	vals, present, err = k.TakeKarg("usbcore.autosuspend")
+	assert.NoError(t, err)
+	assert.True(t, present)
+	assert.Equal(t, []string{"-1"}, vals)
+	assert.Empty(t, k.Modules())
+
+	vals, present, err = k.TakeKarg("rd.break")
+	assert.NoError(t, err)
+	assert.False(t, present)
+	assert.Nil(t, vals)
+
+	assert.Equal(t, "root=/dev/sda1", k.String())
+	assert.Equal(t, 1, k.Len())
+}
+
+func TestKargs_ToMap(t *testing.T) {
+	k := NewKargs([]byte(`console=tty0 rd-break msg="a b" console=ttyS0 rd_break=pre-mount`))
+
+	assert.Equal(t, map[string][]string{
+		"console":  {"tty0", "ttyS0"},
+		"rd_break": {"", "pre-mount"},
+		"msg":      {"a b"},
+	}, k.ToMap())
+
+	m := k.ToMap()
+	m["console"][0] = "changed"
+	vals, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"tty0", "ttyS0"}, vals)
+
+	assert.Empty(t, NewKargsEmpty().ToMap())
+}
+
+func TestKargs_UniqueKeys(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 rd-break console=ttyS0 rd_break quiet"))
+	assert.Equal(t, []string{"root", "console", "rd-break", "quiet"}, k.UniqueKeys())
+
+	assert.NoError(t, k.DeleteKargByValue("console", "tty0"))
+	assert.Equal(t, []string{"root", "rd-break", "console", "quiet"}, k.UniqueKeys())
+
+	assert.Empty(t, NewKargsEmpty().UniqueKeys())
+}
+
+func TestMakeKarg(t *testing.T) {
+	karg, err := MakeKarg("with-dashes", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Karg{CanonicalKey: "with_dashes", Key: "with-dashes", Raw: "with-dashes", Value: ""}, karg)
+
+	karg, err = MakeKarg("key", "val")
+	assert.NoError(t, err)
+	assert.Equal(t, Karg{CanonicalKey: "key", Key: "key", Raw: "key=val", Value: "val"}, karg)
+
+	karg, err = MakeKarg("key", "with spaces")
+	assert.NoError(t, err)
+	assert.Equal(t, Karg{CanonicalKey: "key", Key: "key", Raw: `key="with spaces"`, Value: "with spaces"}, karg)
+
+	// Matches what SetKarg stores
+	k := NewKargsEmpty()
+	err = k.SetKarg("key", `'quoted val'`)
+	assert.NoError(t, err)
+	karg, err = MakeKarg("key", `'quoted val'`)
+	assert.NoError(t, err)
+	assert.Equal(t, k.list.karg, karg)
+
+	_, err = MakeKarg("invalid key", "val")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
 func TestNewKargs(t *testing.T) {
 	in := `key1 key2=val`
 	k := NewKargs([]byte(in))
@@ -230,6 +1244,41 @@ func TestNewKargs(t *testing.T) {
 	assert.Equal(t, in, k.String())
 }
 
+func TestNewKargs_withASCII(t *testing.T) {
+	in := `root=/dev/sda1 msg="a b" quiet`
+	assert.Equal(t, NewKargs([]byte(in)).Keys(), NewKargs([]byte(in), WithASCII()).Keys())
+
+	k := NewKargs([]byte("a=\u00a0b c"), WithASCII())
+	vals, _ := k.GetKarg("a")
+	assert.Equal(t, []string{"\u00a0b"}, vals)
+	assert.Equal(t, 2, k.Len())
+
+	// The kargs don't change with the input, and can be modified as usual.
+	line := []byte(benchCmdline)
+	k = NewKargs(line, WithASCII())
+	copy(line, "XXXXXXXXXX")
+	assert.Equal(t, benchCmdline, k.String())
+	assert.NoError(t, k.SetKarg("root", "/dev/sda2"))
+	assert.NoError(t, k.DeleteKarg("quiet"))
+	assert.NoError(t, k.AppendKarg("rd.break", ""))
+	assert.NoError(t, k.ConsistencyCheck())
+
+	k, err := parse([]byte("a b c d"), WithASCII(), WithMaxParams(2))
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, "a b", k.String())
+}
+
+func TestNewKargs_withCompatDequote(t *testing.T) {
+	in := `key="unterminated`
+	k := NewKargs([]byte(in))
+	vals, _ := k.GetKarg("key")
+	assert.Equal(t, []string{"unterminated"}, vals)
+
+	k = NewKargs([]byte(in), WithCompatDequote())
+	vals, _ = k.GetKarg("key")
+	assert.Equal(t, []string{"unterminate"}, vals)
+}
+
 func TestNewKargsEmpty(t *testing.T) {
 	// Test empty
 	emptyK := NewKargsEmpty()
@@ -238,4 +1287,73 @@ func TestNewKargsEmpty(t *testing.T) {
 	assert.Nil(t, emptyK.list)
 	assert.Nil(t, emptyK.last)
 	assert.Empty(t, emptyK.keyMap)
+	assert.Empty(t, emptyK.moduleMap)
+}
+
+func TestParseKargs(t *testing.T) {
+	line := []byte(`root=/dev/sda1 msg="a long value" console=tty0`)
+
+	k, err := ParseKargs(line, WithMaxParams(3), WithMaxTokenLength(18), WithMaxKeyLength(7))
+	assert.NoError(t, err)
+	assert.Equal(t, string(line), k.String())
+
+	tests := []struct {
+		opt    ParseOption
+		limit  Limit
+		offset int
+	}{
+		{WithMaxParams(2), LimitParams, 34},
+		{WithMaxTokenLength(17), LimitTokenLength, 15},
+		{WithMaxKeyLength(6), LimitKeyLength, 34},
+	}
+	for _, test := range tests {
+		k, err := ParseKargs(line, test.opt)
+		assert.Nil(t, k)
+		assert.ErrorIs(t, err, ErrLimitExceeded)
+		var limitErr *LimitError
+		if assert.ErrorAs(t, err, &limitErr) {
+			assert.Equal(t, test.limit, limitErr.Limit)
+			assert.Equal(t, test.offset, limitErr.Offset)
+		}
+	}
+
+	// NewKargs keeps what was parsed before the limit.
+	assert.Equal(t, `root=/dev/sda1 msg="a long value"`, NewKargs(line, WithMaxParams(2)).String())
+	assert.Equal(t, "number of kargs exceeds limit of 2 at offset 36", (&LimitError{Limit: LimitParams, Max: 2, Offset: 36}).Error())
+}
+
+func BenchmarkKargs_EqualString(b *testing.B) {
+	k := NewKargs([]byte(benchCmdline))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.EqualString(benchCmdline)
+	}
+}
+
+func BenchmarkKargs_FlagsForModule(b *testing.B) {
+	k := NewKargs([]byte(benchCmdline))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.FlagsForModule("printk")
+	}
+}
+
+func BenchmarkKargs_Modules(b *testing.B) {
+	k := NewKargs([]byte(benchCmdline))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.Modules()
+	}
+}
+
+func BenchmarkKargs_String_cached(b *testing.B) {
+	k := NewKargs([]byte(benchCmdline))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = k.String()
+	}
 }
@@ -0,0 +1,95 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "bytes"
+
+// SplitLikeKernel splits line into parameters exactly like the kernel's
+// next_arg() in kernel/params.c does, and returns them as the kernel passes them
+// on, i.e. as "param" or "param=value" with the quotes it removes left out. It
+// is meant as a reference to check this package against the kernel:
+//
+//   - Only double quotes group, and there are no escapes. A quote anywhere in a
+//     parameter toggles whether whitespace separates parameters.
+//   - If the value begins with a quote, that quote is removed, as is the last
+//     character of the parameter if it is a quote. The same goes for a
+//     parameter beginning with a quote.
+//   - Whitespace is what the kernel's isspace() accepts, which includes the
+//     Latin-1 no-break space byte 0xA0.
+//   - An = at the very beginning of a parameter doesn't start a value.
+//   - A NUL byte ends the command line.
+func SplitLikeKernel(line string) []string {
+	var params []string
+	args := []byte(line)
+	if idx := bytes.IndexByte(args, 0); idx != -1 {
+		args = args[:idx]
+	}
+	pos := skipKernelSpaces(args, 0)
+	for pos < len(args) {
+		var param string
+		param, pos = nextKernelArg(args, pos)
+		params = append(params, param)
+		pos = skipKernelSpaces(args, pos)
+	}
+	return params
+}
+
+// nextKernelArg returns the parameter starting at args[pos], as described in
+// SplitLikeKernel, and the position just past it.
+func nextKernelArg(args []byte, pos int) (string, int) {
+	inQuote, quoted := false, false
+	if args[pos] == '"' {
+		pos++
+		inQuote, quoted = true, true
+	}
+	arg := args[pos:]
+	i, equals := 0, 0
+	for ; i < len(arg); i++ {
+		if isKernelSpace(arg[i]) && !inQuote {
+			break
+		}
+		if equals == 0 && arg[i] == '=' {
+			equals = i
+		}
+		if arg[i] == '"' {
+			inQuote = !inQuote
+		}
+	}
+
+	// The kernel cuts the parameter and its value by writing NUL bytes into
+	// it, so work on a copy and cut at the first NUL afterwards.
+	buf := append([]byte(nil), arg[:i]...)
+	if equals != 0 {
+		if equals+1 < len(buf) && buf[equals+1] == '"' {
+			buf = append(buf[:equals+1], buf[equals+2:]...)
+			if i > 0 && arg[i-1] == '"' && len(buf) > equals+1 {
+				buf = buf[:len(buf)-1]
+			}
+		} else if quoted && i > 0 && arg[i-1] == '"' {
+			buf = buf[:len(buf)-1]
+		}
+	} else if quoted && i > 0 && arg[i-1] == '"' {
+		buf = buf[:len(buf)-1]
+	}
+
+	next := pos + i
+	if next < len(args) {
+		next++
+	}
+	return string(buf), next
+}
+
+// skipKernelSpaces returns the position of the first byte at or after pos in
+// args that is not whitespace.
+func skipKernelSpaces(args []byte, pos int) int {
+	for pos < len(args) && isKernelSpace(args[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// isKernelSpace reports whether the kernel's isspace() accepts c.
+func isKernelSpace(c byte) bool {
+	return c == ' ' || (c >= '\t' && c <= '\r') || c == 0xa0
+}
@@ -0,0 +1,37 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLikeKernel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  \t\n ", nil},
+		{"root=/dev/sda1  quiet\tro\n", []string{"root=/dev/sda1", "quiet", "ro"}},
+		{`msg="a b" x`, []string{"msg=a b", "x"}},
+		{`"msg=a b" x`, []string{"msg=a b", "x"}},
+		{`"quoted" x`, []string{"quoted", "x"}},
+		{`msg='a b'`, []string{"msg='a", "b'"}},
+		{`msg="a\"b"`, []string{`msg=a\"b`}},
+		{`msg=a"b c"d e`, []string{`msg=a"b c"d`, "e"}},
+		{`msg="unterminated a b`, []string{"msg=unterminated a b"}},
+		{`msg="`, []string{"msg="}},
+		{`key= x`, []string{"key=", "x"}},
+		{`=value`, []string{"=value"}},
+		{"a\xa0b", []string{"a", "b"}},
+		{"a b\x00c d", []string{"a", "b"}},
+		{`"a=b" "c`, []string{"a=b", "c"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, SplitLikeKernel(test.in), test.in)
+	}
+}
@@ -0,0 +1,69 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exprLexer scans the tokens of the expressions parsed by ParseCondition and
+// ParsePolicy. The parsers embed it and build their grammars on top.
+type exprLexer struct {
+	input string
+	pos   int
+	what  string // What is parsed, for errors, e.g. "condition"
+	err   error  // Sentinel error wrapped by errors
+}
+
+// errorf returns an error wrapping the sentinel error of l at the current
+// position.
+func (l *exprLexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("parsing %s %q at offset %d: %s: %w", l.what, l.input, l.pos, fmt.Sprintf(format, args...), l.err)
+}
+
+// skipSpace skips whitespace and returns the new position.
+func (l *exprLexer) skipSpace() int {
+	for l.pos < len(l.input) && strings.IndexByte(" \t\n", l.input[l.pos]) != -1 {
+		l.pos++
+	}
+	return l.pos
+}
+
+// accept consumes tok if it comes next and reports whether it did.
+func (l *exprLexer) accept(tok string) bool {
+	l.skipSpace()
+	if strings.HasPrefix(l.input[l.pos:], tok) {
+		l.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// acceptWord consumes the word w if it comes next, not followed by further
+// letters, and reports whether it did.
+func (l *exprLexer) acceptWord(w string) bool {
+	l.skipSpace()
+	end := l.pos + len(w)
+	if !strings.HasPrefix(l.input[l.pos:], w) || (end < len(l.input) && isWordByte(l.input[end])) {
+		return false
+	}
+	l.pos = end
+	return true
+}
+
+// scanWhile consumes the bytes for which ok returns true, after skipping
+// whitespace, and returns them.
+func (l *exprLexer) scanWhile(ok func(c byte) bool) string {
+	start := l.skipSpace()
+	for l.pos < len(l.input) && ok(l.input[l.pos]) {
+		l.pos++
+	}
+	return l.input[start:l.pos]
+}
+
+// isWordByte returns whether c may be part of a word like implies.
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
@@ -6,9 +6,13 @@ package kargs
 import "fmt"
 
 type kargItem struct {
-	karg Karg
-	next *kargItem
-	prev *kargItem
+	karg    Karg
+	next    *kargItem
+	prev    *kargItem
+	oneShot bool         // Whether karg is removed by ConsumeOneShot
+	cond    *Condition   // Condition for including karg in Resolve, if any
+	line    *cmdlineLine // Line of a CmdlineFile karg was read from, if any
+	source  string       // Provenance of karg, e.g. ProvenanceDHCP, if any
 }
 
 // remove deletes k from the list
@@ -26,7 +30,9 @@ func remove(k *kargItem) error {
 	return nil
 }
 
-// replace replaces oldK with newK in the list
+// replace replaces oldK with newK in the list. newK takes over the line of a
+// CmdlineFile oldK was read from, so that replacing a value keeps the comments
+// around it.
 func replace(oldK, newK *kargItem) error {
 	if oldK == nil {
 		return fmt.Errorf("replace: old item: %w", ErrNilPtr)
@@ -36,6 +42,7 @@ func replace(oldK, newK *kargItem) error {
 	}
 	newK.prev = oldK.prev
 	newK.next = oldK.next
+	newK.line = oldK.line
 	if oldK.prev != nil {
 		oldK.prev.next = newK
 	}
@@ -47,3 +54,214 @@ func replace(oldK, newK *kargItem) error {
 
 	return nil
 }
+
+// appendItem adds karg to the end of the list of k, updating the key and module
+// maps and the parameter count, and returns the new list item.
+func (k *Kargs) appendItem(karg Karg) *kargItem {
+	newKargItem := k.newItem(karg)
+	newKargItem.prev = k.last
+	k.invalidate()
+	if k.list == nil {
+		k.list = newKargItem
+	} else {
+		k.last.next = newKargItem
+	}
+	k.last = newKargItem
+	k.keyMap[karg.CanonicalKey] = append(k.keyMap[karg.CanonicalKey], newKargItem)
+	k.indexModule(newKargItem)
+	k.numParams++
+	return newKargItem
+}
+
+// insertBefore adds karg to the list of k right before mark, or at the end if
+// mark is nil, updating the key and module maps and the parameter count, and
+// returns the new list item.
+func (k *Kargs) insertBefore(mark *kargItem, karg Karg) *kargItem {
+	if mark == nil {
+		return k.appendItem(karg)
+	}
+	newKargItem := k.newItem(karg)
+	k.linkBefore(mark, newKargItem)
+	k.keyMap[karg.CanonicalKey] = insertInOrder(k.keyMap[karg.CanonicalKey], newKargItem)
+	if mod, ok := moduleName(karg.CanonicalKey); ok {
+		k.moduleMap[mod] = insertInOrder(k.moduleMap[mod], newKargItem)
+	}
+	k.numParams++
+	return newKargItem
+}
+
+// linkBefore links the unlinked item into the list of k right before mark, or
+// at the end if mark is nil. It does not update the key and module maps.
+func (k *Kargs) linkBefore(mark, item *kargItem) {
+	k.invalidate()
+	if mark == nil {
+		item.prev = k.last
+		item.next = nil
+		if k.last != nil {
+			k.last.next = item
+		} else {
+			k.list = item
+		}
+		k.last = item
+		return
+	}
+	item.prev = mark.prev
+	item.next = mark
+	if mark.prev != nil {
+		mark.prev.next = item
+	} else {
+		k.list = item
+	}
+	mark.prev = item
+}
+
+// moveItems unlinks items, which must be in list order, and links them back in
+// the same order before the item returned by markFn, which is called once they
+// are unlinked and may return nil for the end of the list.
+func (k *Kargs) moveItems(items []*kargItem, markFn func() *kargItem) {
+	for _, item := range items {
+		k.unlink(item)
+	}
+	mark := markFn()
+	for _, item := range items {
+		k.linkBefore(mark, item)
+	}
+	// The relative order of items is kept, so only the module index, which
+	// holds other keys as well, needs to be brought back into list order.
+	if mod, ok := moduleName(items[0].karg.CanonicalKey); ok {
+		moduleItems := k.moduleMap[mod][:0]
+		for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+			if itemMod, _ := moduleName(llTracker.karg.CanonicalKey); itemMod == mod {
+				moduleItems = append(moduleItems, llTracker)
+			}
+		}
+		k.moduleMap[mod] = moduleItems
+	}
+}
+
+// insertInOrder inserts item into items, which holds list items in list order,
+// such that the order is kept.
+func insertInOrder(items []*kargItem, item *kargItem) []*kargItem {
+	member := make(map[*kargItem]int, len(items))
+	for idx, ptr := range items {
+		member[ptr] = idx + 1
+	}
+	pos := 0
+	for prev := item.prev; prev != nil; prev = prev.prev {
+		if idx, ok := member[prev]; ok {
+			pos = idx
+			break
+		}
+	}
+	items = append(items, nil)
+	copy(items[pos+1:], items[pos:])
+	items[pos] = item
+	return items
+}
+
+// unlink removes item from the list of k like remove, but also updates the
+// pointers to the first and last items of the list if needed.
+func (k *Kargs) unlink(item *kargItem) error {
+	if item == nil {
+		return fmt.Errorf("unlink: %w", ErrNilPtr)
+	}
+	if k.list == item {
+		k.list = item.next
+	}
+	if k.last == item {
+		k.last = item.prev
+	}
+	return remove(item)
+}
+
+// removeItem removes item from k entirely, i.e. from the list, the key and
+// module maps, and the parameter count.
+func (k *Kargs) removeItem(item *kargItem) error {
+	if err := k.unlink(item); err != nil {
+		return err
+	}
+	canonicalKey := item.karg.CanonicalKey
+	items := k.keyMap[canonicalKey]
+	for idx, ptr := range items {
+		if ptr == item {
+			if len(items) == 1 {
+				delete(k.keyMap, canonicalKey)
+			} else {
+				newItems := make([]*kargItem, 0, len(items)-1)
+				newItems = append(newItems, items[:idx]...)
+				k.keyMap[canonicalKey] = append(newItems, items[idx+1:]...)
+			}
+			break
+		}
+	}
+	k.unindexModule(item)
+	k.numParams--
+	k.invalidate()
+	return nil
+}
+
+// itemAt returns the list item at position i of k, counting from 0, or nil if i
+// is out of range.
+func (k *Kargs) itemAt(i int) *kargItem {
+	if i < 0 || i >= k.numParams {
+		return nil
+	}
+	llTracker := k.list
+	for ; i > 0; i-- {
+		llTracker = llTracker.next
+	}
+	return llTracker
+}
+
+// newItem returns a new, unlinked list item holding karg, taken from the items
+// allocated ahead by parse or allocated from the arena of k if it has one.
+func (k *Kargs) newItem(karg Karg) *kargItem {
+	if len(k.slab) > 0 {
+		item := &k.slab[0]
+		k.slab = k.slab[1:]
+		item.karg = karg
+		return item
+	}
+	if k.cfg.arena == nil {
+		return &kargItem{karg: karg}
+	}
+	item := k.cfg.arena.newItem()
+	item.karg = karg
+	return item
+}
+
+// filter returns a new Kargs containing copies of the kargs of k for which keep
+// returns true, in list order.
+func (k *Kargs) filter(keep func(Karg) bool) *Kargs {
+	filtered := NewKargsEmpty()
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if keep(llTracker.karg) {
+			filtered.appendItem(llTracker.karg)
+		}
+	}
+	return filtered
+}
+
+// reset removes all kargs from k, keeping its settings. The key and module maps
+// are emptied rather than replaced, so that their storage is reused.
+func (k *Kargs) reset() {
+	k.list = nil
+	k.last = nil
+	if k.keyMap == nil {
+		k.keyMap = make(map[string][]*kargItem)
+	}
+	if k.moduleMap == nil {
+		k.moduleMap = make(map[string][]*kargItem)
+	}
+	clear(k.keyMap)
+	clear(k.moduleMap)
+	k.numParams = 0
+	k.invalidate()
+}
+
+// invalidate marks the cached string form of k as stale. It must be called by
+// every operation that modifies the list.
+func (k *Kargs) invalidate() {
+	k.str = ""
+	k.strValid = false
+}
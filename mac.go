@@ -0,0 +1,170 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// MACChange records a value rewritten by NormalizeMACs.
+type MACChange struct {
+	Key      string // Key of the karg, as spelled on the command line
+	OldValue string // Dequoted value before normalizing
+	NewValue string // Dequoted value after normalizing
+}
+
+// NormalizeMAC returns the canonical form of the 48-bit MAC address mac, i.e.
+// six lowercase hexadecimal pairs separated by colons, like
+// 52:54:00:ab:cd:ef. mac may use colons, hyphens, or the dotted form
+// 5254.00ab.cdef. An error wrapping ErrInvalidAddress is returned if mac is not
+// such an address.
+func NormalizeMAC(mac string) (string, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return "", fmt.Errorf("MAC address %s: %w", mac, ErrInvalidAddress)
+	}
+	return hw.String(), nil
+}
+
+// NormalizeMACs finds the MAC addresses embedded in the values of the kargs of
+// k, e.g. in ifname=eth0:52-54-00-AB-CD-EF or netconsole=, and rewrites them
+// to the form returned by NormalizeMAC. BOOTIF= is the exception: as
+// PXELINUX and dracut expect, its address keeps hyphens as separators and its
+// leading hardware type, as in BOOTIF=01-52-54-00-ab-cd-ef, and is only
+// lowercased. It returns the values that changed, in command line order.
+// Values the QuoteMode of k rejects after normalizing are left unchanged, like
+// in RewriteValues, and not returned.
+func (k *Kargs) NormalizeMACs() []MACChange {
+	var changes []MACChange
+	k.rewriteValues(func(key, value string) (string, bool) {
+		sep := byte(':')
+		if key == "BOOTIF" {
+			sep = '-'
+		}
+		normalized := normalizeMACsIn(value, sep)
+		return normalized, normalized != value
+	}, func(oldKarg, newKarg Karg) {
+		changes = append(changes, MACChange{Key: oldKarg.Key, OldValue: oldKarg.Value, NewValue: newKarg.Value})
+	})
+	return changes
+}
+
+// normalizeMACsIn returns value with each MAC address found in it lowercased
+// and its separators replaced by sep.
+func normalizeMACsIn(value string, sep byte) string {
+	var repls []replacement
+	for _, span := range macSpans(value) {
+		mac, _ := NormalizeMAC(value[span[0]:span[1]])
+		if sep != ':' {
+			mac = strings.ReplaceAll(mac, ":", string(sep))
+		}
+		repls = append(repls, replacement{start: span[0], end: span[1], text: mac})
+	}
+	return applyReplacements(value, repls)
+}
+
+// macSpans returns the start and end byte offsets of the MAC addresses found in
+// value, in order. For the BOOTIF form, the span excludes the hardware type.
+func macSpans(value string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(value); {
+		if i > 0 && isAlnum(value[i-1]) || continuesPairs(value, i) {
+			i++
+			continue
+		}
+		start, end := macAt(value, i)
+		if end < 0 {
+			i++
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+		i = end
+	}
+	return spans
+}
+
+// replacement replaces the bytes of a string from start to end with text.
+type replacement struct {
+	start, end int
+	text       string
+}
+
+// applyReplacements returns value with repls, which must be ordered and not
+// overlap, applied.
+func applyReplacements(value string, repls []replacement) string {
+	if len(repls) == 0 {
+		return value
+	}
+	var sb strings.Builder
+	last := 0
+	for _, repl := range repls {
+		sb.WriteString(value[last:repl.start])
+		sb.WriteString(repl.text)
+		last = repl.end
+	}
+	sb.WriteString(value[last:])
+	return sb.String()
+}
+
+// macAt returns the byte offsets of the MAC address starting at offset i of
+// value, or -1 as end if there is none. Addresses must stand alone, i.e. not be
+// followed by a letter or digit. Seven hyphen-separated pairs are taken as a
+// hardware type followed by an address, as used by BOOTIF, in which case start
+// is past the hardware type.
+func macAt(value string, i int) (start, end int) {
+	if end := i + 14; end <= len(value) && (end == len(value) || !isAlnum(value[end])) &&
+		value[i+4] == '.' && value[i+9] == '.' && isHex(value[i:i+4]) && isHex(value[i+5:i+9]) && isHex(value[i+10:end]) {
+		return i, end
+	}
+	if i+2 > len(value) || !isHex(value[i:i+2]) {
+		return i, -1
+	}
+	pairs, pos := 1, i+2
+	var sep byte
+	for pos+3 <= len(value) && (value[pos] == ':' || value[pos] == '-') && isHex(value[pos+1:pos+3]) {
+		if sep == 0 {
+			sep = value[pos]
+		} else if value[pos] != sep {
+			break
+		}
+		pairs++
+		pos += 3
+	}
+	if pos < len(value) && isAlnum(value[pos]) {
+		return i, -1
+	}
+	switch {
+	case pairs == 6:
+		return i, pos
+	case pairs == 7 && sep == '-':
+		return i + 3, pos
+	}
+	return i, -1
+}
+
+// continuesPairs returns whether offset i of value follows a hexadecimal pair
+// and a separator, so that an address found there would be the tail of a longer
+// run of pairs rather than stand alone.
+func continuesPairs(value string, i int) bool {
+	return i >= 3 && (value[i-1] == ':' || value[i-1] == '-') && isHex(value[i-3:i-1]) &&
+		(i == 3 || !isAlnum(value[i-4]))
+}
+
+// isHex returns whether s consists of hexadecimal digits only.
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlnum returns whether c is an ASCII letter or digit.
+func isAlnum(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
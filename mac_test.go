@@ -0,0 +1,49 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMAC(t *testing.T) {
+	for _, mac := range []string{"52:54:00:AB:CD:EF", "52-54-00-ab-cd-ef", "5254.00ab.cdef", "52:54:00:ab:cd:ef"} {
+		normalized, err := NormalizeMAC(mac)
+		assert.NoError(t, err, mac)
+		assert.Equal(t, "52:54:00:ab:cd:ef", normalized, mac)
+	}
+
+	for _, mac := range []string{"", "52:54:00:ab:cd", "52:54:00:ab:cd:ef:01:23", "52:54:00:ab:cd:eg"} {
+		_, err := NormalizeMAC(mac)
+		assert.ErrorIs(t, err, ErrInvalidAddress, mac)
+	}
+}
+
+func TestKargs_NormalizeMACs(t *testing.T) {
+	k := NewKargs([]byte("ifname=eth0:52-54-00-AB-CD-EF BOOTIF=01:52:54:00:AB:CD:EF BOOTIF=01-52-54-00-AB-CD-EF " +
+		"netconsole=6665@10.0.0.1/eth0,6666@10.0.0.2/5254.00AB.CDEF ip=[fe80::52:54:0:ab:cd:ef]:dhcp " +
+		"rd.id=01:52:54:00:AB:CD:EF ifname=eth1:52:54:00:12:34:56 root=/dev/sda1"))
+
+	changes := k.NormalizeMACs()
+	assert.Equal(t, []MACChange{
+		{Key: "ifname", OldValue: "eth0:52-54-00-AB-CD-EF", NewValue: "eth0:52:54:00:ab:cd:ef"},
+		{Key: "BOOTIF", OldValue: "01-52-54-00-AB-CD-EF", NewValue: "01-52-54-00-ab-cd-ef"},
+		{Key: "netconsole", OldValue: "6665@10.0.0.1/eth0,6666@10.0.0.2/5254.00AB.CDEF", NewValue: "6665@10.0.0.1/eth0,6666@10.0.0.2/52:54:00:ab:cd:ef"},
+	}, changes)
+	assert.Equal(t, "ifname=eth0:52:54:00:ab:cd:ef BOOTIF=01:52:54:00:AB:CD:EF BOOTIF=01-52-54-00-ab-cd-ef "+
+		"netconsole=6665@10.0.0.1/eth0,6666@10.0.0.2/52:54:00:ab:cd:ef ip=[fe80::52:54:0:ab:cd:ef]:dhcp "+
+		"rd.id=01:52:54:00:AB:CD:EF ifname=eth1:52:54:00:12:34:56 root=/dev/sda1", k.String())
+
+	assert.Empty(t, k.NormalizeMACs())
+
+	// Rewrites the QuoteMode rejects are not reported.
+	k = NewKargs([]byte(`msg="mac 52-54-00-AB-CD-EF" ifname=eth0:52-54-00-AB-CD-EF`), WithQuoteMode(QuoteNever))
+	changes = k.NormalizeMACs()
+	assert.Equal(t, []MACChange{
+		{Key: "ifname", OldValue: "eth0:52-54-00-AB-CD-EF", NewValue: "eth0:52:54:00:ab:cd:ef"},
+	}, changes)
+	assert.Equal(t, `msg="mac 52-54-00-AB-CD-EF" ifname=eth0:52:54:00:ab:cd:ef`, k.String())
+}
@@ -0,0 +1,145 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Marshal returns a new Kargs holding the fields of the struct v, or the struct
+// v points to, as parameters, in field order. It is the inverse of Unmarshal
+// and uses the same karg struct tags, so unmarshaling the result yields the
+// original values. Fields are written as follows:
+//
+//   - strings as key=value, or as key alone if empty
+//   - booleans as key alone if true and as key=0 if false
+//   - integers in decimal, or with the size option in the largest unit of K,
+//     M, G, T, P, or E that represents them exactly, e.g. 2G
+//   - slices with the multi option as one parameter per element
+//   - slices with the csv option as one parameter with the elements joined by
+//     commas, each in double quotes if it contains a comma
+//
+// Fields with the omitempty option are left out if they have their zero value
+// or are empty slices; slices with the multi option are always left out if
+// empty. Values are written in kernel form as described in Sign, so that both
+// the kernel and NewKargs read the String of the result back exactly.
+//
+// An error wrapping ErrNilPtr is returned if v is nil, and one wrapping
+// ErrUnsupported if v is not a struct or a pointer to one or a tag is malformed
+// or doesn't fit its field. An error wrapping ErrInvalidValue is returned for
+// values that can't be written such that Unmarshal reads them back, i.e.
+// negative sizes and csv elements containing double quotes, one wrapping
+// ErrUnquotable for values that need quotes but contain a double quote, and one
+// wrapping ErrKernelMismatch if the kernel would read a value differently for
+// any other reason.
+func Marshal(v any) (*Kargs, error) {
+	rv := reflect.ValueOf(v)
+	if v == nil || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, fmt.Errorf("marshaling %T: %w", v, ErrNilPtr)
+	}
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("marshaling %T: %w", v, ErrUnsupported)
+	}
+	fields, err := kargFields(rv.Type())
+	if err != nil {
+		return nil, fmt.Errorf("marshaling %T: %w", v, err)
+	}
+	k := NewKargsEmpty()
+	for _, field := range fields {
+		values, err := field.values(rv.Field(field.index))
+		if err != nil {
+			return nil, fmt.Errorf("marshaling %T: %w", v, err)
+		}
+		for _, value := range values {
+			karg, err := kernelKarg(field.key, value)
+			if err != nil {
+				return nil, fmt.Errorf("marshaling %T: %w", v, err)
+			}
+			k.appendItem(karg)
+		}
+	}
+	if _, err := k.kernelForm(); err != nil {
+		return nil, fmt.Errorf("marshaling %T: %w", v, err)
+	}
+	return k, nil
+}
+
+// values returns the values of the parameters fv, the value of f, is written
+// as, one per parameter.
+func (f kargField) values(fv reflect.Value) ([]string, error) {
+	if f.omit && fv.IsZero() || fv.Kind() == reflect.Slice && fv.Len() == 0 && (f.omit || f.multi) {
+		return nil, nil
+	}
+	if fv.Kind() != reflect.Slice {
+		value, err := f.format(fv)
+		if err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+	values := make([]string, fv.Len())
+	for idx := range values {
+		value, err := f.format(fv.Index(idx))
+		if err != nil {
+			return nil, err
+		}
+		if f.csv {
+			if strings.Contains(value, `"`) {
+				return nil, fmt.Errorf("field %s: element %q contains a quote: %w", f.name, value, ErrInvalidValue)
+			}
+			if strings.Contains(value, ",") {
+				value = `"` + value + `"`
+			}
+		}
+		values[idx] = value
+	}
+	if f.csv {
+		return []string{strings.Join(values, ",")}, nil
+	}
+	return values, nil
+}
+
+// format returns fv, which is not a slice, as a value.
+func (f kargField) format(fv reflect.Value) (string, error) {
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		if fv.Bool() {
+			return "", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := fv.Int()
+		if !f.size {
+			return strconv.FormatInt(n, 10), nil
+		}
+		if n < 0 {
+			return "", fmt.Errorf("field %s: negative size %d: %w", f.name, n, ErrInvalidValue)
+		}
+		return formatMemSize(uint64(n)), nil
+	default:
+		if f.size {
+			return formatMemSize(fv.Uint()), nil
+		}
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	}
+}
+
+// formatMemSize returns n in the largest memparse unit that represents it
+// exactly.
+func formatMemSize(n uint64) string {
+	for i := len("KMGTPE"); i > 0; i-- {
+		if shift := 10 * i; n != 0 && n&(1<<shift-1) == 0 {
+			return strconv.FormatUint(n>>shift, 10) + "KMGTPE"[i-1:i]
+		}
+	}
+	return strconv.FormatUint(n, 10)
+}
@@ -0,0 +1,85 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type marshalParams struct {
+	Root     string   `karg:"root"`
+	ReadOnly bool     `karg:"ro,omitempty"`
+	Splash   bool     `karg:"splash"`
+	Consoles []string `karg:"console,multi"`
+	Mem      uint64   `karg:"mem,size"`
+	Crash    int64    `karg:"crashkernel,size,omitempty"`
+	Loglevel int8     `karg:"loglevel"`
+	Blocked  []string `karg:"modprobe.blacklist,csv,omitempty"`
+	Init     string   `karg:"init"`
+	Label    string   `karg:"label,omitempty"`
+	Skipped  string   `karg:"-"`
+	Untagged int
+}
+
+func TestMarshal(t *testing.T) {
+	in := marshalParams{
+		Root:     "/dev/sda1",
+		ReadOnly: true,
+		Consoles: []string{"tty0", "ttyS0,115200"},
+		Mem:      3 << 29,
+		Loglevel: -1,
+		Blocked:  []string{"nouveau", "odd,name"},
+		Init:     `/sbin/init --opt 'x'`,
+		Skipped:  "x",
+		Untagged: 1,
+	}
+	k, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `root=/dev/sda1 ro splash=0 console=tty0 console=ttyS0,115200 mem=1536M loglevel=-1 `+
+		`modprobe.blacklist=nouveau,"odd,name" init="/sbin/init --opt 'x'"`, k.String())
+
+	// The command line is read back exactly, by the kernel as well.
+	in.Skipped, in.Untagged = "", 0
+	for _, k := range []*Kargs{k, NewKargs([]byte(k.String()))} {
+		var out marshalParams
+		assert.NoError(t, Unmarshal(k, &out))
+		assert.Equal(t, in, out)
+	}
+	assert.Equal(t, []string{"root=/dev/sda1", "ro", "splash=0", "console=tty0", "console=ttyS0,115200",
+		"mem=1536M", "loglevel=-1", `modprobe.blacklist=nouveau,"odd,name"`, "init=/sbin/init --opt 'x'"},
+		SplitLikeKernel(k.String()))
+
+	// Pointers work as well, and zero values without omitempty are kept.
+	k, err = Marshal(&marshalParams{Splash: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "root splash mem=0 loglevel=0 init", k.String())
+}
+
+func TestMarshal_errors(t *testing.T) {
+	_, err := Marshal(nil)
+	assert.ErrorIs(t, err, ErrNilPtr)
+	_, err = Marshal((*marshalParams)(nil))
+	assert.ErrorIs(t, err, ErrNilPtr)
+	_, err = Marshal("root")
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = Marshal(struct {
+		V []string `karg:"v"`
+	}{})
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = Marshal(marshalParams{Crash: -1})
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = Marshal(marshalParams{Blocked: []string{`a"b`}})
+	assert.ErrorIs(t, err, ErrInvalidValue)
+
+	// Values the kernel can't read back are rejected.
+	_, err = Marshal(marshalParams{Init: `"q`, Label: "l"})
+	assert.ErrorIs(t, err, ErrUnquotable)
+	_, err = Marshal(marshalParams{Init: `a "b" c`})
+	assert.ErrorIs(t, err, ErrUnquotable)
+	_, err = Marshal(marshalParams{Init: `a"b`, Label: "l"})
+	assert.ErrorIs(t, err, ErrKernelMismatch)
+}
@@ -0,0 +1,41 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"crypto"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// MeasurementDigest returns the digest, computed with alg, of the command line
+// as systemd-stub measures it into the TPM when booting a unified kernel image:
+// the string form of k, as returned by String, encoded as UTF-16LE and
+// terminated by a NUL character. Verifiers can use it to precompute the
+// expected PCR values for k. Bytes that aren't valid UTF-8 are encoded as
+// U+FFFD.
+//
+// Like crypto.Hash.New, it panics if alg is not available.
+func (k *Kargs) MeasurementDigest(alg crypto.Hash) []byte {
+	units := utf16.Encode([]rune(k.String()))
+	buf := make([]byte, 0, 2*len(units)+2)
+	for _, unit := range units {
+		buf = binary.LittleEndian.AppendUint16(buf, unit)
+	}
+	buf = append(buf, 0, 0)
+	h := alg.New()
+	h.Write(buf)
+	return h.Sum(nil)
+}
+
+// MeasurementDigestUTF8 returns the digest, computed with alg, of the command
+// line as measured by boot loaders that pass it on unconverted: the string form
+// of k, as returned by String, terminated by a NUL byte.
+//
+// Like crypto.Hash.New, it panics if alg is not available.
+func (k *Kargs) MeasurementDigestUTF8(alg crypto.Hash) []byte {
+	h := alg.New()
+	h.Write([]byte(k.Format(WithNUL())))
+	return h.Sum(nil)
+}
@@ -0,0 +1,32 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"crypto"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_MeasurementDigest(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 quiet"))
+
+	want := sha256.Sum256([]byte("r\x00o\x00o\x00t\x00=\x00/\x00d\x00e\x00v\x00/\x00s\x00d\x00a\x001\x00 \x00q\x00u\x00i\x00e\x00t\x00\x00\x00"))
+	assert.Equal(t, want[:], k.MeasurementDigest(crypto.SHA256))
+	assert.Len(t, k.MeasurementDigest(crypto.SHA384), 48)
+
+	// Characters outside the BMP are encoded as surrogate pairs.
+	k = NewKargs([]byte("x=\U0001F600"))
+	want = sha256.Sum256([]byte("x\x00=\x00\x3d\xd8\x00\xde\x00\x00"))
+	assert.Equal(t, want[:], k.MeasurementDigest(crypto.SHA256))
+}
+
+func TestKargs_MeasurementDigestUTF8(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 quiet"))
+	want := sha256.Sum256([]byte("root=/dev/sda1 quiet\x00"))
+	assert.Equal(t, want[:], k.MeasurementDigestUTF8(crypto.SHA256))
+}
@@ -0,0 +1,221 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "fmt"
+
+// Conflict describes a key changed in different ways by both sides of a
+// three-way merge. Each side lists the values of the key in command line order,
+// with an empty string for occurrences without a value; a nil slice means the
+// key is absent on that side.
+type Conflict struct {
+	Key    string   // Canonical key
+	Base   []string // Values in the common ancestor
+	Ours   []string // Values on our side
+	Theirs []string // Values on their side
+}
+
+// Resolver decides the values of a key whose values differ between both sides
+// of a merge, given by its canonical key and the values on each side in the
+// form described in Conflict. It returns the values the merged kargs should
+// have, with an empty string for an occurrence without a value; returning no
+// values drops the key. Returning an error aborts the merge.
+type Resolver func(key string, ours, theirs []string) ([]string, error)
+
+// mergeSource tells where the kargs of a key in a merge come from.
+type mergeSource int
+
+const (
+	mergeOurs     mergeSource = iota // Key is taken from ours
+	mergeTheirs                      // Key is taken from theirs
+	mergeResolved                    // Key is taken from a Resolver
+)
+
+// mergeDecision records where the kargs of a key in a merge come from.
+type mergeDecision struct {
+	source   mergeSource
+	resolved []Karg // Kargs for mergeResolved
+}
+
+// Merge merges theirs into ours and returns the result as a new Kargs. Keys
+// present on only one side are taken from that side, and keys with the same
+// values on both sides are taken from ours. For keys whose values differ,
+// resolve decides the values; if resolve is nil, theirs wins. An error is
+// returned if resolve fails or returns a value that the QuoteMode of ours
+// rejects; resolved values are quoted according to it.
+//
+// The result is ordered like that of Merge3.
+func Merge(ours, theirs *Kargs, resolve Resolver) (*Kargs, error) {
+	if resolve == nil {
+		resolve = func(key string, ours, theirs []string) ([]string, error) {
+			return theirs, nil
+		}
+	}
+	decisions := make(map[string]mergeDecision)
+	for _, kargs := range []*Kargs{ours, theirs} {
+		for item := kargs.list; item != nil; item = item.next {
+			key := item.karg.CanonicalKey
+			if _, done := decisions[key]; done {
+				continue
+			}
+			ourVals, theirVals := ours.values(key), theirs.values(key)
+			switch {
+			case theirVals == nil, equalValues(ourVals, theirVals):
+				decisions[key] = mergeDecision{source: mergeOurs}
+			case ourVals == nil:
+				decisions[key] = mergeDecision{source: mergeTheirs}
+			default:
+				decision, err := resolveKey(key, ours, theirs, resolve)
+				if err != nil {
+					return nil, err
+				}
+				decisions[key] = decision
+			}
+		}
+	}
+	return assembleMerge(ours, theirs, decisions), nil
+}
+
+// Merge3 merges the changes made to base by ours and theirs and returns the
+// result as a new Kargs, along with the keys that conflict. Kargs are compared
+// by canonical key: a key changed on only one side, i.e. whose values differ
+// from those in base, takes the values of that side, including being added or
+// removed. A key changed on both sides in the same way takes those values. A
+// key changed on both sides in different ways is a conflict; the result keeps
+// our version of it. A nil base merges as if it were empty.
+//
+// The result keeps the order of ours, with kargs taken from theirs placed at
+// the first occurrence of their key in ours, and keys only present in theirs
+// appended in their order.
+func Merge3(base, ours, theirs *Kargs) (*Kargs, []Conflict) {
+	var conflicts []Conflict
+	merged, _ := merge3(base, ours, theirs, func(conflict Conflict) (mergeDecision, error) {
+		conflicts = append(conflicts, conflict)
+		return mergeDecision{source: mergeOurs}, nil
+	})
+	return merged, conflicts
+}
+
+// Merge3Func merges like Merge3, but lets resolve decide the values of
+// conflicting keys instead of reporting them. Resolved values are quoted
+// according to the QuoteMode of ours. An error is returned if resolve fails or
+// returns a value that QuoteMode rejects.
+func Merge3Func(base, ours, theirs *Kargs, resolve Resolver) (*Kargs, error) {
+	return merge3(base, ours, theirs, func(conflict Conflict) (mergeDecision, error) {
+		return resolveKey(conflict.Key, ours, theirs, resolve)
+	})
+}
+
+// merge3 implements Merge3, calling onConflict to decide conflicting keys.
+func merge3(base, ours, theirs *Kargs, onConflict func(Conflict) (mergeDecision, error)) (*Kargs, error) {
+	if base == nil {
+		base = NewKargsEmpty()
+	}
+	decisions := make(map[string]mergeDecision)
+	for _, kargs := range []*Kargs{ours, theirs, base} {
+		for item := kargs.list; item != nil; item = item.next {
+			key := item.karg.CanonicalKey
+			if _, done := decisions[key]; done {
+				continue
+			}
+			baseVals, ourVals, theirVals := base.values(key), ours.values(key), theirs.values(key)
+			switch {
+			case equalValues(ourVals, theirVals), equalValues(theirVals, baseVals):
+				decisions[key] = mergeDecision{source: mergeOurs}
+			case equalValues(ourVals, baseVals):
+				decisions[key] = mergeDecision{source: mergeTheirs}
+			default:
+				decision, err := onConflict(Conflict{Key: key, Base: baseVals, Ours: ourVals, Theirs: theirVals})
+				if err != nil {
+					return nil, err
+				}
+				decisions[key] = decision
+			}
+		}
+	}
+	return assembleMerge(ours, theirs, decisions), nil
+}
+
+// resolveKey calls resolve for the canonical key and turns the values it
+// returns into kargs quoted according to the QuoteMode of ours, spelling the key
+// like its first occurrence in ours, or in theirs if ours lacks it.
+func resolveKey(key string, ours, theirs *Kargs, resolve Resolver) (mergeDecision, error) {
+	vals, err := resolve(key, ours.values(key), theirs.values(key))
+	if err != nil {
+		return mergeDecision{}, fmt.Errorf("resolving key %s: %w", key, err)
+	}
+	spelling := key
+	if items := ours.keyMap[key]; len(items) > 0 {
+		spelling = items[0].karg.Key
+	} else if items := theirs.keyMap[key]; len(items) > 0 {
+		spelling = items[0].karg.Key
+	}
+	decision := mergeDecision{source: mergeResolved}
+	for _, val := range vals {
+		karg, err := ours.cfg.makeKarg(spelling, val)
+		if err != nil {
+			return mergeDecision{}, fmt.Errorf("resolving key %s: %w", key, err)
+		}
+		decision.resolved = append(decision.resolved, karg)
+	}
+	return decision, nil
+}
+
+// assembleMerge builds the result of a merge from the decisions made for each
+// canonical key, in the order described in Merge3.
+func assembleMerge(ours, theirs *Kargs, decisions map[string]mergeDecision) *Kargs {
+	merged := NewKargsEmpty()
+	placed := make(map[string]bool)
+	place := func(key string) {
+		if placed[key] {
+			return
+		}
+		placed[key] = true
+		switch decision := decisions[key]; decision.source {
+		case mergeTheirs:
+			for _, item := range theirs.keyMap[key] {
+				merged.appendItem(item.karg)
+			}
+		case mergeResolved:
+			for _, karg := range decision.resolved {
+				merged.appendItem(karg)
+			}
+		}
+	}
+	for item := ours.list; item != nil; item = item.next {
+		if key := item.karg.CanonicalKey; decisions[key].source == mergeOurs {
+			merged.appendItem(item.karg)
+		} else {
+			place(key)
+		}
+	}
+	for item := theirs.list; item != nil; item = item.next {
+		place(item.karg.CanonicalKey)
+	}
+	return merged
+}
+
+// values returns the values of all occurrences of the canonical key in k, or nil
+// if there are none.
+func (k *Kargs) values(canonicalKey string) []string {
+	var vals []string
+	for _, item := range k.keyMap[canonicalKey] {
+		vals = append(vals, item.karg.Value)
+	}
+	return vals
+}
+
+// equalValues returns whether a and b hold the same values in the same order,
+// treating nil and empty slices alike.
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
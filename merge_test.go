@@ -0,0 +1,125 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge3(t *testing.T) {
+	base := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet splash"))
+	ours := NewKargs([]byte("root=/dev/sda2 console=tty0 quiet debug"))
+	theirs := NewKargs([]byte("root=/dev/sda1 console=tty0 console=ttyS0 splash nomodeset"))
+
+	merged, conflicts := Merge3(base, ours, theirs)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, "root=/dev/sda2 console=tty0 console=ttyS0 debug nomodeset", merged.String())
+}
+
+func TestMerge3_conflicts(t *testing.T) {
+	base := NewKargs([]byte("root=/dev/sda1 quiet loglevel=3"))
+	ours := NewKargs([]byte("root=/dev/sda2 loglevel=7"))
+	theirs := NewKargs([]byte("root=/dev/sda3 quiet"))
+
+	merged, conflicts := Merge3(base, ours, theirs)
+	assert.Equal(t, []Conflict{
+		{Key: "root", Base: []string{"/dev/sda1"}, Ours: []string{"/dev/sda2"}, Theirs: []string{"/dev/sda3"}},
+		{Key: "loglevel", Base: []string{"3"}, Ours: []string{"7"}, Theirs: nil},
+	}, conflicts)
+	assert.Equal(t, "root=/dev/sda2 loglevel=7", merged.String())
+}
+
+func TestMerge3_sameChange(t *testing.T) {
+	base := NewKargs([]byte("root=/dev/sda1 quiet"))
+	ours := NewKargs([]byte("root=/dev/sda2 rd-break"))
+	theirs := NewKargs([]byte("rd_break root=/dev/sda2"))
+
+	merged, conflicts := Merge3(base, ours, theirs)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, "root=/dev/sda2 rd-break", merged.String())
+}
+
+func TestMerge3_nilBase(t *testing.T) {
+	ours := NewKargs([]byte("quiet root=/dev/sda1"))
+	theirs := NewKargs([]byte("quiet root=/dev/sda2 splash"))
+
+	merged, conflicts := Merge3(nil, ours, theirs)
+	assert.Equal(t, []Conflict{
+		{Key: "root", Ours: []string{"/dev/sda1"}, Theirs: []string{"/dev/sda2"}},
+	}, conflicts)
+	assert.Equal(t, "quiet root=/dev/sda1 splash", merged.String())
+}
+
+func TestMerge(t *testing.T) {
+	ours := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet crashkernel=128M"))
+	theirs := NewKargs([]byte("console=ttyS0 quiet crashkernel=256M splash"))
+
+	merged, err := Merge(ours, theirs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda1 console=ttyS0 quiet crashkernel=256M splash", merged.String())
+
+	union := func(key string, ours, theirs []string) ([]string, error) {
+		if key == "console" {
+			return append(ours, theirs...), nil
+		}
+		return ours, nil
+	}
+	merged, err = Merge(ours, theirs, union)
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda1 console=tty0 console=ttyS0 quiet crashkernel=128M splash", merged.String())
+}
+
+func TestMerge_resolverError(t *testing.T) {
+	failing := func(key string, ours, theirs []string) ([]string, error) {
+		return nil, ErrUnsupported
+	}
+	_, err := Merge(NewKargs([]byte("a=1")), NewKargs([]byte("a=2")), failing)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestMerge3Func(t *testing.T) {
+	base := NewKargs([]byte("root=/dev/sda1 rd-shell=128M quiet"))
+	ours := NewKargs([]byte("root=/dev/sda1 rd-shell=256M"))
+	theirs := NewKargs([]byte("root=/dev/sda2 rd_shell=512M quiet"))
+
+	var resolved []string
+	larger := func(key string, ours, theirs []string) ([]string, error) {
+		resolved = append(resolved, key)
+		if len(ours) == 1 && len(theirs) == 1 && theirs[0] > ours[0] {
+			return theirs, nil
+		}
+		return ours, nil
+	}
+	merged, err := Merge3Func(base, ours, theirs, larger)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rd_shell"}, resolved)
+	assert.Equal(t, "root=/dev/sda2 rd-shell=512M", merged.String())
+
+	drop := func(key string, ours, theirs []string) ([]string, error) {
+		return nil, nil
+	}
+	merged, err = Merge3Func(base, ours, theirs, drop)
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda2", merged.String())
+
+	failing := func(key string, ours, theirs []string) ([]string, error) {
+		return nil, ErrUnsupported
+	}
+	_, err = Merge3Func(base, ours, theirs, failing)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	// Resolved values are quoted according to the QuoteMode of ours.
+	spaced := func(key string, ours, theirs []string) ([]string, error) {
+		return []string{"256M 512M"}, nil
+	}
+	ours = NewKargs([]byte("root=/dev/sda1 rd-shell=256M"), WithQuoteMode(QuoteKernel))
+	merged, err = Merge3Func(base, ours, theirs, spaced)
+	assert.NoError(t, err)
+	assert.Equal(t, `root=/dev/sda2 rd-shell="256M 512M"`, merged.String())
+	ours = NewKargs([]byte("root=/dev/sda1 rd-shell=256M"), WithQuoteMode(QuoteNever))
+	_, err = Merge3Func(base, ours, theirs, spaced)
+	assert.ErrorIs(t, err, ErrUnquotable)
+}
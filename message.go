@@ -0,0 +1,174 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MessageID identifies the kind of a Message, independent of the language it
+// is rendered in. IDs are stable, so catalogs can be maintained outside of
+// this package.
+type MessageID string
+
+// IDs of the messages produced by this package, with the names of their
+// arguments.
+const (
+	MsgUnterminatedQuote MessageID = "parse.unterminated_quote" // raw
+	MsgEmptyKey          MessageID = "parse.empty_key"          // raw
+	MsgQuotedKey         MessageID = "parse.quoted_key"         // raw, key
+	MsgInvalidUTF8       MessageID = "parse.invalid_utf8"       // raw
+	MsgControlCharacter  MessageID = "parse.control_character"  // raw, char
+	MsgKernelMismatch    MessageID = "parse.kernel_mismatch"    // raw, kernel, want
+	MsgSpecMissing       MessageID = "spec.missing"             // key, want
+	MsgSpecForbidden     MessageID = "spec.forbidden"           // key, got
+	MsgSpecValue         MessageID = "spec.value"               // key, want, got
+	MsgOther             MessageID = "other"                    // error
+)
+
+// Code is the stable, machine-readable code of a kind of finding, like
+// KARG001, so that CI systems can suppress or gate on findings without matching
+// their text. A code is never reused or renumbered once released: kinds of
+// findings that are dropped leave a gap, and new ones get the next free number.
+// Codes KARG001 to KARG099 are problems found while parsing, codes from KARG100
+// on differences from a KargsSpec.
+type Code string
+
+// Codes of the findings produced by this package.
+const (
+	CodeUnterminatedQuote Code = "KARG001"
+	CodeEmptyKey          Code = "KARG002"
+	CodeQuotedKey         Code = "KARG003"
+	CodeInvalidUTF8       Code = "KARG004"
+	CodeControlCharacter  Code = "KARG005"
+	CodeKernelMismatch    Code = "KARG006"
+	CodeSpecMissing       Code = "KARG101"
+	CodeSpecForbidden     Code = "KARG102"
+	CodeSpecValue         Code = "KARG103"
+)
+
+// messageCodes maps the IDs of the messages produced by this package to the
+// codes of their findings.
+var messageCodes = map[MessageID]Code{
+	MsgUnterminatedQuote: CodeUnterminatedQuote,
+	MsgEmptyKey:          CodeEmptyKey,
+	MsgQuotedKey:         CodeQuotedKey,
+	MsgInvalidUTF8:       CodeInvalidUTF8,
+	MsgControlCharacter:  CodeControlCharacter,
+	MsgKernelMismatch:    CodeKernelMismatch,
+	MsgSpecMissing:       CodeSpecMissing,
+	MsgSpecForbidden:     CodeSpecForbidden,
+	MsgSpecValue:         CodeSpecValue,
+}
+
+// Code returns the code of the findings described by messages with ID id, or
+// "" if there is none, as for MsgOther and IDs not produced by this package.
+func (id MessageID) Code() Code {
+	return messageCodes[id]
+}
+
+// Message is a finding of a validation, like a ParseIssue or a Violation, in a
+// form that can be rendered in different languages or house styles by a
+// Catalog: an ID naming the kind of finding and the arguments filling in its
+// details.
+type Message struct {
+	ID   MessageID
+	Args map[string]string
+}
+
+// Catalog renders messages, e.g. in one language.
+type Catalog interface {
+	// Render returns m rendered, or false if the catalog doesn't know m.ID.
+	Render(m Message) (string, bool)
+}
+
+// TemplateCatalog is a Catalog holding a template per message ID, in which
+// each {name} is replaced by the argument called name.
+type TemplateCatalog map[MessageID]string
+
+// Render renders m with its template.
+func (c TemplateCatalog) Render(m Message) (string, bool) {
+	template, ok := c[m.ID]
+	if !ok {
+		return "", false
+	}
+	pairs := make([]string, 0, 2*len(m.Args))
+	for name, value := range m.Args {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template), true
+}
+
+// EnglishCatalog holds the English messages used when no other catalog knows a
+// message. It can serve as the starting point for other catalogs.
+var EnglishCatalog = TemplateCatalog{
+	MsgUnterminatedQuote: "{raw}: quote is not terminated",
+	MsgEmptyKey:          "{raw}: key is empty",
+	MsgQuotedKey:         "{raw}: key {key} is quoted",
+	MsgInvalidUTF8:       "{raw}: not valid UTF-8",
+	MsgControlCharacter:  "{raw}: contains control character {char}",
+	MsgKernelMismatch:    "{raw}: kernel reads {kernel} instead of {want}",
+	MsgSpecMissing:       "{key}={want} is missing",
+	MsgSpecForbidden:     "{key} must not be set, is set to {got}",
+	MsgSpecValue:         "{key} must be {want}, is {got}",
+	MsgOther:             "{error}",
+}
+
+// Render returns m rendered by the first of catalogs that knows its ID, or by
+// EnglishCatalog if none does. Messages unknown to all catalogs are rendered
+// as their ID followed by their arguments.
+func (m Message) Render(catalogs ...Catalog) string {
+	for _, catalog := range catalogs {
+		if s, ok := catalog.Render(m); ok {
+			return s
+		}
+	}
+	if s, ok := EnglishCatalog.Render(m); ok {
+		return s
+	}
+	names := make([]string, 0, len(m.Args))
+	for name := range m.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, len(names))
+	for idx, name := range names {
+		args[idx] = name + "=" + m.Args[name]
+	}
+	return fmt.Sprintf("%s(%s)", m.ID, strings.Join(args, ", "))
+}
+
+// String returns m rendered by EnglishCatalog.
+func (m Message) String() string {
+	return m.Render()
+}
+
+// messageError is an error carrying the Message describing it, so that the
+// finding it is reported in can be rendered by a Catalog.
+type messageError struct {
+	msg Message
+	err error
+}
+
+func (e *messageError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *messageError) Unwrap() error {
+	return e.err
+}
+
+// errorMessage returns the Message carried by err, or one with ID MsgOther
+// holding its text if there is none.
+func errorMessage(err error) Message {
+	var msgErr *messageError
+	if errors.As(err, &msgErr) {
+		return msgErr.msg
+	}
+	return Message{ID: MsgOther, Args: map[string]string{"error": err.Error()}}
+}
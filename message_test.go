@@ -0,0 +1,74 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage_Render(t *testing.T) {
+	_, issues := ParseStrictAll([]byte(`"k"=v a="open`))
+	if !assert.Len(t, issues, 3) {
+		return
+	}
+	assert.Equal(t, MsgQuotedKey, issues[0].Message().ID)
+	assert.Equal(t, `"k"`, issues[0].Message().Args["key"])
+	assert.Equal(t, MsgKernelMismatch, issues[1].Message().ID)
+	msg := issues[2].Message()
+	assert.Equal(t, MsgUnterminatedQuote, msg.ID)
+	assert.Equal(t, `a="open: quote is not terminated`, msg.String())
+
+	// Errors keep their text and sentinels.
+	assert.ErrorIs(t, issues[2], ErrUnterminatedQuote)
+	assert.EqualError(t, issues[2].Err, "checking quotes: quote is not terminated")
+
+	german := TemplateCatalog{
+		MsgUnterminatedQuote: "{raw}: Anführungszeichen nicht geschlossen",
+	}
+	houseStyle := TemplateCatalog{
+		MsgQuotedKey: "E042 quoted key in {raw}",
+	}
+	assert.Equal(t, `a="open: Anführungszeichen nicht geschlossen`, issues[2].Message().Render(german, houseStyle))
+	assert.Equal(t, `E042 quoted key in "k"=v`, issues[0].Message().Render(german, houseStyle))
+	assert.Equal(t, `"k"=v: kernel reads k"=v instead of "k"=v`, issues[1].Message().Render(german))
+
+	violation := Violation{Kind: ViolationValue, Key: "console", Want: "ttyS0", Got: []string{"tty0", "tty1"}}
+	assert.Equal(t, "console must be ttyS0, is tty0, tty1", violation.Message().String())
+	violation = Violation{Kind: ViolationMissing, Key: "quiet"}
+	assert.Equal(t, Message{ID: MsgSpecMissing, Args: map[string]string{"key": "quiet", "want": "", "got": ""}}, violation.Message())
+
+	unknown := Message{ID: "custom.thing", Args: map[string]string{"b": "2", "a": "1"}}
+	assert.Equal(t, "custom.thing(a=1, b=2)", unknown.String())
+
+	other := ParseIssue{Err: errors.New("something else")}
+	assert.Equal(t, "something else", other.Message().String())
+}
+
+func TestCodes(t *testing.T) {
+	_, issues := ParseStrictAll([]byte("=v a=\x01 \"k\"=v b=\"open"))
+	var codes []Code
+	for _, issue := range issues {
+		codes = append(codes, issue.Code())
+	}
+	assert.Equal(t, []Code{CodeEmptyKey, CodeControlCharacter, CodeQuotedKey, CodeKernelMismatch, CodeUnterminatedQuote}, codes)
+
+	assert.Equal(t, Code("KARG102"), Violation{Kind: ViolationForbidden, Key: "quiet"}.Code())
+	assert.Equal(t, Code(""), ParseIssue{Err: errors.New("something else")}.Code())
+	assert.Equal(t, Code(""), MessageID("custom.thing").Code())
+
+	// Every message ID produced by this package has its own code.
+	seen := make(map[Code]MessageID)
+	for id := range EnglishCatalog {
+		if id == MsgOther {
+			continue
+		}
+		code := id.Code()
+		assert.NotEmpty(t, code, id)
+		assert.NotContains(t, seen, code, id)
+		seen[code] = id
+	}
+}
@@ -0,0 +1,95 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// moduleName returns the module portion of canonicalKey (the part before the
+// first dot) and whether canonicalKey is a module parameter at all.
+func moduleName(canonicalKey string) (string, bool) {
+	idx := strings.Index(canonicalKey, ".")
+	if idx <= 0 {
+		return "", false
+	}
+	return canonicalKey[:idx], true
+}
+
+// indexModule adds item to the module map if it is a module parameter.
+func (k *Kargs) indexModule(item *kargItem) {
+	mod, ok := moduleName(item.karg.CanonicalKey)
+	if !ok {
+		return
+	}
+	k.moduleMap[mod] = append(k.moduleMap[mod], item)
+}
+
+// unindexModule removes item from the module map if it is present.
+func (k *Kargs) unindexModule(item *kargItem) {
+	mod, ok := moduleName(item.karg.CanonicalKey)
+	if !ok {
+		return
+	}
+	items := k.moduleMap[mod]
+	for idx, ptr := range items {
+		if ptr == item {
+			if len(items) == 1 {
+				delete(k.moduleMap, mod)
+			} else {
+				newItems := make([]*kargItem, 0, len(items)-1)
+				newItems = append(newItems, items[:idx]...)
+				k.moduleMap[mod] = append(newItems, items[idx+1:]...)
+			}
+			return
+		}
+	}
+}
+
+// reindexModule swaps oldItem for newItem in the module map, keeping its
+// position among the module's parameters when both belong to the same module.
+func (k *Kargs) reindexModule(oldItem, newItem *kargItem) {
+	oldMod, oldOk := moduleName(oldItem.karg.CanonicalKey)
+	newMod, newOk := moduleName(newItem.karg.CanonicalKey)
+	if oldOk && newOk && oldMod == newMod {
+		for idx, ptr := range k.moduleMap[oldMod] {
+			if ptr == oldItem {
+				k.moduleMap[oldMod][idx] = newItem
+				return
+			}
+		}
+	}
+	k.unindexModule(oldItem)
+	k.indexModule(newItem)
+}
+
+// moduleOptions returns the flags for the module identified by name as
+// individual flag or flag=value options, quoting values that contain
+// whitespace. Only the first occurrence of each flag is returned.
+func (k *Kargs) moduleOptions(name string) ([]string, error) {
+	if name == "" || strings.ContainsAny(name, ".= \n\t") {
+		return nil, fmt.Errorf("checking module %q: %w", name, ErrInvalidModule)
+	}
+	prefix := canonicalizeKey(name) + "."
+	var opts []string
+	for _, item := range k.moduleMap[canonicalizeKey(name)] {
+		canonicalFlag := item.karg.CanonicalKey
+		if !strings.HasPrefix(canonicalFlag, prefix) {
+			continue
+		}
+		if occurrences := k.keyMap[canonicalFlag]; len(occurrences) > 0 && occurrences[0] != item {
+			continue
+		}
+		opt := canonicalFlag[len(prefix):]
+		if value := item.karg.Value; value != "" {
+			if strings.ContainsAny(value, " \n\t") {
+				value = `"` + value + `"`
+			}
+			opt += "=" + value
+		}
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
@@ -0,0 +1,29 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+//go:build linux
+// +build linux
+
+package kargs
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// LoadModule loads the kernel module identified by name with modprobe(8),
+// passing it the module's flags from the command line as resolved by
+// LoadModuleOptions. The output of modprobe is included in the returned error
+// if it fails.
+func (k *Kargs) LoadModule(name string) error {
+	opts, err := k.moduleOptions(name)
+	if err != nil {
+		return err
+	}
+	out, err := exec.Command("modprobe", append([]string{name}, opts...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to load module %s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
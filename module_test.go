@@ -0,0 +1,57 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleName(t *testing.T) {
+	checks := []struct {
+		in    string
+		mod   string
+		isMod bool
+	}{
+		{in: "nomod", mod: "", isMod: false},
+		{in: "mod.key", mod: "mod", isMod: true},
+		{in: "mod.sub.key", mod: "mod", isMod: true},
+		{in: ".key", mod: "", isMod: false},
+	}
+	for _, check := range checks {
+		mod, isMod := moduleName(check.in)
+		assert.Equal(t, check.mod, mod)
+		assert.Equal(t, check.isMod, isMod)
+	}
+}
+
+func TestKargs_moduleMap(t *testing.T) {
+	k := NewKargs([]byte("mod.key1 nomod mod-two.key=val mod.key2=val"))
+	assert.Len(t, k.moduleMap, 2)
+	assert.Len(t, k.moduleMap["mod"], 2)
+	assert.Len(t, k.moduleMap["mod_two"], 1)
+
+	// Appending indexes new module parameters
+	k.AppendKargs("mod.key3 other.key")
+	assert.Len(t, k.moduleMap, 3)
+	assert.Len(t, k.moduleMap["mod"], 3)
+
+	// Setting replaces the indexed item in place
+	err := k.SetKarg("mod.key1", "val")
+	assert.NoError(t, err)
+	assert.Len(t, k.moduleMap["mod"], 3)
+	assert.Equal(t, "mod.key1=val", k.moduleMap["mod"][0].karg.Raw)
+
+	// Deleting removes the module once it has no parameters left
+	err = k.DeleteKarg("other.key")
+	assert.NoError(t, err)
+	assert.Len(t, k.moduleMap, 2)
+	_, exists := k.moduleMap["other"]
+	assert.False(t, exists)
+
+	err = k.DeleteKargByValue("mod.key2", "val")
+	assert.NoError(t, err)
+	assert.Len(t, k.moduleMap["mod"], 2)
+}
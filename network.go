@@ -0,0 +1,218 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// IPConfig is the network configuration given by an ip= parameter, in one of
+// the forms dracut accepts:
+//
+//	ip=<autoconf>
+//	ip=<interface>:<autoconf>[:[<mtu>][:<macaddr>]]
+//	ip=<client-IP>:[<peer>]:<gateway-IP>:<netmask>:<hostname>:<interface>:<autoconf>[:[<mtu>][:<macaddr>]]
+//	ip=<client-IP>:[<peer>]:<gateway-IP>:<netmask>:<hostname>:<interface>:<autoconf>[:[<dns1>][:<dns2>]]
+//
+// IPv6 addresses must be enclosed in brackets, e.g. [2001:db8::1], and may
+// carry a zone, e.g. [fe80::1%eth0]. Fields that are not given are left zero.
+type IPConfig struct {
+	ClientIP  netip.Addr   // Static address of the client
+	Peer      netip.Addr   // Address of the peer for point-to-point links
+	Gateway   netip.Addr   // Address of the default gateway
+	Netmask   string       // Netmask, as a dotted IPv4 mask or a prefix length
+	Hostname  string       // Host name of the client
+	Interface string       // Name of the interface to configure
+	Autoconf  string       // Configuration method, e.g. dhcp or none
+	MTU       int          // MTU of the interface
+	MAC       string       // MAC address to set on the interface
+	DNS       []netip.Addr // Name servers
+}
+
+// ParseIPConfig parses value, the value of an ip= parameter, into an IPConfig.
+// An error wrapping ErrInvalidAddress is returned if value does not follow one
+// of the forms described at IPConfig or holds an invalid address.
+func ParseIPConfig(value string) (IPConfig, error) {
+	var cfg IPConfig
+	fields, err := SplitAddressFields(value)
+	if err != nil {
+		return cfg, fmt.Errorf("parsing ip=%s: %w", value, err)
+	}
+	var rest []string
+	switch {
+	case len(fields) == 1:
+		cfg.Autoconf = fields[0]
+		return cfg, nil
+	case isAutoconf(fields[1]):
+		cfg.Interface, cfg.Autoconf = fields[0], fields[1]
+		rest = fields[2:]
+	case len(fields) >= 7:
+		for idx, addr := range []*netip.Addr{&cfg.ClientIP, &cfg.Peer, &cfg.Gateway} {
+			if *addr, err = parseOptionalAddr(fields[idx]); err != nil {
+				return cfg, fmt.Errorf("parsing ip=%s: %w", value, err)
+			}
+		}
+		if err := checkNetmask(fields[3]); err != nil {
+			return cfg, fmt.Errorf("parsing ip=%s: %w", value, err)
+		}
+		cfg.Netmask, cfg.Hostname, cfg.Interface, cfg.Autoconf = fields[3], fields[4], fields[5], fields[6]
+		rest = fields[7:]
+	default:
+		return cfg, fmt.Errorf("parsing ip=%s: %d fields: %w", value, len(fields), ErrInvalidAddress)
+	}
+	if err := cfg.parseTrailer(rest); err != nil {
+		return cfg, fmt.Errorf("parsing ip=%s: %w", value, err)
+	}
+	return cfg, nil
+}
+
+// parseTrailer parses the fields following the autoconf field into cfg, which
+// are either an MTU optionally followed by the six parts of a MAC address, or up
+// to two name servers.
+func (cfg *IPConfig) parseTrailer(rest []string) error {
+	if len(rest) == 0 {
+		return nil
+	}
+	if len(rest) == 7 || isDecimal(rest[0]) {
+		if rest[0] != "" {
+			mtu, err := strconv.Atoi(rest[0])
+			if err != nil {
+				return fmt.Errorf("MTU %s: %w", rest[0], ErrInvalidAddress)
+			}
+			cfg.MTU = mtu
+		}
+		switch {
+		case len(rest) == 7:
+			cfg.MAC = strings.Join(rest[1:], ":")
+		case len(rest) > 2 || (len(rest) == 2 && rest[1] != ""):
+			return fmt.Errorf("MAC address %s: %w", strings.Join(rest[1:], ":"), ErrInvalidAddress)
+		}
+		return nil
+	}
+	if len(rest) > 2 {
+		return fmt.Errorf("%d trailing fields: %w", len(rest), ErrInvalidAddress)
+	}
+	for _, field := range rest {
+		if field == "" {
+			continue
+		}
+		addr, err := parseOptionalAddr(field)
+		if err != nil {
+			return err
+		}
+		cfg.DNS = append(cfg.DNS, addr)
+	}
+	return nil
+}
+
+// IPConfigs parses the values of all ip= parameters of k with ParseIPConfig and
+// returns them in command line order. Parameters that fail to parse are left
+// out and reported in a KeyErrors, one per failure, keyed by ip.
+func (k *Kargs) IPConfigs() ([]IPConfig, error) {
+	var cfgs []IPConfig
+	var errs KeyErrors
+	for _, ptr := range k.keyMap["ip"] {
+		cfg, err := ParseIPConfig(ptr.karg.Value)
+		if err != nil {
+			errs = append(errs, &KeyError{Key: ptr.karg.Key, Err: err})
+			continue
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	if len(errs) > 0 {
+		return cfgs, errs
+	}
+	return cfgs, nil
+}
+
+// SplitAddressFields splits value, the value of a network parameter like ip=
+// or netroot=, into its colon-separated fields. Colons inside brackets do not
+// separate fields, so that IPv6 addresses can be given as e.g. [2001:db8::1];
+// the brackets of a field that is bracketed as a whole are removed. An error
+// wrapping ErrInvalidAddress is returned if a bracket is not closed.
+func SplitAddressFields(value string) ([]string, error) {
+	var fields []string
+	start, depth := 0, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return nil, fmt.Errorf("unopened bracket at offset %d: %w", i, ErrInvalidAddress)
+			}
+			depth--
+		case ':':
+			if depth == 0 {
+				fields = append(fields, unbracket(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unclosed bracket: %w", ErrInvalidAddress)
+	}
+	return append(fields, unbracket(value[start:])), nil
+}
+
+// unbracket removes the brackets enclosing field, if any.
+func unbracket(field string) string {
+	if len(field) >= 2 && field[0] == '[' && field[len(field)-1] == ']' {
+		return field[1 : len(field)-1]
+	}
+	return field
+}
+
+// parseOptionalAddr parses an address field as returned by SplitAddressFields,
+// which may hold an IPv6 zone. An empty field gives the zero Addr.
+func parseOptionalAddr(field string) (netip.Addr, error) {
+	if field == "" {
+		return netip.Addr{}, nil
+	}
+	addr, err := netip.ParseAddr(field)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("address %s: %w", field, ErrInvalidAddress)
+	}
+	return addr, nil
+}
+
+// checkNetmask checks that field is empty, a dotted IPv4 netmask, or a prefix
+// length.
+func checkNetmask(field string) error {
+	if field == "" {
+		return nil
+	}
+	if bits, err := strconv.Atoi(field); err == nil && bits >= 0 && bits <= 128 {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(field); err == nil && addr.Is4() {
+		return nil
+	}
+	return fmt.Errorf("netmask %s: %w", field, ErrInvalidAddress)
+}
+
+// isAutoconf returns whether field names an autoconfiguration method of ip=.
+func isAutoconf(field string) bool {
+	switch field {
+	case "none", "off", "dhcp", "on", "any", "dhcp6", "auto6", "either6", "link6", "single-dhcp", "ibft":
+		return true
+	}
+	return false
+}
+
+// isDecimal returns whether s is a non-empty string of decimal digits.
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
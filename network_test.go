@@ -0,0 +1,108 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitAddressFields(t *testing.T) {
+	fields, err := SplitAddressFields("[2001:db8::2]::[2001:db8::1]:64:host:eth0:none")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2001:db8::2", "", "2001:db8::1", "64", "host", "eth0", "none"}, fields)
+
+	fields, err = SplitAddressFields("iscsi:[fe80::1%eth0]:6:3260:0:iqn.2009-06.tld:disk")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"iscsi", "fe80::1%eth0", "6", "3260", "0", "iqn.2009-06.tld", "disk"}, fields)
+
+	fields, err = SplitAddressFields("dhcp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dhcp"}, fields)
+
+	_, err = SplitAddressFields("[2001:db8::1:eth0")
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+	_, err = SplitAddressFields("2001:db8::1]:eth0")
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestParseIPConfig(t *testing.T) {
+	checks := []struct {
+		value string
+		want  IPConfig
+	}{
+		{
+			value: "dhcp6",
+			want:  IPConfig{Autoconf: "dhcp6"},
+		},
+		{
+			value: "eth0:dhcp",
+			want:  IPConfig{Interface: "eth0", Autoconf: "dhcp"},
+		},
+		{
+			value: "eth0:dhcp:9000:52:54:00:12:34:56",
+			want:  IPConfig{Interface: "eth0", Autoconf: "dhcp", MTU: 9000, MAC: "52:54:00:12:34:56"},
+		},
+		{
+			value: "192.168.1.10::192.168.1.1:255.255.255.0:node1:eth0:none:1500",
+			want: IPConfig{
+				ClientIP:  netip.MustParseAddr("192.168.1.10"),
+				Gateway:   netip.MustParseAddr("192.168.1.1"),
+				Netmask:   "255.255.255.0",
+				Hostname:  "node1",
+				Interface: "eth0",
+				Autoconf:  "none",
+				MTU:       1500,
+			},
+		},
+		{
+			value: "[2001:db8::10]::[fe80::1%eth0]:64:node1:eth0:none:[2001:db8::53]:192.168.1.53",
+			want: IPConfig{
+				ClientIP:  netip.MustParseAddr("2001:db8::10"),
+				Gateway:   netip.MustParseAddr("fe80::1%eth0"),
+				Netmask:   "64",
+				Hostname:  "node1",
+				Interface: "eth0",
+				Autoconf:  "none",
+				DNS:       []netip.Addr{netip.MustParseAddr("2001:db8::53"), netip.MustParseAddr("192.168.1.53")},
+			},
+		},
+	}
+	for _, check := range checks {
+		cfg, err := ParseIPConfig(check.value)
+		assert.NoError(t, err, check.value)
+		assert.Equal(t, check.want, cfg, check.value)
+	}
+
+	for _, value := range []string{
+		"2001:db8::10::2001:db8::1:64:node1:eth0:none",
+		"[2001:db8::10]::[2001:db8::1]:64:node1:eth0",
+		"[not-an-ip]::::node1:eth0:none",
+		"10.0.0.2::10.0.0.1:255.0.0.0.0:node1:eth0:none",
+		"eth0:dhcp:1500:52:54",
+		"[2001:db8::10:eth0:none",
+	} {
+		_, err := ParseIPConfig(value)
+		assert.ErrorIs(t, err, ErrInvalidAddress, value)
+	}
+}
+
+func TestKargs_IPConfigs(t *testing.T) {
+	k := NewKargs([]byte("ip=eth0:dhcp ip=[2001:db8::10]::[2001:db8::1]:64::eth1:none ip=[broken rd.neednet=1"))
+
+	cfgs, err := k.IPConfigs()
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"ip"}, keyErrs.Keys())
+	assert.Len(t, cfgs, 2)
+	assert.Equal(t, "eth0", cfgs[0].Interface)
+	assert.Equal(t, netip.MustParseAddr("2001:db8::1"), cfgs[1].Gateway)
+
+	cfgs, err = NewKargs([]byte("quiet")).IPConfigs()
+	assert.NoError(t, err)
+	assert.Empty(t, cfgs)
+}
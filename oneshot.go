@@ -0,0 +1,39 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "fmt"
+
+// ConsumeOneShot removes all kargs marked with MarkOneShot from k and returns
+// them in their original order, so they apply to a single boot only.
+func (k *Kargs) ConsumeOneShot() ([]Karg, error) {
+	var consumed []Karg
+	for llTracker := k.list; llTracker != nil; {
+		next := llTracker.next
+		if llTracker.oneShot {
+			if err := k.removeItem(llTracker); err != nil {
+				return consumed, fmt.Errorf("failed to consume key %s: %w", llTracker.karg.Key, err)
+			}
+			consumed = append(consumed, llTracker.karg)
+		}
+		llTracker = next
+	}
+	return consumed, nil
+}
+
+// MarkOneShot marks all occurrences of key as one-shot, meaning that the next
+// call to ConsumeOneShot removes them, like grub-reboot does for boot entries.
+// The mark is kept in memory only: it is not part of the string form of k, and
+// a karg replaced by e.g. SetKarg loses it. An error is returned if key is not
+// set.
+func (k *Kargs) MarkOneShot(key string) error {
+	occurrences := k.keyMap[canonicalizeKey(key)]
+	if len(occurrences) == 0 {
+		return fmt.Errorf("failed to mark key %s as one-shot: %w", key, ErrNotExists)
+	}
+	for _, ptr := range occurrences {
+		ptr.oneShot = true
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_ConsumeOneShot(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 debug loglevel=7 quiet debug=full"))
+
+	err := k.MarkOneShot("debug")
+	assert.NoError(t, err)
+	err = k.MarkOneShot("loglevel")
+	assert.NoError(t, err)
+	err = k.MarkOneShot("nonexistent")
+	assert.ErrorIs(t, err, ErrNotExists)
+
+	consumed, err := k.ConsumeOneShot()
+	assert.NoError(t, err)
+	assert.Equal(t, []Karg{
+		{CanonicalKey: "debug", Key: "debug", Raw: "debug", Value: ""},
+		{CanonicalKey: "loglevel", Key: "loglevel", Raw: "loglevel=7", Value: "7"},
+		{CanonicalKey: "debug", Key: "debug", Raw: "debug=full", Value: "full"},
+	}, consumed)
+	assert.Equal(t, "root=/dev/sda1 quiet", k.String())
+	assert.False(t, k.ContainsKarg("debug"))
+	assert.Equal(t, 2, k.numParams)
+
+	// Nothing is left to consume
+	consumed, err = k.ConsumeOneShot()
+	assert.NoError(t, err)
+	assert.Empty(t, consumed)
+}
+
+func TestKargs_MarkOneShot_replaced(t *testing.T) {
+	k := NewKargs([]byte("debug=1"))
+	err := k.MarkOneShot("debug")
+	assert.NoError(t, err)
+
+	// Replacing the karg drops the mark
+	err = k.SetKarg("debug", "2")
+	assert.NoError(t, err)
+	consumed, err := k.ConsumeOneShot()
+	assert.NoError(t, err)
+	assert.Empty(t, consumed)
+	assert.Equal(t, "debug=2", k.String())
+}
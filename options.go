@@ -0,0 +1,208 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "fmt"
+
+// ParseOption configures how NewKargs parses a command line and how the
+// resulting Kargs behaves when it is modified afterwards.
+type ParseOption func(*parseConfig)
+
+// parseConfig holds the settings applied by ParseOptions.
+type parseConfig struct {
+	arena         *Arena      // Allocator for list items, nil for the heap
+	noBufferPool  bool        // Whether to allocate a fresh buffer for each render
+	compatDequote bool        // Whether to use the original dequoting rules
+	quoteMode     QuoteMode   // How to quote values that are written
+	setPosition   SetPosition // Where SetKarg leaves an existing key
+	ascii         bool        // Whether to parse with the ASCII-only tokenizer
+	maxTokenLen   int         // Maximum length of a parsed token, 0 for none
+	maxKeyLen     int         // Maximum length of a parsed key, 0 for none
+	maxParams     int         // Maximum number of parsed kargs, 0 for none
+
+	quotedSeparator    bool // Whether a quoted "--" separates init arguments
+	repeatedSeparators bool // Whether every "--", not just the first, is a separator
+
+	traceFn func(TraceEvent) // Receiver of parse decisions, nil for none
+}
+
+// newParseConfig applies opts on top of the default settings.
+func newParseConfig(opts ...ParseOption) parseConfig {
+	var cfg parseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// dequote removes the quotes surrounding value using the dequoting rules
+// selected by cfg.
+func (cfg parseConfig) dequote(value string) string {
+	if cfg.compatDequote {
+		return dequoteCompat(value)
+	}
+	return dequote(value)
+}
+
+// tokenize splits input into tokens with the tokenizer selected by cfg.
+func (cfg parseConfig) tokenize(input string, fn func(Token) error) error {
+	if cfg.ascii {
+		return tokenizeASCII(input, fn)
+	}
+	return Tokenize(input, fn)
+}
+
+// checkLimits returns a *LimitError if t, parsed after numParams kargs, exceeds
+// a limit of cfg.
+func (cfg parseConfig) checkLimits(t Token, numParams int) error {
+	switch {
+	case cfg.maxParams > 0 && numParams >= cfg.maxParams:
+		return &LimitError{Limit: LimitParams, Max: cfg.maxParams, Offset: t.Start}
+	case cfg.maxTokenLen > 0 && len(t.Raw) > cfg.maxTokenLen:
+		return &LimitError{Limit: LimitTokenLength, Max: cfg.maxTokenLen, Offset: t.Start}
+	case cfg.maxKeyLen > 0 && len(t.Key) > cfg.maxKeyLen:
+		return &LimitError{Limit: LimitKeyLength, Max: cfg.maxKeyLen, Offset: t.Start}
+	}
+	return nil
+}
+
+// makeKarg returns a Karg for key and value, quoting and dequoting value
+// according to the settings of cfg.
+func (cfg parseConfig) makeKarg(key, value string) (Karg, error) {
+	if err := checkKey(key); err != nil {
+		return Karg{}, fmt.Errorf("key check failed: %w", err)
+	}
+	karg := Karg{
+		Key:          enquote(key),
+		CanonicalKey: canonicalizeKey(key),
+		Value:        cfg.dequote(value),
+	}
+	if value == "" {
+		karg.Raw = enquote(key)
+	} else {
+		quoted, err := quoteMode(value, cfg.quoteMode)
+		if err != nil {
+			return Karg{}, fmt.Errorf("value check failed: %w", err)
+		}
+		karg.Raw = fmt.Sprintf("%s=%s", key, quoted)
+	}
+	return karg, nil
+}
+
+// WithArena makes the Kargs allocate its parameters from a instead of from the
+// heap, both while parsing and when parameters are added later. See Arena for
+// the lifetime rules that apply.
+func WithArena(a *Arena) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.arena = a
+	}
+}
+
+// WithASCII makes NewKargs parse the command line with a faster tokenizer that
+// only knows ASCII whitespace and quotes, without decoding UTF-8. It scans the
+// byte slice it is given as is to count the kargs, so that their storage is
+// allocated at once rather than karg by karg. The result is the same for
+// command lines that are pure ASCII, which is the common case.
+// Otherwise, non-ASCII characters are never separators or quotes, e.g. a
+// no-break space or typographic quotes are kept as part of the parameter, while
+// the default tokenizer treats them as whitespace and quotes.
+func WithASCII() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.ascii = true
+	}
+}
+
+// WithCompatDequote makes the Kargs remove quotes from values using the rules
+// of older versions of this package instead of the current, kernel-like ones.
+// It exists for callers that depend on the exact results of the old rules for
+// values mixing escaped and unescaped quotes.
+func WithCompatDequote() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.compatDequote = true
+	}
+}
+
+// WithMaxKeyLength limits the length of each key parsed from the command line
+// to n bytes. n <= 0 means no limit, which is the default.
+func WithMaxKeyLength(n int) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.maxKeyLen = n
+	}
+}
+
+// WithMaxParams limits the number of kargs parsed from the command line to n.
+// n <= 0 means no limit, which is the default.
+func WithMaxParams(n int) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.maxParams = n
+	}
+}
+
+// WithMaxTokenLength limits the length of each karg parsed from the command
+// line, including its key and value, to n bytes. n <= 0 means no limit, which
+// is the default.
+func WithMaxTokenLength(n int) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.maxTokenLen = n
+	}
+}
+
+// WithQuotedSeparator makes a "--" in double quotes, i.e. "--" with the quotes,
+// separate the kernel parameters from the arguments passed to init, like a bare
+// "--" does. The kernel removes double quotes before looking for the
+// separator, so this matches its behavior, but bootloaders that quote arguments
+// they pass through intend them to be taken literally, which is the default.
+// The kernel doesn't treat single quotes as quotes, so '--' never separates.
+func WithQuotedSeparator() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.quotedSeparator = true
+	}
+}
+
+// WithRepeatedSeparators makes every "--" a separator rather than just the
+// first one, so that a "--" following the first one is not passed to init as an
+// argument. The kernel passes such a "--" on to init, which is the default, but
+// command lines assembled by several bootloader stages may contain one
+// separator per stage.
+func WithRepeatedSeparators() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.repeatedSeparators = true
+	}
+}
+
+// WithQuoteMode makes the Kargs quote values written into it, e.g. by SetKarg,
+// according to mode. The default is QuoteDefault.
+func WithQuoteMode(mode QuoteMode) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.quoteMode = mode
+	}
+}
+
+// WithSetPosition makes SetKarg place the surviving occurrence of a key that
+// already exists according to pos. The default is SetKeepFirst.
+func WithSetPosition(pos SetPosition) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.setPosition = pos
+	}
+}
+
+// WithTrace makes NewKargs report the decisions it makes while parsing to fn,
+// e.g. where parameters are split and which quotes open and close quoted
+// sections, to help find out why an input parsed the way it did. fn is called
+// synchronously, in input order. Tracing is meant for debugging and slows
+// parsing down.
+func WithTrace(fn func(event TraceEvent)) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.traceFn = fn
+	}
+}
+
+// WithoutBufferPool makes the Kargs allocate a fresh buffer each time it is
+// rendered into a string instead of reusing buffers from a shared pool. This
+// trades throughput for not retaining buffer memory between renders.
+func WithoutBufferPool() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.noBufferPool = true
+	}
+}
@@ -0,0 +1,352 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Claim makes owner the only writer allowed to modify the kargs matched by
+// prefixOrKey through an OwnedKargs (see As). If prefixOrKey ends with a dot,
+// it matches all keys beginning with it, e.g. "rd." or "nvidia_drm."; otherwise
+// it matches that key only. Like elsewhere, - and _ are treated the same.
+//
+// Claiming again for the same owner does nothing. An error wrapping ErrOwned is
+// returned if the claim overlaps with one of another owner, and one wrapping
+// ErrInvalidKey if prefixOrKey is empty or contains invalid characters.
+func (k *Kargs) Claim(prefixOrKey, owner string) error {
+	if err := checkKey(prefixOrKey); err != nil {
+		return err
+	}
+	if prefixOrKey == "" || prefixOrKey == "." {
+		return fmt.Errorf("claiming %q: %w", prefixOrKey, ErrInvalidKey)
+	}
+	claim := canonicalizeKey(prefixOrKey)
+	for other, otherOwner := range k.claims {
+		if otherOwner != owner && claimsOverlap(claim, other) {
+			return fmt.Errorf("claiming %s for %s: %s is claimed by %s: %w", prefixOrKey, owner, other, otherOwner, ErrOwned)
+		}
+	}
+	k.claimFor(claim, owner)
+	return nil
+}
+
+// Owner returns the owner of key, if it was claimed by Claim.
+func (k *Kargs) Owner(key string) (string, bool) {
+	canonicalKey := canonicalizeKey(key)
+	for claim, owner := range k.claims {
+		if claimMatches(claim, canonicalKey) {
+			return owner, true
+		}
+	}
+	return "", false
+}
+
+// claimFor records the canonical claim for owner.
+func (k *Kargs) claimFor(claim, owner string) {
+	if k.claims == nil {
+		k.claims = make(map[string]string)
+	}
+	k.claims[claim] = owner
+}
+
+// checkOwner returns an error wrapping ErrOwned if key is claimed by someone
+// other than owner.
+func (k *Kargs) checkOwner(key, owner string) error {
+	if claimedBy, ok := k.Owner(key); ok && claimedBy != owner {
+		return fmt.Errorf("modifying %s as %s: claimed by %s: %w", key, owner, claimedBy, ErrOwned)
+	}
+	return nil
+}
+
+// claimMatches returns whether the canonical claim matches canonicalKey.
+func claimMatches(claim, canonicalKey string) bool {
+	if strings.HasSuffix(claim, ".") {
+		return strings.HasPrefix(canonicalKey, claim)
+	}
+	return claim == canonicalKey
+}
+
+// claimsOverlap returns whether some key is matched by both canonical claims.
+func claimsOverlap(a, b string) bool {
+	return claimMatches(a, b) || claimMatches(b, a)
+}
+
+// OwnedKargs modifies a Kargs on behalf of an owner, refusing to modify kargs
+// claimed by other owners. Kargs that aren't claimed can be modified by every
+// owner. Methods behave like those of Kargs with the same name, but return an
+// error wrapping ErrOwned if a karg they would add, change, move, or remove is
+// claimed by another owner, in which case the Kargs is left unchanged. Bulk
+// methods report claimed keys like other failed keys, or skip the kargs of other
+// owners where noted.
+//
+// Claims are only enforced by the methods of OwnedKargs. Mutators of Kargs it
+// doesn't offer, like Deduplicate, CanonicalizeInPlace, or MergeDHCP, and
+// modifying the Kargs directly bypass them; they are meant for the party that
+// hands out OwnedKargs to the individual writers.
+type OwnedKargs struct {
+	k     *Kargs
+	owner string
+}
+
+// As returns an OwnedKargs modifying k on behalf of owner.
+func (k *Kargs) As(owner string) *OwnedKargs {
+	return &OwnedKargs{k: k, owner: owner}
+}
+
+// AppendKarg is like Kargs.AppendKarg.
+func (o *OwnedKargs) AppendKarg(key, value string) error {
+	if err := o.k.checkOwner(key, o.owner); err != nil {
+		return err
+	}
+	return o.k.AppendKarg(key, value)
+}
+
+// DeleteKarg is like Kargs.DeleteKarg.
+func (o *OwnedKargs) DeleteKarg(key string) error {
+	if err := o.k.checkOwner(key, o.owner); err != nil {
+		return err
+	}
+	return o.k.DeleteKarg(key)
+}
+
+// DeleteKargByValue is like Kargs.DeleteKargByValue.
+func (o *OwnedKargs) DeleteKargByValue(key, value string) error {
+	if err := o.k.checkOwner(key, o.owner); err != nil {
+		return err
+	}
+	return o.k.DeleteKargByValue(key, value)
+}
+
+// ReplaceAll is like Kargs.ReplaceAll.
+func (o *OwnedKargs) ReplaceAll(key, value string) error {
+	if err := o.k.checkOwner(key, o.owner); err != nil {
+		return err
+	}
+	return o.k.ReplaceAll(key, value)
+}
+
+// SetKarg is like Kargs.SetKarg.
+func (o *OwnedKargs) SetKarg(key, value string) error {
+	if err := o.k.checkOwner(key, o.owner); err != nil {
+		return err
+	}
+	return o.k.SetKarg(key, value)
+}
+
+// check returns an error wrapping ErrOwned if any of keys is claimed by an owner
+// other than that of o.
+func (o *OwnedKargs) check(keys ...string) error {
+	for _, key := range keys {
+		if err := o.k.checkOwner(key, o.owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddKargValue is like Kargs.AddKargValue.
+func (o *OwnedKargs) AddKargValue(key, value string) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.AddKargValue(key, value)
+}
+
+// PrependKarg is like Kargs.PrependKarg.
+func (o *OwnedKargs) PrependKarg(key, value string) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.PrependKarg(key, value)
+}
+
+// InsertKargAfter is like Kargs.InsertKargAfter. Only key must not be claimed
+// by another owner, since anchorKey is left unchanged.
+func (o *OwnedKargs) InsertKargAfter(anchorKey, key, value string) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.InsertKargAfter(anchorKey, key, value)
+}
+
+// InsertKargBefore is like Kargs.InsertKargBefore, with claims checked like
+// InsertKargAfter.
+func (o *OwnedKargs) InsertKargBefore(anchorKey, key, value string) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.InsertKargBefore(anchorKey, key, value)
+}
+
+// SetKargPosition is like Kargs.SetKargPosition.
+func (o *OwnedKargs) SetKargPosition(key, value string, pos SetPosition) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.SetKargPosition(key, value, pos)
+}
+
+// SetKargs is like Kargs.SetKargs, reporting keys claimed by other owners in the
+// KeyErrors.
+func (o *OwnedKargs) SetKargs(kargs map[string]string) error {
+	keys := make([]string, 0, len(kargs))
+	for key := range kargs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs KeyErrors
+	for _, key := range keys {
+		if err := o.SetKarg(key, kargs[key]); err != nil {
+			errs = append(errs, &KeyError{Key: key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SetKargsOrdered is like Kargs.SetKargsOrdered, reporting keys claimed by other
+// owners in the KeyErrors.
+func (o *OwnedKargs) SetKargsOrdered(kargs []Karg) error {
+	var errs KeyErrors
+	for _, karg := range kargs {
+		if err := o.SetKarg(karg.Key, karg.Value); err != nil {
+			errs = append(errs, &KeyError{Key: karg.Key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ReplaceKargValue is like Kargs.ReplaceKargValue.
+func (o *OwnedKargs) ReplaceKargValue(key, oldValue, newValue string) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.ReplaceKargValue(key, oldValue, newValue)
+}
+
+// RenameKarg is like Kargs.RenameKarg. Neither oldKey nor newKey may be claimed
+// by another owner.
+func (o *OwnedKargs) RenameKarg(oldKey, newKey string) error {
+	if err := o.check(oldKey, newKey); err != nil {
+		return err
+	}
+	return o.k.RenameKarg(oldKey, newKey)
+}
+
+// TakeKarg is like Kargs.TakeKarg.
+func (o *OwnedKargs) TakeKarg(key string) ([]string, bool, error) {
+	if err := o.check(key); err != nil {
+		return nil, false, err
+	}
+	return o.k.TakeKarg(key)
+}
+
+// MoveKarg is like Kargs.MoveKarg.
+func (o *OwnedKargs) MoveKarg(key string, position int) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.MoveKarg(key, position)
+}
+
+// MoveKargAfter is like Kargs.MoveKargAfter. Only key must not be claimed by
+// another owner, since anchorKey stays in place.
+func (o *OwnedKargs) MoveKargAfter(key, anchorKey string) error {
+	if err := o.check(key); err != nil {
+		return err
+	}
+	return o.k.MoveKargAfter(key, anchorKey)
+}
+
+// DeleteAt is like Kargs.DeleteAt.
+func (o *OwnedKargs) DeleteAt(i int) error {
+	if item := o.k.itemAt(i); item != nil {
+		if err := o.check(item.karg.Key); err != nil {
+			return err
+		}
+	}
+	return o.k.DeleteAt(i)
+}
+
+// DeleteKargs is like Kargs.DeleteKargs, reporting keys claimed by other owners
+// in the KeyErrors.
+func (o *OwnedKargs) DeleteKargs(keys ...string) error {
+	var errs KeyErrors
+	for _, key := range keys {
+		if err := o.DeleteKarg(key); err != nil {
+			errs = append(errs, &KeyError{Key: key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// DeleteKargsRegexp is like Kargs.DeleteKargsRegexp, but deletes nothing if re
+// matches a key claimed by another owner.
+func (o *OwnedKargs) DeleteKargsRegexp(re *regexp.Regexp) (int, error) {
+	for item := o.k.list; item != nil; item = item.next {
+		if re.MatchString(item.karg.CanonicalKey) {
+			if err := o.check(item.karg.Key); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return o.k.DeleteKargsRegexp(re)
+}
+
+// RewriteValues is like Kargs.RewriteValues, but skips kargs claimed by other
+// owners; rewrite is not called for them.
+func (o *OwnedKargs) RewriteValues(rewrite func(key, value string) (string, bool)) int {
+	return o.k.RewriteValues(func(key, value string) (string, bool) {
+		if o.check(key) != nil {
+			return "", false
+		}
+		return rewrite(key, value)
+	})
+}
+
+// Apply is like Kargs.Apply, reporting changes of keys claimed by other owners
+// in the KeyErrors instead of applying them.
+func (o *OwnedKargs) Apply(diff KargsDiff) error {
+	var errs KeyErrors
+	for _, change := range diff {
+		if err := o.check(change.Key); err != nil {
+			errs = append(errs, &KeyError{Key: change.Key, Err: err})
+			continue
+		}
+		var keyErrs KeyErrors
+		if err := o.k.Apply(KargsDiff{change}); errors.As(err, &keyErrs) {
+			errs = append(errs, keyErrs...)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Sort is like Kargs.Sort, but sorts nothing and returns an error wrapping
+// ErrOwned if the Kargs holds kargs claimed by another owner, since sorting
+// would move them.
+func (o *OwnedKargs) Sort() error {
+	for item := o.k.list; item != nil; item = item.next {
+		if err := o.check(item.karg.Key); err != nil {
+			return err
+		}
+	}
+	o.k.Sort()
+	return nil
+}
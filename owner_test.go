@@ -0,0 +1,133 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_Claim(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 rd.break nvidia-drm.modeset=1"))
+
+	assert.NoError(t, k.Claim("rd.", "dracut"))
+	assert.NoError(t, k.Claim("nvidia_drm.", "gpu-operator"))
+	assert.NoError(t, k.Claim("root", "admin"))
+	assert.NoError(t, k.Claim("rd.luks.uuid", "dracut"))
+
+	assert.ErrorIs(t, k.Claim("rd.break", "admin"), ErrOwned)
+	assert.ErrorIs(t, k.Claim("rd.luks.", "admin"), ErrOwned)
+	assert.ErrorIs(t, k.Claim("nvidia-drm.modeset", "admin"), ErrOwned)
+	assert.ErrorIs(t, k.Claim("", "admin"), ErrInvalidKey)
+	assert.ErrorIs(t, k.Claim("bad key", "admin"), ErrInvalidKey)
+
+	owner, ok := k.Owner("rd.shell")
+	assert.True(t, ok)
+	assert.Equal(t, "dracut", owner)
+	owner, ok = k.Owner("nvidia-drm.modeset")
+	assert.True(t, ok)
+	assert.Equal(t, "gpu-operator", owner)
+	_, ok = k.Owner("quiet")
+	assert.False(t, ok)
+	_, ok = k.Owner("rd")
+	assert.False(t, ok)
+}
+
+func TestOwnedKargs(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 rd.break nvidia-drm.modeset=1"))
+	assert.NoError(t, k.Claim("rd.", "dracut"))
+	assert.NoError(t, k.Claim("nvidia_drm.", "gpu-operator"))
+
+	admin := k.As("admin")
+	assert.ErrorIs(t, admin.SetKarg("rd.shell", "1"), ErrOwned)
+	assert.ErrorIs(t, admin.AppendKarg("rd.shell", "1"), ErrOwned)
+	assert.ErrorIs(t, admin.DeleteKarg("rd.break"), ErrOwned)
+	assert.ErrorIs(t, admin.DeleteKargByValue("nvidia_drm.modeset", "1"), ErrOwned)
+	assert.ErrorIs(t, admin.ReplaceAll("nvidia-drm.modeset", "0"), ErrOwned)
+	assert.NoError(t, admin.SetKarg("root", "/dev/sda2"))
+	assert.NoError(t, admin.AppendKarg("console", "ttyS0"))
+
+	dracut := k.As("dracut")
+	assert.NoError(t, dracut.DeleteKarg("rd.break"))
+	assert.NoError(t, dracut.SetKarg("rd.shell", "0"))
+
+	gpu := k.As("gpu-operator")
+	assert.NoError(t, gpu.ReplaceAll("nvidia-drm.modeset", "0"))
+	assert.ErrorIs(t, gpu.DeleteKarg("rd.shell"), ErrOwned)
+
+	assert.Equal(t, "root=/dev/sda2 nvidia-drm.modeset=0 console=ttyS0 rd.shell=0", k.String())
+
+	// Claims are copied by Clone.
+	clone := k.Clone()
+	assert.ErrorIs(t, clone.As("admin").DeleteKarg("rd.shell"), ErrOwned)
+}
+
+func TestOwnedKargs_allMutators(t *testing.T) {
+	const line = "root=/dev/sda1 rd.break rd.shell=1 quiet"
+	k := NewKargs([]byte(line))
+	assert.NoError(t, k.Claim("rd.", "dracut"))
+	admin := k.As("admin")
+
+	assert.ErrorIs(t, admin.AddKargValue("rd.shell", "0"), ErrOwned)
+	assert.ErrorIs(t, admin.PrependKarg("rd.debug", ""), ErrOwned)
+	assert.ErrorIs(t, admin.InsertKargAfter("root", "rd.debug", ""), ErrOwned)
+	assert.ErrorIs(t, admin.InsertKargBefore("root", "rd.debug", ""), ErrOwned)
+	assert.ErrorIs(t, admin.SetKargPosition("rd.shell", "0", SetMoveToEnd), ErrOwned)
+	assert.ErrorIs(t, admin.ReplaceKargValue("rd.shell", "1", "0"), ErrOwned)
+	assert.ErrorIs(t, admin.RenameKarg("rd.break", "nobreak"), ErrOwned)
+	assert.ErrorIs(t, admin.RenameKarg("quiet", "rd.quiet"), ErrOwned)
+	_, _, err := admin.TakeKarg("rd.break")
+	assert.ErrorIs(t, err, ErrOwned)
+	assert.ErrorIs(t, admin.MoveKarg("rd.break", 0), ErrOwned)
+	assert.ErrorIs(t, admin.MoveKargAfter("rd.break", "quiet"), ErrOwned)
+	assert.ErrorIs(t, admin.DeleteAt(1), ErrOwned)
+	_, err = admin.DeleteKargsRegexp(regexp.MustCompile(`^(quiet|rd\.break)$`))
+	assert.ErrorIs(t, err, ErrOwned)
+	assert.ErrorIs(t, admin.Sort(), ErrOwned)
+	assert.Equal(t, line, k.String())
+
+	// Bulk methods report claimed keys and carry on with the others.
+	err = admin.SetKargs(map[string]string{"rd.shell": "0", "loglevel": "3"})
+	assert.ErrorIs(t, err, ErrOwned)
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"rd.shell"}, keyErrs.Keys())
+	err = admin.SetKargsOrdered([]Karg{{Key: "rd.shell", Value: "0"}, {Key: "nomodeset"}})
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"rd.shell"}, keyErrs.Keys())
+	err = admin.DeleteKargs("rd.break", "nomodeset")
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"rd.break"}, keyErrs.Keys())
+	err = admin.Apply(KargsDiff{
+		{Op: ChangeDelete, Key: "rd.shell"},
+		{Op: ChangeReplace, Key: "root", Values: []string{"/dev/sda2"}},
+	})
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"rd.shell"}, keyErrs.Keys())
+	rewritten := admin.RewriteValues(func(key, value string) (string, bool) {
+		return strings.ToUpper(value), value != ""
+	})
+	assert.Equal(t, 2, rewritten)
+	assert.Equal(t, "root=/DEV/SDA2 rd.break rd.shell=1 quiet loglevel=3", k.String())
+
+	// The claiming owner may do all of it.
+	dracut := k.As("dracut")
+	assert.NoError(t, dracut.AddKargValue("rd.shell", "0"))
+	assert.NoError(t, dracut.RenameKarg("rd.break", "rd.debug"))
+	assert.NoError(t, dracut.MoveKarg("rd.debug", 0))
+	vals, ok, err := dracut.TakeKarg("rd.shell")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"1", "0"}, vals)
+	assert.NoError(t, dracut.Sort())
+	assert.Equal(t, "loglevel=3 quiet rd.debug root=/DEV/SDA2", k.String())
+	assert.NoError(t, dracut.DeleteAt(2))
+	n, err := admin.DeleteKargsRegexp(regexp.MustCompile(`^(quiet|loglevel)$`))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "root=/DEV/SDA2", k.String())
+}
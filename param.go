@@ -0,0 +1,131 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Param describes a well-known parameter for tools that help writing command
+// lines, like completion with CompleteKey and CompleteValue.
+type Param struct {
+	Name        string   // Key as conventionally written
+	Description string   // Short, human-readable description
+	Flag        bool     // Whether the parameter takes no value
+	Values      []string // Valid values, if they are a fixed set
+	Unit        string   // Unit of numeric values, e.g. "seconds" or "bytes"
+}
+
+// params is the registry of well-known parameters, keyed by canonical key.
+var params = struct {
+	sync.RWMutex
+	params map[string]Param
+}{
+	params: make(map[string]Param),
+}
+
+func init() {
+	for _, p := range []Param{
+		{Name: "console", Description: "Output console device and options"},
+		{Name: "crashkernel", Description: "Memory reserved for the crash kernel", Unit: "bytes"},
+		{Name: "debug", Description: "Enable kernel debugging messages", Flag: true},
+		{Name: "init", Description: "Program run as init"},
+		{Name: "intel_iommu", Description: "Intel IOMMU driver options", Values: []string{"on", "off", "igfx_off", "sm_on", "sm_off"}},
+		{Name: "iommu", Description: "IOMMU options", Values: []string{"off", "force", "noforce", "pt", "nopt"}},
+		{Name: "ipv6.disable", Description: "Disable IPv6", Values: []string{"0", "1"}},
+		{Name: "loglevel", Description: "Console log level", Values: []string{"0", "1", "2", "3", "4", "5", "6", "7"}},
+		{Name: "mem", Description: "Limit the amount of memory used", Unit: "bytes"},
+		{Name: "mitigations", Description: "CPU vulnerability mitigations", Values: []string{"off", "auto", "auto,nosmt"}},
+		{Name: "net.ifnames", Description: "Predictable network interface names", Values: []string{"0", "1"}},
+		{Name: "nomodeset", Description: "Disable kernel mode setting", Flag: true},
+		{Name: "panic", Description: "Reboot delay after a panic", Unit: "seconds"},
+		{Name: "quiet", Description: "Disable most log messages", Flag: true},
+		{Name: "rd.break", Description: "Drop to a shell in the initramfs", Values: []string{"cmdline", "pre-udev", "pre-trigger", "initqueue", "pre-mount", "mount", "pre-pivot", "cleanup"}},
+		{Name: "rdinit", Description: "Program run as init from the initramfs"},
+		{Name: "ro", Description: "Mount the root file system read-only", Flag: true},
+		{Name: "root", Description: "Root file system"},
+		{Name: "rootdelay", Description: "Delay before mounting the root file system", Unit: "seconds"},
+		{Name: "rootfstype", Description: "Root file system type"},
+		{Name: "rootwait", Description: "Wait for the root device to appear", Flag: true},
+		{Name: "rw", Description: "Mount the root file system read-write", Flag: true},
+		{Name: "selinux", Description: "Enable or disable SELinux", Values: []string{"0", "1"}},
+		{Name: "systemd.log_level", Description: "systemd log level", Values: []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}},
+		{Name: "systemd.unit", Description: "Unit to boot into"},
+		{Name: "transparent_hugepage", Description: "Transparent huge page mode", Values: []string{"always", "madvise", "never"}},
+	} {
+		params.params[canonicalizeKey(p.Name)] = p
+	}
+}
+
+// LookupParam returns the registered description of the parameter identified
+// by key.
+func LookupParam(key string) (Param, bool) {
+	params.RLock()
+	defer params.RUnlock()
+	p, ok := params.params[canonicalizeKey(key)]
+	return p, ok
+}
+
+// RegisterParam registers p as a well-known parameter, replacing any parameter
+// registered under the same canonical key before. The registry is shared by the
+// whole program and ships with a selection of common kernel, dracut, and
+// systemd parameters.
+func RegisterParam(p Param) {
+	params.Lock()
+	defer params.Unlock()
+	params.params[canonicalizeKey(p.Name)] = p
+}
+
+// UnregisterParam removes the parameter identified by key from the registry.
+func UnregisterParam(key string) {
+	params.Lock()
+	defer params.Unlock()
+	delete(params.params, canonicalizeKey(key))
+}
+
+// Candidate is a completion offered by CompleteKey or CompleteValue.
+type Candidate struct {
+	Text        string // Completed key or value
+	Description string // Description of the parameter, if known
+	Unit        string // Unit of the value, if known
+	Flag        bool   // For keys, whether the parameter takes no value
+}
+
+// CompleteKey returns the registered parameters whose keys begin with prefix,
+// sorted by key. Like elsewhere, - and _ are treated the same.
+func CompleteKey(prefix string) []Candidate {
+	canonicalPrefix := canonicalizeKey(prefix)
+	params.RLock()
+	var candidates []Candidate
+	for key, p := range params.params {
+		if strings.HasPrefix(key, canonicalPrefix) {
+			candidates = append(candidates, Candidate{Text: p.Name, Description: p.Description, Unit: p.Unit, Flag: p.Flag})
+		}
+	}
+	params.RUnlock()
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Text < candidates[j].Text
+	})
+	return candidates
+}
+
+// CompleteValue returns the valid values beginning with prefix of the
+// registered parameter identified by key, in the order they were registered.
+// Nothing is returned for unknown parameters and those without a fixed set of
+// values.
+func CompleteValue(key, prefix string) []Candidate {
+	p, ok := LookupParam(key)
+	if !ok {
+		return nil
+	}
+	var candidates []Candidate
+	for _, value := range p.Values {
+		if strings.HasPrefix(value, prefix) {
+			candidates = append(candidates, Candidate{Text: value, Description: p.Description, Unit: p.Unit})
+		}
+	}
+	return candidates
+}
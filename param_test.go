@@ -0,0 +1,56 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteKey(t *testing.T) {
+	var names []string
+	for _, c := range CompleteKey("root") {
+		names = append(names, c.Text)
+	}
+	assert.Equal(t, []string{"root", "rootdelay", "rootfstype", "rootwait"}, names)
+
+	candidates := CompleteKey("transparent-huge")
+	if assert.Len(t, candidates, 1) {
+		assert.Equal(t, "transparent_hugepage", candidates[0].Text)
+		assert.NotEmpty(t, candidates[0].Description)
+	}
+	candidates = CompleteKey("quie")
+	if assert.Len(t, candidates, 1) {
+		assert.True(t, candidates[0].Flag)
+	}
+	assert.Empty(t, CompleteKey("no-such-param"))
+	assert.NotEmpty(t, CompleteKey(""))
+}
+
+func TestCompleteValue(t *testing.T) {
+	var values []string
+	for _, c := range CompleteValue("rd.break", "pre-") {
+		values = append(values, c.Text)
+	}
+	assert.Equal(t, []string{"pre-udev", "pre-trigger", "pre-mount", "pre-pivot"}, values)
+
+	assert.Len(t, CompleteValue("loglevel", ""), 8)
+	assert.Empty(t, CompleteValue("root", "/dev"))
+	assert.Empty(t, CompleteValue("missing", ""))
+}
+
+func TestRegisterParam(t *testing.T) {
+	RegisterParam(Param{Name: "nvidia-drm.modeset", Description: "NVIDIA kernel mode setting", Values: []string{"0", "1"}})
+	defer UnregisterParam("nvidia_drm.modeset")
+
+	p, ok := LookupParam("nvidia_drm.modeset")
+	assert.True(t, ok)
+	assert.Equal(t, "nvidia-drm.modeset", p.Name)
+	assert.Equal(t, []Candidate{{Text: "1", Description: "NVIDIA kernel mode setting"}}, CompleteValue("nvidia-drm.modeset", "1"))
+
+	UnregisterParam("nvidia-drm.modeset")
+	_, ok = LookupParam("nvidia_drm.modeset")
+	assert.False(t, ok)
+}
@@ -6,7 +6,6 @@ package kargs
 import (
 	"fmt"
 	"strings"
-	"unicode"
 )
 
 // Kernel variables must allow '-' and '_' to be equivalent in variable names.
@@ -26,10 +25,62 @@ func checkKey(key string) error {
 	return nil
 }
 
-// dequote removes single and double quotes that aren't escaped with a
-// backslash.
+// dequote removes the quotes surrounding a value. It works like the kernel, but
+// accepts single quotes as well as double quotes and understands escapes:
+//
+//   - A value that doesn't begin with a quote is returned unchanged.
+//   - Otherwise, the opening quote is removed, as is a closing quote of the same
+//     kind at the end of the value. A value without a closing quote is
+//     unterminated and keeps the rest of its characters.
+//   - Between the quotes, a backslash followed by the opening quote character or
+//     by another backslash stands for that character alone. Any other
+//     character, including a lone backslash or an unescaped quote that doesn't
+//     end the value, is kept as is.
 func dequote(line string) string {
-	if len(line) == 0 {
+	if len(line) == 0 || (line[0] != '"' && line[0] != '\'') {
+		return line
+	}
+
+	const (
+		stateQuoted = iota // Inside the quotes
+		stateEscape        // After a backslash inside the quotes
+	)
+	quote := line[0]
+	state := stateQuoted
+	newLine := make([]byte, 0, len(line))
+	for i := 1; i < len(line); i++ {
+		c := line[i]
+		switch state {
+		case stateQuoted:
+			switch {
+			case c == '\\':
+				state = stateEscape
+			case c == quote && i == len(line)-1:
+				// Closing quote
+			default:
+				newLine = append(newLine, c)
+			}
+		case stateEscape:
+			if c != quote && c != '\\' {
+				// Not an escape sequence, keep the backslash
+				newLine = append(newLine, '\\')
+			}
+			newLine = append(newLine, c)
+			state = stateQuoted
+		}
+	}
+	if state == stateEscape {
+		// Trailing lone backslash
+		newLine = append(newLine, '\\')
+	}
+	return string(newLine)
+}
+
+// dequoteCompat removes single and double quotes that aren't escaped with a
+// backslash. It is the original, ad hoc implementation of dequote, kept for
+// callers relying on its exact behavior (see WithCompatDequote).
+func dequoteCompat(line string) string {
+	if len(line) < 2 {
 		return line
 	}
 
@@ -77,44 +128,10 @@ func dequote(line string) string {
 // of =), and the trimmedValue (dequoted value). These values are passed to the
 // handler function, which is executed for each token.
 func doParse(input string, handler func(flag, key, canonicalKey, value, trimmedValue string)) {
-	lastQuote := rune(0)
-	quotedFieldsCheck := func(c rune) bool {
-		switch {
-		case c == lastQuote:
-			lastQuote = rune(0)
-			return false
-		case lastQuote != rune(0):
-			return false
-		case unicode.In(c, unicode.Quotation_Mark):
-			lastQuote = c
-			return false
-		default:
-			return unicode.IsSpace(c)
-		}
-	}
-
-	for _, flag := range strings.FieldsFunc(string(input), quotedFieldsCheck) {
-		// Split the flag into a key and value
-		split := strings.Index(flag, "=")
-
-		if len(flag) == 0 {
-			continue
-		}
-		var key, value string
-		if split == -1 {
-			// If no value, leave the flag without =
-			key = flag
-		} else {
-			// If value, flag is key=value
-			key = flag[:split]
-			value = flag[split+1:]
-		}
-		canonicalKey := canonicalizeKey(key)
-		trimmedValue := dequote(value)
-
-		// Call the passed handler for each token
-		handler(flag, key, canonicalKey, value, trimmedValue)
-	}
+	Tokenize(input, func(t Token) error {
+		handler(t.Raw, t.Key, t.CanonicalKey, t.RawValue, t.Value)
+		return nil
+	})
 }
 
 // enquote surrounds a string in double quotes if it contains spaces and isn't
@@ -133,50 +150,79 @@ func enquote(line string) string {
 }
 
 // parse parses the raw byte slice into a Kargs struct and returns a pointer
-// to it.
-func parse(raw []byte) *Kargs {
-	return parseToStruct(string(raw))
+// to it, along with an error if parsing stopped at a limit.
+//
+// With WithASCII, raw is first scanned as is to count its kargs, which is cheap
+// without UTF-8 decoding, so that the list items and the key map can be
+// allocated at once instead of one by one. raw is converted to a string only
+// once, to hold the kargs, since they must not change with raw.
+func parse(raw []byte, opts ...ParseOption) (*Kargs, error) {
+	cfg := newParseConfig(opts...)
+	if !cfg.ascii {
+		return cfg.parseString(string(raw))
+	}
+	n := 0
+	scanASCII(raw, func(int, int) error {
+		n++
+		return nil
+	})
+	if cfg.maxParams > 0 {
+		n = min(n, cfg.maxParams)
+	}
+	k := &Kargs{
+		keyMap:    make(map[string][]*kargItem, n),
+		moduleMap: make(map[string][]*kargItem),
+		cfg:       cfg,
+	}
+	if cfg.arena == nil {
+		k.slab = make([]kargItem, n)
+	}
+	err := k.appendParsed(string(raw), nil)
+	k.slab = nil
+	return k, err
 }
 
 // parseToStruct takes a kernel command line string and parses it into a Kargs
-// struct, whose pointer is returned.
-func parseToStruct(input string) *Kargs {
-	var (
-		last      *kargItem
-		ll        *kargItem
-		llTracker     = ll
-		numParams int = 0
-	)
-	keyMap := make(map[string][]*kargItem)
-	doParse(input, func(flag, key, canonicalKey, value, trimmedValue string) {
-		newKarg := Karg{
-			CanonicalKey: canonicalKey,
-			Key:          key,
-			Raw:          flag,
-			Value:        trimmedValue,
+// struct, whose pointer is returned. If a limit set by a ParseOption is
+// exceeded, parsing stops and the kargs parsed before are returned along with a
+// *LimitError.
+func parseToStruct(input string, opts ...ParseOption) (*Kargs, error) {
+	return newParseConfig(opts...).parseString(input)
+}
+
+// parseString is like parseToStruct with the settings of cfg.
+func (cfg parseConfig) parseString(input string) (*Kargs, error) {
+	k := &Kargs{
+		keyMap:    make(map[string][]*kargItem),
+		moduleMap: make(map[string][]*kargItem),
+		cfg:       cfg,
+	}
+	err := k.appendParsed(input, nil)
+	return k, err
+}
+
+// appendParsed parses input with the settings of k and appends its kargs to k,
+// calling added, if not nil, with each token and the list item made from it.
+// If a limit is exceeded, parsing stops and a *LimitError is returned.
+func (k *Kargs) appendParsed(input string, added func(Token, *kargItem)) error {
+	cfg := k.cfg
+	return cfg.tokenize(input, func(t Token) error {
+		if err := cfg.checkLimits(t, k.numParams); err != nil {
+			return err
 		}
-		newKargItem := &kargItem{
-			karg: newKarg,
+		value := cfg.dequote(t.RawValue)
+		if cfg.traceFn != nil {
+			cfg.trace(t, value)
 		}
-		if llTracker == nil {
-			// Linked list is empty, create first item
-			ll = newKargItem
-			llTracker = ll
-		} else {
-			// Linked list is nonempty, append item and set
-			// prev/next pointers
-			newKargItem.prev = llTracker
-			llTracker.next = newKargItem
-			llTracker = llTracker.next
+		item := k.appendItem(Karg{
+			CanonicalKey: t.CanonicalKey,
+			Key:          t.Key,
+			Raw:          t.Raw,
+			Value:        value,
+		})
+		if added != nil {
+			added(t, item)
 		}
-		numParams++
-		keyMap[canonicalKey] = append(keyMap[canonicalKey], newKargItem)
-		last = newKargItem
+		return nil
 	})
-	return &Kargs{
-		last:      last,
-		list:      ll,
-		keyMap:    keyMap,
-		numParams: numParams,
-	}
 }
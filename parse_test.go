@@ -33,6 +33,17 @@ func TestDequote(t *testing.T) {
 		[]string{`\'escaped ended single quotes\'`, `\'escaped ended single quotes\'`},
 		[]string{`o"bscure double quotes"`, `o"bscure double quotes"`},
 		[]string{`o'bscure single quotes'`, `o'bscure single quotes'`},
+		[]string{`a\"b"c"`, `a\"b"c"`},
+		[]string{`"inner \"escaped\" quotes"`, `inner "escaped" quotes`},
+		[]string{`'inner \'escaped\' quotes'`, `inner 'escaped' quotes`},
+		[]string{`"mixed 'quotes'"`, `mixed 'quotes'`},
+		[]string{`"inner "unescaped" quotes"`, `inner "unescaped" quotes`},
+		[]string{`"escaped \\ backslash"`, `escaped \ backslash`},
+		[]string{`"lone \ backslash\"`, `lone \ backslash"`},
+		[]string{`"unterminated`, `unterminated`},
+		[]string{`"mismatched'`, `mismatched'`},
+		[]string{`"`, ``},
+		[]string{`""`, ``},
 	}
 	for _, check := range checks {
 		in := check[0]
@@ -42,6 +53,39 @@ func TestDequote(t *testing.T) {
 	}
 }
 
+func TestDequoteCompat(t *testing.T) {
+	checks := [][]string{
+		// Input, expected output
+		[]string{`no quotes`, `no quotes`},
+		[]string{`"ended double quotes"`, `ended double quotes`},
+		[]string{`'ended single quotes'`, `ended single quotes`},
+		[]string{`\"escaped ended double quotes\"`, `\"escaped ended double quotes\"`},
+		[]string{`"inner \"escaped\" quotes"`, `inner "escaped" quotes`},
+		[]string{`"unterminated`, `unterminate`},
+		[]string{`"`, `"`},
+	}
+	for _, check := range checks {
+		in := check[0]
+		want := check[1]
+		have := dequoteCompat(in)
+		assert.Equal(t, have, want)
+	}
+}
+
+func FuzzDequote(f *testing.F) {
+	for _, seed := range []string{``, `plain`, `"quoted"`, `'quoted'`, `"a\"b"`, `a\"b"c"`, `"\\"`, `"`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		out := dequote(in)
+		assert.LessOrEqual(t, len(out), len(in))
+		if len(in) == 0 || (in[0] != '"' && in[0] != '\'') {
+			assert.Equal(t, in, out)
+		}
+		dequoteCompat(in)
+	})
+}
+
 func TestDoParse(t *testing.T) {
 	in := `noval dup=val1 dup=val2 nondup=val with-dashes with-dashes-val=val "key quotes" \"key escaped quotes\" vq="value quotes" veq=\"value escaped quotes\"`
 	expKargs := []Karg{
@@ -109,7 +153,8 @@ func TestParseToStruct(t *testing.T) {
 		"with_dashes_val": 1,
 	}
 
-	k := parseToStruct(in)
+	k, err := parseToStruct(in)
+	assert.NoError(t, err)
 
 	// Make sure struct is not nil
 	assert.NotNil(t, k)
@@ -0,0 +1,324 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Policy is a named rule that a command line must satisfy, written as an
+// expression so that it can be kept in a file and maintained without writing
+// Go, e.g.
+//
+//	has('init') implies value('init') == '/sbin/init'
+//
+// An expression is built from these terms:
+//
+//	has(key)          true if key is set
+//	has(key, v)       true if key occurs with value v, see HasKargValue
+//	value(key) == v   true if the last value of key, which is the one the kernel
+//	                  uses, is v; value of a missing key is the empty string
+//	value(key) != v   the opposite
+//	count(key) OP n   compares the number of occurrences of key with the
+//	                  integer n, where OP is one of == != < <= > >=
+//	true, false       constants
+//
+// Keys and values are strings in single or double quotes, with a backslash
+// escaping the quote and the backslash. Terms can be combined with !, &&, ||,
+// and implies (or =>), listed from tightest to loosest binding, and grouped
+// with parentheses. implies is right-associative. Create a Policy with
+// ParsePolicy or ParsePolicies.
+type Policy struct {
+	name string
+	expr string
+	root policyNode
+}
+
+// policyNode is a node of the syntax tree of a Policy.
+type policyNode interface {
+	eval(k *Kargs) bool
+}
+
+type (
+	policyNot      struct{ x policyNode }
+	policyAnd      struct{ x, y policyNode }
+	policyOr       struct{ x, y policyNode }
+	policyImplies  struct{ x, y policyNode }
+	policyConst    bool
+	policyHas      struct{ key string }
+	policyHasValue struct{ key, value string }
+	policyValue    struct {
+		key, value string
+		negate     bool
+	}
+	policyCount struct {
+		key string
+		op  string
+		n   int
+	}
+)
+
+func (n policyNot) eval(k *Kargs) bool      { return !n.x.eval(k) }
+func (n policyAnd) eval(k *Kargs) bool      { return n.x.eval(k) && n.y.eval(k) }
+func (n policyOr) eval(k *Kargs) bool       { return n.x.eval(k) || n.y.eval(k) }
+func (n policyImplies) eval(k *Kargs) bool  { return !n.x.eval(k) || n.y.eval(k) }
+func (n policyConst) eval(*Kargs) bool      { return bool(n) }
+func (n policyHas) eval(k *Kargs) bool      { return k.ContainsKarg(n.key) }
+func (n policyHasValue) eval(k *Kargs) bool { return k.HasKargValue(n.key, n.value) }
+
+func (n policyValue) eval(k *Kargs) bool {
+	value := ""
+	if vals, _ := k.GetKarg(n.key); len(vals) > 0 {
+		value = vals[len(vals)-1]
+	}
+	return (value == n.value) != n.negate
+}
+
+func (n policyCount) eval(k *Kargs) bool {
+	count := k.Count(n.key)
+	switch n.op {
+	case "==":
+		return count == n.n
+	case "!=":
+		return count != n.n
+	case "<":
+		return count < n.n
+	case "<=":
+		return count <= n.n
+	case ">":
+		return count > n.n
+	default:
+		return count >= n.n
+	}
+}
+
+// ParsePolicy parses expr as described in Policy into a Policy called name. An
+// error wrapping ErrInvalidPolicy is returned if expr is malformed.
+func ParsePolicy(name, expr string) (*Policy, error) {
+	p := policyParser{exprLexer{input: expr, what: "policy", err: ErrInvalidPolicy}}
+	root, err := p.parseImplies()
+	if err == nil && p.skipSpace() < len(p.input) {
+		err = p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{name: name, expr: expr, root: root}, nil
+}
+
+// ParsePolicies reads policies from r, one per line, written as a name followed
+// by a colon and the expression, e.g.
+//
+//	init-path: has('init') implies value('init') == '/sbin/init'
+//
+// Empty lines and lines starting with # are skipped. An error wrapping
+// ErrInvalidPolicy is returned for the first malformed line.
+func ParsePolicies(r io.Reader) ([]*Policy, error) {
+	var policies []*Policy
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, expr, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return policies, fmt.Errorf("line %d: missing policy name: %w", lineNo, ErrInvalidPolicy)
+		}
+		policy, err := ParsePolicy(strings.TrimSpace(name), strings.TrimSpace(expr))
+		if err != nil {
+			return policies, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		policies = append(policies, policy)
+	}
+	if err := scanner.Err(); err != nil {
+		return policies, fmt.Errorf("reading policies: %w", err)
+	}
+	return policies, nil
+}
+
+// Eval returns whether k satisfies p.
+func (p *Policy) Eval(k *Kargs) bool {
+	return p.root.eval(k)
+}
+
+// Name returns the name of p.
+func (p *Policy) Name() string {
+	return p.name
+}
+
+// String returns the expression p was parsed from.
+func (p *Policy) String() string {
+	return p.expr
+}
+
+// CheckPolicies evaluates policies against k and returns those k violates, in
+// the order given, or nil if it satisfies all of them.
+func (k *Kargs) CheckPolicies(policies ...*Policy) []*Policy {
+	var violated []*Policy
+	for _, policy := range policies {
+		if !policy.Eval(k) {
+			violated = append(violated, policy)
+		}
+	}
+	return violated
+}
+
+// policyParser is a recursive descent parser for policies.
+type policyParser struct {
+	exprLexer
+}
+
+func (p *policyParser) parseImplies() (policyNode, error) {
+	x, err := p.parseOr()
+	if err == nil && (p.acceptWord("implies") || p.accept("=>")) {
+		var y policyNode
+		if y, err = p.parseImplies(); err == nil {
+			x = policyImplies{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *policyParser) parseOr() (policyNode, error) {
+	x, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var y policyNode
+		if y, err = p.parseAnd(); err == nil {
+			x = policyOr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *policyParser) parseAnd() (policyNode, error) {
+	x, err := p.parseUnary()
+	for err == nil && p.accept("&&") {
+		var y policyNode
+		if y, err = p.parseUnary(); err == nil {
+			x = policyAnd{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *policyParser) parseUnary() (policyNode, error) {
+	switch {
+	case p.accept("!"):
+		x, err := p.parseUnary()
+		return policyNot{x}, err
+	case p.accept("("):
+		x, err := p.parseImplies()
+		if err == nil && !p.accept(")") {
+			err = p.errorf("missing )")
+		}
+		return x, err
+	case p.acceptWord("true"):
+		return policyConst(true), nil
+	case p.acceptWord("false"):
+		return policyConst(false), nil
+	case p.acceptWord("has"):
+		args, err := p.parseArgs(1, 2)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 2 {
+			return policyHasValue{key: args[0], value: args[1]}, nil
+		}
+		return policyHas{key: args[0]}, nil
+	case p.acceptWord("value"):
+		args, err := p.parseArgs(1, 1)
+		if err != nil {
+			return nil, err
+		}
+		negate := false
+		switch {
+		case p.accept("=="):
+		case p.accept("!="):
+			negate = true
+		default:
+			return nil, p.errorf("expected == or != after value()")
+		}
+		value, err := p.parseString()
+		return policyValue{key: args[0], value: value, negate: negate}, err
+	case p.acceptWord("count"):
+		args, err := p.parseArgs(1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseCount(args[0])
+	}
+	return nil, p.errorf("expected has(), value(), count(), true, or false")
+}
+
+// parseCount parses the comparison following count(key).
+func (p *policyParser) parseCount(key string) (policyNode, error) {
+	var op string
+	for _, candidate := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, p.errorf("expected comparison after count()")
+	}
+	n, err := strconv.Atoi(p.scanWhile(func(c byte) bool { return c >= '0' && c <= '9' }))
+	if err != nil {
+		return nil, p.errorf("expected integer")
+	}
+	return policyCount{key: key, op: op, n: n}, nil
+}
+
+// parseArgs parses a parenthesized list of between minArgs and maxArgs strings.
+func (p *policyParser) parseArgs(minArgs, maxArgs int) ([]string, error) {
+	if !p.accept("(") {
+		return nil, p.errorf("missing (")
+	}
+	var args []string
+	for {
+		arg, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.accept(")") {
+		return nil, p.errorf("missing )")
+	}
+	if len(args) < minArgs || len(args) > maxArgs {
+		return nil, p.errorf("wrong number of arguments")
+	}
+	return args, nil
+}
+
+// parseString parses a string in single or double quotes.
+func (p *policyParser) parseString() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) || (p.input[p.pos] != '\'' && p.input[p.pos] != '"') {
+		return "", p.errorf("expected string")
+	}
+	quote := p.input[p.pos]
+	var sb strings.Builder
+	for end := p.pos + 1; end < len(p.input); end++ {
+		switch c := p.input[end]; {
+		case c == quote:
+			p.pos = end + 1
+			return sb.String(), nil
+		case c == '\\' && end+1 < len(p.input):
+			end++
+			sb.WriteByte(p.input[end])
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
@@ -0,0 +1,93 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	k := NewKargs([]byte(`init=/sbin/init console=tty0 console=ttyS0 selinux=0 selinux=1 msg="it's"`))
+
+	checks := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "has('init') implies value('init') == '/sbin/init'", want: true},
+		{expr: "has('rdinit') implies value('rdinit') == '/init'", want: true},
+		{expr: `has("init") => value("init") != "/sbin/init"`, want: false},
+		{expr: "value('selinux') == '1'", want: true},
+		{expr: "value('missing') == ''", want: true},
+		{expr: "has('console', 'ttyS0') && count('console') == 2", want: true},
+		{expr: "count('console') > 2 || count('console') <= 1", want: false},
+		{expr: "count('console') >= 2 && count('root') < 1 && count('init') != 0", want: true},
+		{expr: `value('msg') == 'it\'s'`, want: true},
+		{expr: "!has('quiet') && !(has('debug') || false)", want: true},
+		{expr: "true implies false implies true", want: true},
+		{expr: "(true implies false) implies false", want: true},
+	}
+	for _, check := range checks {
+		policy, err := ParsePolicy("test", check.expr)
+		if !assert.NoError(t, err, check.expr) {
+			continue
+		}
+		assert.Equal(t, check.want, policy.Eval(k), check.expr)
+		assert.Equal(t, check.expr, policy.String())
+		assert.Equal(t, "test", policy.Name())
+	}
+}
+
+func TestParsePolicy_errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"has()",
+		"has('a', 'b', 'c')",
+		"has('a'",
+		"value('a')",
+		"value('a') == 1",
+		"count('a') == 'b'",
+		"count('a')",
+		"has('a) ",
+		"(has('a')",
+		"has('a') implies",
+		"has('a') has('b')",
+		"hasty('a') || true",
+	} {
+		_, err := ParsePolicy("test", expr)
+		assert.ErrorIs(t, err, ErrInvalidPolicy, expr)
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(strings.NewReader(`
+# Boot parameter policy
+init-path: has('init') implies value('init') == '/sbin/init'
+selinux-enforced: value('selinux') != '0'
+
+single-console: count('console') <= 1
+`))
+	assert.NoError(t, err)
+	if !assert.Len(t, policies, 3) {
+		return
+	}
+	assert.Equal(t, "init-path", policies[0].Name())
+
+	k := NewKargs([]byte("init=/sbin/init selinux=0 console=tty0 console=ttyS0"))
+	violated := k.CheckPolicies(policies...)
+	if assert.Len(t, violated, 2) {
+		assert.Equal(t, "selinux-enforced", violated[0].Name())
+		assert.Equal(t, "single-console", violated[1].Name())
+	}
+
+	assert.Nil(t, NewKargs([]byte("init=/sbin/init console=tty0")).CheckPolicies(policies...))
+
+	_, err = ParsePolicies(strings.NewReader("ok: true\nno name here\n"))
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+	assert.ErrorContains(t, err, "line 2")
+	_, err = ParsePolicies(strings.NewReader("ok: true\nbad: has(\n"))
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+}
@@ -0,0 +1,117 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PromExporter renders selected kargs as a Prometheus info-style metric in the
+// text exposition format, e.g.
+//
+//	kernel_cmdline_info{console="tty0,ttyS0",root="/dev/sda1"} 1
+//
+// so that monitoring systems can alert on unexpected boot parameters. The zero
+// value exports no labels under the default metric name.
+type PromExporter struct {
+	// Name is the name of the metric. kernel_cmdline_info is used if it is
+	// empty.
+	Name string
+
+	// Keys lists the keys to export as labels, in the order the labels are
+	// written. Keys that aren't present are left out.
+	Keys []string
+
+	// Redact lists keys whose values must not be exposed. Their labels have
+	// the value "redacted" instead.
+	Redact []string
+}
+
+// defaultPromName is the metric name used if PromExporter.Name is empty.
+const defaultPromName = "kernel_cmdline_info"
+
+// WriteMetric writes the metric for k to w, preceded by HELP and TYPE comments.
+// Each label is named after the canonical key, with characters not allowed in
+// label names replaced by underscores, and holds the values of the key joined
+// by commas; occurrences without a value count as "true". An error wrapping
+// ErrUnsupported is returned if the metric name is invalid or two keys map to
+// the same label name.
+func (e PromExporter) WriteMetric(w io.Writer, k *Kargs) error {
+	name := e.Name
+	if name == "" {
+		name = defaultPromName
+	}
+	if promName(name) != name || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("exporting metric %q: invalid name: %w", name, ErrUnsupported)
+	}
+	redact := make(map[string]bool, len(e.Redact))
+	for _, key := range e.Redact {
+		redact[canonicalizeKey(key)] = true
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# HELP %s Kernel command line parameters.\n", name)
+	fmt.Fprintf(&sb, "# TYPE %s gauge\n", name)
+	sb.WriteString(name)
+	labels := make(map[string]string, len(e.Keys))
+	for _, key := range e.Keys {
+		canonicalKey := canonicalizeKey(key)
+		vals := k.values(canonicalKey)
+		if vals == nil {
+			continue
+		}
+		label := promName(canonicalKey)
+		if other, dup := labels[label]; dup {
+			if other == canonicalKey {
+				continue
+			}
+			return fmt.Errorf("exporting keys %s and %s as label %s: %w", other, canonicalKey, label, ErrUnsupported)
+		}
+		labels[label] = canonicalKey
+		for idx, val := range vals {
+			if val == "" {
+				vals[idx] = "true"
+			}
+		}
+		value := strings.Join(vals, ",")
+		if redact[canonicalKey] {
+			value = "redacted"
+		}
+		if len(labels) == 1 {
+			sb.WriteByte('{')
+		} else {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%s=\"%s\"", label, promEscaper.Replace(value))
+	}
+	if len(labels) > 0 {
+		sb.WriteByte('}')
+	}
+	sb.WriteString(" 1\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// promEscaper escapes label values in the text exposition format.
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promName returns name with all characters that aren't allowed in metric and
+// label names replaced by underscores, and an underscore prepended if it begins
+// with a digit.
+func promName(name string) string {
+	b := []byte(name)
+	for idx, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+		default:
+			b[idx] = '_'
+		}
+	}
+	if len(b) == 0 || (b[0] >= '0' && b[0] <= '9') {
+		return "_" + string(b)
+	}
+	return string(b)
+}
@@ -0,0 +1,46 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromExporter_WriteMetric(t *testing.T) {
+	k := NewKargs([]byte(`root=/dev/sda1 console=tty0 console=ttyS0 quiet rd.luks.key=/secret msg="say \"hi\""`))
+
+	var buf bytes.Buffer
+	e := PromExporter{
+		Keys:   []string{"root", "console", "quiet", "rd.luks.key", "msg", "missing"},
+		Redact: []string{"rd.luks.key"},
+	}
+	assert.NoError(t, e.WriteMetric(&buf, k))
+	assert.Equal(t, "# HELP kernel_cmdline_info Kernel command line parameters.\n"+
+		"# TYPE kernel_cmdline_info gauge\n"+
+		`kernel_cmdline_info{root="/dev/sda1",console="tty0,ttyS0",quiet="true",rd_luks_key="redacted",msg="say \"hi\""} 1`+"\n",
+		buf.String())
+
+	buf.Reset()
+	assert.NoError(t, PromExporter{Name: "node_boot_info"}.WriteMetric(&buf, k))
+	assert.Contains(t, buf.String(), "\nnode_boot_info 1\n")
+}
+
+func TestPromExporter_WriteMetric_errors(t *testing.T) {
+	k := NewKargs([]byte("rd.break rd_break=1"))
+
+	var buf bytes.Buffer
+	assert.ErrorIs(t, PromExporter{Name: "bad-name"}.WriteMetric(&buf, k), ErrUnsupported)
+	assert.ErrorIs(t, PromExporter{Name: "__reserved"}.WriteMetric(&buf, k), ErrUnsupported)
+	assert.ErrorIs(t, PromExporter{Keys: []string{"rd.break", "rd_break"}}.WriteMetric(&buf, k), ErrUnsupported)
+	assert.NoError(t, PromExporter{Keys: []string{"rd-break", "rd_break"}}.WriteMetric(&buf, k))
+}
+
+func TestPromName(t *testing.T) {
+	assert.Equal(t, "rd_luks_uuid", promName("rd.luks.uuid"))
+	assert.Equal(t, "_8250_nr_uarts", promName("8250.nr_uarts"))
+	assert.Equal(t, "a1_b", promName("a1:b"))
+}
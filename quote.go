@@ -0,0 +1,179 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuoteMode controls how values are quoted when parameters are written into a
+// Kargs, e.g. by SetKarg. Select it with WithQuoteMode.
+type QuoteMode int
+
+const (
+	// QuoteDefault quotes values containing spaces with Go-style escapes
+	// unless they are already surrounded by quotes. This is the historical
+	// behavior of this package. Note that the kernel does not understand the
+	// escapes it may produce.
+	QuoteDefault QuoteMode = iota
+
+	// QuoteKernel surrounds values containing whitespace with double quotes
+	// without escaping anything, which is what the kernel expects. Values
+	// containing a double quote cannot be represented, since the kernel
+	// would take it as opening or closing a quoted section, and are
+	// rejected.
+	QuoteKernel
+
+	// QuoteAlways is like QuoteKernel, but surrounds every value with double
+	// quotes, whether it contains whitespace or not.
+	QuoteAlways
+
+	// QuoteNever never quotes values and rejects those containing
+	// whitespace or a double quote.
+	QuoteNever
+
+	// QuotePreserve keeps values that are already surrounded by quotes
+	// exactly as they were given and quotes all others like QuoteKernel.
+	QuotePreserve
+)
+
+// String returns the name of mode.
+func (mode QuoteMode) String() string {
+	switch mode {
+	case QuoteDefault:
+		return "default"
+	case QuoteKernel:
+		return "kernel"
+	case QuoteAlways:
+		return "always"
+	case QuoteNever:
+		return "never"
+	case QuotePreserve:
+		return "preserve"
+	default:
+		return fmt.Sprintf("QuoteMode(%d)", int(mode))
+	}
+}
+
+// QuoteValue returns value in a form that can be used as the value of a
+// parameter on the command line. Values that contain whitespace or begin with a
+// quote are surrounded by double quotes, with double quotes and backslashes
+// inside escaped by a backslash; all other values are returned unchanged.
+// UnquoteValue reverses QuoteValue, so UnquoteValue(QuoteValue(v)) always
+// returns v.
+//
+// Note that the kernel itself does not understand escapes, so values that are
+// quoted and contain double quotes or backslashes are only read back correctly
+// by this package.
+func QuoteValue(value string) string {
+	if !strings.ContainsAny(value, " \n\t") && (len(value) == 0 || (value[0] != '"' && value[0] != '\'')) {
+		return value
+	}
+	var sb strings.Builder
+	sb.Grow(len(value) + 2)
+	sb.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(value[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// UnquoteValue returns the value represented by quoted, which is the value of a
+// parameter as it appears on the command line. Values that begin with a single
+// or double quote must end with the same, unescaped quote, which is removed
+// along with the opening one; inside the quotes, a backslash escapes the quote
+// character and the backslash itself. All other values are returned unchanged.
+// An error is returned if quoted is not terminated.
+func UnquoteValue(quoted string) (string, error) {
+	if len(quoted) == 0 || (quoted[0] != '"' && quoted[0] != '\'') {
+		return quoted, nil
+	}
+	if !isQuoted(quoted) {
+		return "", fmt.Errorf("unquoting value %s: %w", quoted, ErrUnterminatedQuote)
+	}
+	// The closing quote must not be escaped, i.e. be preceded by an even
+	// number of backslashes.
+	backslashes := 0
+	for i := len(quoted) - 2; i > 0 && quoted[i] == '\\'; i-- {
+		backslashes++
+	}
+	if backslashes%2 != 0 {
+		return "", fmt.Errorf("unquoting value %s: %w", quoted, ErrUnterminatedQuote)
+	}
+	return dequote(quoted), nil
+}
+
+// isQuoted returns whether value is surrounded by a matching pair of single or
+// double quotes.
+func isQuoted(value string) bool {
+	return len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]
+}
+
+// quotedKarg returns a Karg for the already checked key and value, quoting
+// value with QuoteValue so that it is read back exactly. An empty value produces
+// a karg without a value.
+func quotedKarg(key, value string) Karg {
+	karg := Karg{CanonicalKey: canonicalizeKey(key), Key: key, Raw: key, Value: value}
+	if value != "" {
+		karg.Raw = key + "=" + QuoteValue(value)
+	}
+	return karg
+}
+
+// kernelKarg returns a Karg for the already checked key and value, with value
+// written in kernel form by kernelValue.
+func kernelKarg(key, value string) (Karg, error) {
+	karg := Karg{CanonicalKey: canonicalizeKey(key), Key: key, Raw: key, Value: value}
+	if value != "" {
+		quoted, err := kernelValue(value)
+		if err != nil {
+			return Karg{}, fmt.Errorf("value of key %s: %w", key, err)
+		}
+		karg.Raw = key + "=" + quoted
+	}
+	return karg, nil
+}
+
+// kernelValue returns value in kernel form: surrounded by double quotes if it
+// contains whitespace or begins with a quote, and unchanged otherwise, without
+// escapes. An error wrapping ErrUnquotable is returned if value needs quotes
+// but contains a double quote, which the kernel can't read back.
+func kernelValue(value string) (string, error) {
+	if !hasKernelSpace(value) && value[0] != '"' && value[0] != '\'' {
+		return value, nil
+	}
+	if strings.Contains(value, `"`) {
+		return "", fmt.Errorf("value %s: %w", value, ErrUnquotable)
+	}
+	return `"` + value + `"`, nil
+}
+
+// quoteMode quotes value for use on the command line according to mode. value
+// is the value as given by the caller, which may already be quoted.
+func quoteMode(value string, mode QuoteMode) (string, error) {
+	if mode == QuoteDefault {
+		return enquote(value), nil
+	}
+	if mode == QuotePreserve && isQuoted(value) {
+		return value, nil
+	}
+
+	unquoted := dequote(value)
+	needsQuotes := strings.ContainsAny(unquoted, " \n\t") || mode == QuoteAlways
+	switch {
+	case strings.Contains(unquoted, `"`):
+		return "", fmt.Errorf("quoting value %q in mode %s: %w", value, mode, ErrUnquotable)
+	case !needsQuotes:
+		return unquoted, nil
+	case mode == QuoteNever:
+		return "", fmt.Errorf("quoting value %q in mode %s: %w", value, mode, ErrUnquotable)
+	default:
+		return `"` + unquoted + `"`, nil
+	}
+}
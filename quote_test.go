@@ -0,0 +1,108 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteMode(t *testing.T) {
+	checks := []struct {
+		in   string
+		mode QuoteMode
+		want string
+		err  error
+	}{
+		{in: `nospaces`, mode: QuoteDefault, want: `nospaces`},
+		{in: `spaces" inner"`, mode: QuoteDefault, want: `"spaces\" inner\""`},
+		{in: `nospaces`, mode: QuoteKernel, want: `nospaces`},
+		{in: `with spaces`, mode: QuoteKernel, want: `"with spaces"`},
+		{in: `'with spaces'`, mode: QuoteKernel, want: `"with spaces"`},
+		{in: `spaces" inner"`, mode: QuoteKernel, err: ErrUnquotable},
+		{in: `inner"quote`, mode: QuoteKernel, err: ErrUnquotable},
+		{in: `"inner"quote"`, mode: QuoteKernel, err: ErrUnquotable},
+		{in: `nospaces`, mode: QuoteAlways, want: `"nospaces"`},
+		{in: `with spaces`, mode: QuoteAlways, want: `"with spaces"`},
+		{in: `inner"quote`, mode: QuoteAlways, err: ErrUnquotable},
+		{in: `nospaces`, mode: QuoteNever, want: `nospaces`},
+		{in: `with spaces`, mode: QuoteNever, err: ErrUnquotable},
+		{in: `inner"quote`, mode: QuoteNever, err: ErrUnquotable},
+		{in: `'with spaces'`, mode: QuotePreserve, want: `'with spaces'`},
+		{in: `with spaces`, mode: QuotePreserve, want: `"with spaces"`},
+		{in: `inner"quote`, mode: QuotePreserve, err: ErrUnquotable},
+	}
+	for _, check := range checks {
+		have, err := quoteMode(check.in, check.mode)
+		if check.err != nil {
+			assert.ErrorIs(t, err, check.err, "input %q in mode %s", check.in, check.mode)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, check.want, have, "input %q in mode %s", check.in, check.mode)
+	}
+}
+
+func TestQuoteMode_String(t *testing.T) {
+	assert.Equal(t, "kernel", QuoteKernel.String())
+	assert.Equal(t, "QuoteMode(42)", QuoteMode(42).String())
+}
+
+func TestQuoteValue(t *testing.T) {
+	checks := [][]string{
+		// Input, expected output
+		[]string{``, ``},
+		[]string{`plain`, `plain`},
+		[]string{`inner"quote`, `inner"quote`},
+		[]string{`back\slash`, `back\slash`},
+		[]string{`with spaces`, `"with spaces"`},
+		[]string{`"leading quote`, `"\"leading quote"`},
+		[]string{`'leading single quote`, `"'leading single quote"`},
+		[]string{`spaces "and" quotes`, `"spaces \"and\" quotes"`},
+		[]string{`spaces and backslash\`, `"spaces and backslash\\"`},
+	}
+	for _, check := range checks {
+		in := check[0]
+		want := check[1]
+		have := QuoteValue(in)
+		assert.Equal(t, want, have)
+	}
+}
+
+func TestUnquoteValue(t *testing.T) {
+	checks := [][]string{
+		// Input, expected output
+		[]string{``, ``},
+		[]string{`plain`, `plain`},
+		[]string{`inner"quote`, `inner"quote`},
+		[]string{`"with spaces"`, `with spaces`},
+		[]string{`'single quotes'`, `single quotes`},
+		[]string{`"spaces \"and\" quotes"`, `spaces "and" quotes`},
+		[]string{`"backslash\\"`, `backslash\`},
+	}
+	for _, check := range checks {
+		in := check[0]
+		want := check[1]
+		have, err := UnquoteValue(in)
+		assert.NoError(t, err)
+		assert.Equal(t, want, have)
+	}
+
+	for _, in := range []string{`"`, `"unterminated`, `'mismatched"`, `"escaped end\"`} {
+		_, err := UnquoteValue(in)
+		assert.ErrorIs(t, err, ErrUnterminatedQuote, "input %q", in)
+	}
+}
+
+func FuzzQuoteValue(f *testing.F) {
+	for _, seed := range []string{``, `plain`, `with spaces`, `"quoted"`, `a\"b`, `trailing\`, `'`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		have, err := UnquoteValue(QuoteValue(in))
+		assert.NoError(t, err)
+		assert.Equal(t, in, have)
+	})
+}
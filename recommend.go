@@ -0,0 +1,117 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Suggestion is a karg suggested by Recommend, with the reason for it.
+type Suggestion struct {
+	Key       string // Key to set
+	Value     string // Value to set, empty for a karg without a value
+	Rationale string // Why the karg is suggested
+	Rule      string // Name of the rule making the suggestion
+}
+
+// RecommendRule suggests kargs for the machine described by facts, a map of
+// names to values like those used by Resolve. The Rule field of the
+// suggestions it returns is filled in by Recommend.
+type RecommendRule func(facts map[string]string) []Suggestion
+
+// recommendRules is the registry of rules consulted by Recommend, keyed by name.
+var recommendRules = struct {
+	sync.RWMutex
+	rules map[string]RecommendRule
+}{
+	rules: map[string]RecommendRule{
+		"bmc-sol":        recommendBMCSol,
+		"nvme-multipath": recommendNVMeMultipath,
+	},
+}
+
+// RegisterRecommendRule registers rule under name, replacing any rule
+// registered under the same name before. The registry is shared by the whole
+// program and ships with these rules registered:
+//
+//   - bmc-sol suggests console=<port> for the serial-over-LAN port of a BMC,
+//     given as the fact bmc_sol_port (e.g. ttyS1), with the speed from the
+//     optional fact bmc_sol_baud appended (e.g. 115200).
+//   - nvme-multipath suggests nvme_core.multipath=Y if the fact
+//     nvme_multipath is "true", e.g. for arrays reachable over several
+//     controllers.
+func RegisterRecommendRule(name string, rule RecommendRule) {
+	recommendRules.Lock()
+	defer recommendRules.Unlock()
+	recommendRules.rules[name] = rule
+}
+
+// UnregisterRecommendRule removes the rule registered under name, including one
+// of the defaults.
+func UnregisterRecommendRule(name string) {
+	recommendRules.Lock()
+	defer recommendRules.Unlock()
+	delete(recommendRules.rules, name)
+}
+
+// Recommend returns the kargs suggested for the machine described by facts by
+// the registered rules (see RegisterRecommendRule), without applying them.
+// Rules are consulted in the order of their names. Suggestions k already
+// satisfies, i.e. whose key is present with the suggested value, are left out.
+func (k *Kargs) Recommend(facts map[string]string) []Suggestion {
+	recommendRules.RLock()
+	names := make([]string, 0, len(recommendRules.rules))
+	for name := range recommendRules.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rules := make([]RecommendRule, len(names))
+	for idx, name := range names {
+		rules[idx] = recommendRules.rules[name]
+	}
+	recommendRules.RUnlock()
+
+	var suggestions []Suggestion
+	for idx, rule := range rules {
+		for _, suggestion := range rule(facts) {
+			if k.HasKargValue(suggestion.Key, suggestion.Value) {
+				continue
+			}
+			suggestion.Rule = names[idx]
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
+
+// recommendBMCSol implements the bmc-sol rule.
+func recommendBMCSol(facts map[string]string) []Suggestion {
+	port := strings.TrimPrefix(facts["bmc_sol_port"], "/dev/")
+	if port == "" {
+		return nil
+	}
+	console := port
+	if baud := facts["bmc_sol_baud"]; baud != "" {
+		console += "," + baud
+	}
+	return []Suggestion{{
+		Key:       "console",
+		Value:     console,
+		Rationale: "the BMC provides serial-over-LAN on " + port + ", so kernel messages should go there",
+	}}
+}
+
+// recommendNVMeMultipath implements the nvme-multipath rule.
+func recommendNVMeMultipath(facts map[string]string) []Suggestion {
+	if facts["nvme_multipath"] != "true" {
+		return nil
+	}
+	return []Suggestion{{
+		Key:       "nvme_core.multipath",
+		Value:     "Y",
+		Rationale: "NVMe namespaces are reachable over several controllers, which requires native NVMe multipathing",
+	}}
+}
@@ -0,0 +1,54 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_Recommend(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0"))
+	facts := map[string]string{
+		"bmc_sol_port":   "/dev/ttyS1",
+		"bmc_sol_baud":   "115200",
+		"nvme_multipath": "true",
+	}
+
+	suggestions := k.Recommend(facts)
+	if assert.Len(t, suggestions, 2) {
+		assert.Equal(t, "bmc-sol", suggestions[0].Rule)
+		assert.Equal(t, "console", suggestions[0].Key)
+		assert.Equal(t, "ttyS1,115200", suggestions[0].Value)
+		assert.NotEmpty(t, suggestions[0].Rationale)
+		assert.Equal(t, "nvme-multipath", suggestions[1].Rule)
+		assert.Equal(t, "nvme_core.multipath", suggestions[1].Key)
+		assert.Equal(t, "Y", suggestions[1].Value)
+	}
+
+	// Satisfied suggestions are left out.
+	k = NewKargs([]byte("console=ttyS1,115200 nvme-core.multipath=Y"))
+	assert.Empty(t, k.Recommend(facts))
+	assert.Empty(t, NewKargsEmpty().Recommend(nil))
+}
+
+func TestRegisterRecommendRule(t *testing.T) {
+	RegisterRecommendRule("a-test", func(facts map[string]string) []Suggestion {
+		if facts["gpu"] == "nvidia" {
+			return []Suggestion{{Key: "nvidia-drm.modeset", Value: "1", Rationale: "needed for Wayland"}}
+		}
+		return nil
+	})
+	defer UnregisterRecommendRule("a-test")
+	UnregisterRecommendRule("nvme-multipath")
+	defer RegisterRecommendRule("nvme-multipath", recommendNVMeMultipath)
+
+	k := NewKargsEmpty()
+	suggestions := k.Recommend(map[string]string{"gpu": "nvidia", "nvme_multipath": "true", "bmc_sol_port": "ttyS1"})
+	assert.Equal(t, []Suggestion{
+		{Key: "nvidia-drm.modeset", Value: "1", Rationale: "needed for Wayland", Rule: "a-test"},
+		{Key: "console", Value: "ttyS1", Rationale: "the BMC provides serial-over-LAN on ttyS1, so kernel messages should go there", Rule: "bmc-sol"},
+	}, suggestions)
+}
@@ -0,0 +1,140 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SignedKargs is a command line together with a signature over it, as produced
+// by Sign. It can be sent to a client as is, e.g. encoded as JSON, for the
+// client to check with Verify before booting with it.
+type SignedKargs struct {
+	Cmdline   string `json:"cmdline"`   // Command line in kernel form
+	Signature []byte `json:"signature"` // Signature over the bytes of Cmdline
+}
+
+// Sign serializes k in kernel form and signs it with signer. In kernel form,
+// each karg is written with its key as written and, if it has a value, its
+// value, surrounded by double quotes if it contains whitespace or begins with a
+// quote, and kargs are separated by a single space. There are no escapes, so
+// the kernel reads the command line exactly like this package does. An error
+// wrapping ErrUnquotable is returned if a value that needs quotes contains a
+// double quote, which the kernel can't read back, and one wrapping
+// ErrKernelMismatch if the kernel would read the command line differently for
+// any other reason, e.g. because of an unbalanced double quote.
+//
+// Ed25519 keys sign the serialization itself; all other keys sign its SHA-256
+// digest, which for RSA keys results in a PKCS #1 v1.5 signature.
+func (k *Kargs) Sign(signer crypto.Signer) (SignedKargs, error) {
+	cmdline, err := k.kernelForm()
+	if err != nil {
+		return SignedKargs{}, fmt.Errorf("signing kargs: %w", err)
+	}
+	msg, opts := signingInput(signer.Public(), cmdline)
+	sig, err := signer.Sign(rand.Reader, msg, opts)
+	if err != nil {
+		return SignedKargs{}, fmt.Errorf("signing kargs: %w", err)
+	}
+	return SignedKargs{Cmdline: cmdline, Signature: sig}, nil
+}
+
+// Kargs parses the signed command line into a new Kargs. It does not check the
+// signature; call Verify first.
+func (s SignedKargs) Kargs() *Kargs {
+	return NewKargs([]byte(s.Cmdline))
+}
+
+// Verify checks that the signature of s was made over exactly the bytes of its
+// command line by the private key belonging to pub. Any change to the command
+// line, even one this package considers equivalent, invalidates the signature,
+// since the kernel may read it differently; a client must boot with exactly
+// s.Cmdline. Ed25519, ECDSA, and RSA keys are supported; other keys result in an
+// error wrapping ErrUnsupported. An error wrapping ErrBadSignature is returned
+// if the signature doesn't match.
+func (s SignedKargs) Verify(pub crypto.PublicKey) error {
+	msg, _ := signingInput(pub, s.Cmdline)
+	var ok bool
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, msg, s.Signature)
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, msg, s.Signature)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, msg, s.Signature) == nil
+	default:
+		return fmt.Errorf("verifying kargs with %T: %w", pub, ErrUnsupported)
+	}
+	if !ok {
+		return fmt.Errorf("verifying kargs: %w", ErrBadSignature)
+	}
+	return nil
+}
+
+// kernelForm returns the serialization of k in kernel form as described in
+// Sign, after checking that the kernel splits it into the same parameters as
+// this package does.
+func (k *Kargs) kernelForm() (string, error) {
+	var (
+		sb     strings.Builder
+		params []string // Parameters as the kernel passes them on
+	)
+	for item := k.list; item != nil; item = item.next {
+		karg := item.karg
+		if item != k.list {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(karg.Key)
+		param := karg.Key
+		if karg.Value != "" {
+			value, err := kernelValue(karg.Value)
+			if err != nil {
+				return "", fmt.Errorf("key %s: %w", karg.Key, err)
+			}
+			sb.WriteByte('=')
+			sb.WriteString(value)
+			param += "=" + karg.Value
+		}
+		params = append(params, param)
+	}
+	cmdline := sb.String()
+	reread := NewKargs([]byte(cmdline))
+	same := slices.Equal(SplitLikeKernel(cmdline), params) && reread.Len() == k.Len()
+	for item, other := k.list, reread.list; same && item != nil; item, other = item.next, other.next {
+		same = item.karg.Key == other.karg.Key && item.karg.Value == other.karg.Value
+	}
+	if !same {
+		return "", fmt.Errorf("serializing %s: %w", cmdline, ErrKernelMismatch)
+	}
+	return cmdline, nil
+}
+
+// hasKernelSpace reports whether s contains a byte the kernel treats as
+// whitespace.
+func hasKernelSpace(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isKernelSpace(s[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// signingInput returns the message to sign or verify for cmdline with the key
+// pub, and the signer options to sign it with.
+func signingInput(pub crypto.PublicKey, cmdline string) ([]byte, crypto.SignerOpts) {
+	if _, ok := pub.(ed25519.PublicKey); ok {
+		return []byte(cmdline), crypto.Hash(0)
+	}
+	digest := sha256.Sum256([]byte(cmdline))
+	return digest[:], crypto.SHA256
+}
@@ -0,0 +1,104 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_Sign(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	signers := map[string]crypto.Signer{
+		"ed25519": edKey,
+		"ecdsa":   ecKey,
+		"rsa":     rsaKey,
+	}
+	for name, signer := range signers {
+		t.Run(name, func(t *testing.T) {
+			k := NewKargs([]byte(`root=/dev/sda1 rd-break init='/sbin/init' foo="bar baz"`))
+			signed, err := k.Sign(signer)
+			assert.NoError(t, err)
+			assert.Equal(t, `root=/dev/sda1 rd-break init=/sbin/init foo="bar baz"`, signed.Cmdline)
+			assert.NoError(t, signed.Verify(signer.Public()))
+
+			// Spellings this package considers equivalent don't verify,
+			// since the kernel may read them differently.
+			for _, cmdline := range []string{
+				`root=/dev/sda1  rd-break init=/sbin/init foo="bar baz"`,
+				`root=/dev/sda1 rd_break init=/sbin/init foo="bar baz"`,
+				`root=/dev/sda1 rd-break init="/sbin/init" foo='bar baz'`,
+			} {
+				equivalent := signed
+				equivalent.Cmdline = cmdline
+				assert.ErrorIs(t, equivalent.Verify(signer.Public()), ErrBadSignature, cmdline)
+			}
+
+			tampered := signed
+			tampered.Cmdline = `root=/dev/sda2 rd_break init=/sbin/init foo="bar baz"`
+			assert.ErrorIs(t, tampered.Verify(signer.Public()), ErrBadSignature)
+		})
+	}
+}
+
+func TestSignedKargs_Verify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	signed, err := NewKargs([]byte("quiet")).Sign(edKey)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, signed.Verify(otherPub), ErrBadSignature)
+	assert.ErrorIs(t, signed.Verify("not a key"), ErrUnsupported)
+	assert.Equal(t, "quiet", signed.Kargs().String())
+}
+
+func TestSignedKargs_Verify_injection(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	signed, err := NewKargs([]byte(`quiet msg="x init=/bin/sh y"`)).Sign(edKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"quiet", "msg=x init=/bin/sh y"}, SplitLikeKernel(signed.Cmdline))
+
+	// Single quotes group for this package, but not for the kernel, which
+	// would get init=/bin/sh as a parameter of its own.
+	tampered := signed
+	tampered.Cmdline = `quiet msg='x init=/bin/sh y'`
+	assert.Contains(t, SplitLikeKernel(tampered.Cmdline), "init=/bin/sh")
+	assert.ErrorIs(t, tampered.Verify(edKey.Public()), ErrBadSignature)
+}
+
+func TestKargs_Sign_kernelForm(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	// Values are quoted the way the kernel understands, without escapes.
+	k := NewKargs([]byte(`a="'x'" b=c\d`))
+	assert.NoError(t, k.SetKarg("tab", "one\ttwo"))
+	signed, err := k.Sign(edKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=\"'x'\" b=c\\d tab=\"one\ttwo\"", signed.Cmdline)
+	assert.Equal(t, []string{"a='x'", `b=c\d`, "tab=one\ttwo"}, SplitLikeKernel(signed.Cmdline))
+
+	// Values with double quotes can't be written for the kernel.
+	k = NewKargs([]byte(`quiet`))
+	assert.NoError(t, k.SetKarg("msg", `say "hi"`))
+	_, err = k.Sign(edKey)
+	assert.ErrorIs(t, err, ErrUnquotable)
+}
@@ -0,0 +1,106 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Slots holds two sets of kernel command line arguments for A/B booting: the
+// active set, which is used for the next boot, and the fallback set, which is
+// known to work and used if the active set fails. All operations on Slots are
+// atomic, so it is safe for concurrent use, but the Kargs it hands out are not.
+type Slots struct {
+	mu       sync.Mutex
+	active   *Kargs
+	fallback *Kargs
+}
+
+// slotsJSON is the JSON form of Slots.
+type slotsJSON struct {
+	Active   string `json:"active"`
+	Fallback string `json:"fallback"`
+}
+
+// NewSlots returns Slots holding active and fallback. A nil Kargs is replaced
+// by an empty one.
+func NewSlots(active, fallback *Kargs) *Slots {
+	if active == nil {
+		active = NewKargsEmpty()
+	}
+	if fallback == nil {
+		fallback = NewKargsEmpty()
+	}
+	return &Slots{active: active, fallback: fallback}
+}
+
+// Active returns the active set of s.
+func (s *Slots) Active() *Kargs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Fallback returns the fallback set of s.
+func (s *Slots) Fallback() *Kargs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fallback
+}
+
+// MarshalJSON encodes s as an object holding the string forms of both sets. It
+// implements json.Marshaler.
+func (s *Slots) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(slotsJSON{
+		Active:   s.active.String(),
+		Fallback: s.fallback.String(),
+	})
+}
+
+// Promote marks the active set of s as known to work by making a copy of it
+// the fallback set.
+func (s *Slots) Promote() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = NewKargs([]byte(s.active.String()))
+}
+
+// Stage makes candidate the active set of s, to be tried on the next boot, and
+// the previously active set the fallback set. A nil candidate is replaced by an
+// empty Kargs.
+func (s *Slots) Stage(candidate *Kargs) {
+	if candidate == nil {
+		candidate = NewKargsEmpty()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = s.active
+	s.active = candidate
+}
+
+// Swap exchanges the active and fallback sets of s, e.g. to roll back to the
+// fallback set after the active one failed.
+func (s *Slots) Swap() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active, s.fallback = s.fallback, s.active
+}
+
+// UnmarshalJSON decodes s from the form produced by MarshalJSON. It implements
+// json.Unmarshaler.
+func (s *Slots) UnmarshalJSON(data []byte) error {
+	var decoded slotsJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("failed to decode slots: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = NewKargs([]byte(decoded.Active))
+	s.fallback = NewKargs([]byte(decoded.Fallback))
+	return nil
+}
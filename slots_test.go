@@ -0,0 +1,64 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSlots(t *testing.T) {
+	s := NewSlots(nil, nil)
+	assert.NotNil(t, s.Active())
+	assert.NotNil(t, s.Fallback())
+	assert.Empty(t, s.Active().String())
+}
+
+func TestSlots_Promote(t *testing.T) {
+	s := NewSlots(NewKargs([]byte("root=b")), NewKargs([]byte("root=a")))
+	s.Promote()
+	assert.Equal(t, "root=b", s.Fallback().String())
+
+	// The fallback set is a copy
+	err := s.Active().SetKarg("quiet", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "root=b", s.Fallback().String())
+}
+
+func TestSlots_Stage(t *testing.T) {
+	known := NewKargs([]byte("root=a"))
+	s := NewSlots(known, nil)
+
+	candidate := NewKargs([]byte("root=b"))
+	s.Stage(candidate)
+	assert.Same(t, candidate, s.Active())
+	assert.Same(t, known, s.Fallback())
+}
+
+func TestSlots_Swap(t *testing.T) {
+	a := NewKargs([]byte("root=a"))
+	b := NewKargs([]byte("root=b"))
+	s := NewSlots(a, b)
+	s.Swap()
+	assert.Same(t, b, s.Active())
+	assert.Same(t, a, s.Fallback())
+}
+
+func TestSlots_JSON(t *testing.T) {
+	s := NewSlots(NewKargs([]byte(`root=b init="/sbin/init -v"`)), NewKargs([]byte("root=a")))
+	data, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"active": "root=b init=\"/sbin/init -v\"", "fallback": "root=a"}`, string(data))
+
+	var decoded Slots
+	err = json.Unmarshal(data, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, `root=b init="/sbin/init -v"`, decoded.Active().String())
+	assert.Equal(t, "root=a", decoded.Fallback().String())
+
+	err = json.Unmarshal([]byte(`{"active": 1}`), &decoded)
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// procCmdline is the file the command line of the running kernel is read from.
+const procCmdline = "/proc/cmdline"
+
+// snapshotNodeID is the node ID BootSnapshots created by NewBootSnapshotFile
+// keep their snapshot under.
+const snapshotNodeID = "boot"
+
+// BootSnapshot detects changes of the kernel command line across reboots, for
+// auditing agents: it keeps a snapshot of the command line of the running
+// kernel in a NodeStore, and later runs, typically after a reboot, compare the
+// command line of the running kernel with it. The snapshot is kept until it is
+// replaced with Record, so that a change keeps being reported until it has been
+// acknowledged.
+type BootSnapshot struct {
+	store       NodeStore
+	id          string // Node ID of the snapshot in store
+	cmdlinePath string // File holding the command line of the running kernel
+}
+
+// NewBootSnapshot returns a BootSnapshot keeping its snapshot in store under the
+// node ID id.
+func NewBootSnapshot(store NodeStore, id string) *BootSnapshot {
+	return &BootSnapshot{store: store, id: id, cmdlinePath: procCmdline}
+}
+
+// NewBootSnapshotFile returns a BootSnapshot keeping its snapshot in the file at
+// path, in the format of JSONFileStore.
+func NewBootSnapshotFile(path string) *BootSnapshot {
+	return NewBootSnapshot(NewJSONFileStore(path), snapshotNodeID)
+}
+
+// Record replaces the snapshot with the command line of the running kernel. ctx
+// is passed on to the NodeStore.
+func (s *BootSnapshot) Record(ctx context.Context) error {
+	running, err := s.running()
+	if err != nil {
+		return fmt.Errorf("recording boot snapshot: %w", err)
+	}
+	if err := s.store.StoreNode(ctx, s.id, running); err != nil {
+		return fmt.Errorf("recording boot snapshot: %w", err)
+	}
+	return nil
+}
+
+// Compare returns the changes that turn the snapshot into the command line of
+// the running kernel, as computed by Diff, which are empty if it hasn't
+// changed. If there is no snapshot yet, the command line of the running kernel
+// is recorded as the snapshot and no changes are returned. ctx is passed on to
+// the NodeStore.
+func (s *BootSnapshot) Compare(ctx context.Context) (KargsDiff, error) {
+	running, err := s.running()
+	if err != nil {
+		return nil, fmt.Errorf("comparing boot snapshot: %w", err)
+	}
+	snapshot, err := s.store.LookupNode(ctx, s.id)
+	if errors.Is(err, ErrNoNode) {
+		if err := s.store.StoreNode(ctx, s.id, running); err != nil {
+			return nil, fmt.Errorf("comparing boot snapshot: %w", err)
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("comparing boot snapshot: %w", err)
+	}
+	return snapshot.Diff(running), nil
+}
+
+// running returns the command line of the running kernel.
+func (s *BootSnapshot) running() (*Kargs, error) {
+	data, err := os.ReadFile(s.cmdlinePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kernel command line: %w", err)
+	}
+	return NewKargs([]byte(strings.TrimRight(string(data), "\n"))), nil
+}
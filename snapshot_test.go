@@ -0,0 +1,76 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cmdline := filepath.Join(dir, "cmdline")
+	boot := func(line string) {
+		assert.NoError(t, os.WriteFile(cmdline, []byte(line+"\n"), 0o644))
+	}
+	s := NewBootSnapshotFile(filepath.Join(dir, "snapshot.json"))
+	s.cmdlinePath = cmdline
+
+	// The first run records the snapshot.
+	boot("root=/dev/sda1 console=ttyS0 quiet")
+	diff, err := s.Compare(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	// Later runs report changes until they are recorded.
+	boot("root=/dev/sda2 console=ttyS0 nokaslr")
+	want := KargsDiff{
+		{Op: ChangeReplace, Key: "root", Values: []string{"/dev/sda2"}},
+		{Op: ChangeDelete, Key: "quiet"},
+		{Op: ChangeAdd, Key: "nokaslr", Values: []string{""}},
+	}
+	for range 2 {
+		diff, err = s.Compare(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, want, diff)
+	}
+	assert.NoError(t, s.Record(ctx))
+	diff, err = s.Compare(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	// The snapshot outlives the BootSnapshot.
+	s = NewBootSnapshotFile(filepath.Join(dir, "snapshot.json"))
+	s.cmdlinePath = cmdline
+	boot("root=/dev/sda2 console=ttyS0")
+	diff, err = s.Compare(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, KargsDiff{{Op: ChangeDelete, Key: "nokaslr"}}, diff)
+}
+
+func TestBootSnapshot_errors(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{}
+	s := NewBootSnapshot(store, "node1")
+	s.cmdlinePath = filepath.Join(t.TempDir(), "missing")
+
+	_, err := s.Compare(ctx)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.ErrorIs(t, s.Record(ctx), os.ErrNotExist)
+	_, err = store.LookupNode(ctx, "node1")
+	assert.ErrorIs(t, err, ErrNoNode)
+
+	s.cmdlinePath = filepath.Join(t.TempDir(), "cmdline")
+	assert.NoError(t, os.WriteFile(s.cmdlinePath, []byte("quiet\n"), 0o644))
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Compare(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, s.Record(canceled), context.Canceled)
+}
@@ -0,0 +1,77 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Sort orders the kargs of k by canonical key, keeping occurrences of the same
+// key in their original relative order, so that equal sets of kargs always
+// produce the same command line, e.g. for diffs of bootloader entries kept in
+// git. A "--" separator and the arguments for init following it are left at
+// the end in their original order, since init sees them in that order. See
+// also WithSortedOutput for sorting the string form only.
+func (k *Kargs) Sort() {
+	items := k.sortedItems()
+	if len(items) == 0 {
+		return
+	}
+	mark := items[len(items)-1].next
+	for _, item := range items {
+		k.unlink(item)
+	}
+	// Sorting is stable and keeps all items before the separator, so the
+	// key index stays in list order and only the module index needs to be
+	// rebuilt.
+	sortItems(items)
+	for _, item := range items {
+		k.linkBefore(mark, item)
+	}
+	clear(k.moduleMap)
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		k.indexModule(llTracker)
+	}
+}
+
+// sortedItems returns the list items of k that Sort orders, i.e. those up to
+// the first separator, in list order.
+func (k *Kargs) sortedItems() []*kargItem {
+	var items []*kargItem
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		if k.cfg.isSeparator(llTracker.karg, false) {
+			break
+		}
+		items = append(items, llTracker)
+	}
+	return items
+}
+
+// sortItems stably sorts items by canonical key.
+func sortItems(items []*kargItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].karg.CanonicalKey < items[j].karg.CanonicalKey
+	})
+}
+
+// writeSorted is like writeBuffer, but writes the kargs in the order of Sort.
+func (k *Kargs) writeSorted(buf *bytes.Buffer, cfg formatConfig) {
+	items := k.sortedItems()
+	rest := k.list
+	if len(items) > 0 {
+		rest = items[len(items)-1].next
+	}
+	sortItems(items)
+	for llTracker := rest; llTracker != nil; llTracker = llTracker.next {
+		items = append(items, llTracker)
+	}
+	for idx, item := range items {
+		if idx > 0 {
+			buf.WriteString(cfg.separator)
+		}
+		buf.WriteString(item.karg.Raw)
+	}
+	buf.WriteString(cfg.terminator)
+}
@@ -0,0 +1,42 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_Sort(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 usbcore.quirks=a console=ttyS0 quiet usbcore.autosuspend=-1 console=tty0 -- single b a"))
+	want := "console=ttyS0 console=tty0 quiet root=/dev/sda1 usbcore.autosuspend=-1 usbcore.quirks=a -- single b a"
+
+	assert.Equal(t, want, k.Format(WithSortedOutput()))
+	k.Sort()
+	assert.Equal(t, want, k.String())
+	vals, _ := k.GetKarg("console")
+	assert.Equal(t, []string{"ttyS0", "tty0"}, vals)
+	assert.Equal(t, "autosuspend=-1 quirks=a", k.FlagsForModule("usbcore"))
+
+	// The list stays consistent
+	assert.NoError(t, k.AppendKarg("splash", ""))
+	assert.NoError(t, k.DeleteKarg("usbcore.autosuspend"))
+	assert.Equal(t, "console=ttyS0 console=tty0 quiet root=/dev/sda1 usbcore.quirks=a -- single b a splash", k.String())
+	assert.Equal(t, 10, k.Len())
+
+	k = NewKargs([]byte("-- b a"))
+	k.Sort()
+	assert.Equal(t, "-- b a", k.String())
+
+	k = NewKargs([]byte("b a"))
+	k.Sort()
+	assert.Equal(t, "a b", k.String())
+	assert.NoError(t, k.DeleteAt(1))
+	assert.Equal(t, "a", k.String())
+
+	k = NewKargsEmpty()
+	k.Sort()
+	assert.Empty(t, k.String())
+}
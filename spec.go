@@ -0,0 +1,150 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KargsSpec describes the desired state of a command line, for use by
+// configuration management. Only the Key and Value of the kargs it holds are
+// used, and keys are compared in their canonical form. Kargs not mentioned by
+// the spec are left alone.
+type KargsSpec struct {
+	// Present holds key=value pairs that must occur, in addition to any
+	// other occurrences of the same keys, e.g. one of several consoles. An
+	// empty value requires the key to occur without a value.
+	Present []Karg
+
+	// Absent holds keys that must not occur at all.
+	Absent []string
+
+	// Values holds keys that must occur exactly once, with the given value.
+	Values []Karg
+}
+
+// ViolationKind is the kind of a Violation.
+type ViolationKind int
+
+const (
+	// ViolationMissing is reported for a pair of Present that doesn't occur.
+	ViolationMissing ViolationKind = iota
+
+	// ViolationForbidden is reported for a key of Absent that occurs.
+	ViolationForbidden
+
+	// ViolationValue is reported for a key of Values that is missing, occurs
+	// more than once, or has a different value.
+	ViolationValue
+)
+
+// String returns the name of kind.
+func (kind ViolationKind) String() string {
+	switch kind {
+	case ViolationMissing:
+		return "missing"
+	case ViolationForbidden:
+		return "forbidden"
+	case ViolationValue:
+		return "value"
+	default:
+		return fmt.Sprintf("ViolationKind(%d)", int(kind))
+	}
+}
+
+// Violation is a difference between a Kargs and a KargsSpec, as returned by
+// KargsSpec.Verify.
+type Violation struct {
+	Kind ViolationKind // Kind of violation
+	Key  string        // Key as written in the spec
+	Want string        // Required value, for ViolationMissing and ViolationValue
+	Got  []string      // Values the key has, if any
+}
+
+// Message returns v as a Message, to be rendered by a Catalog.
+func (v Violation) Message() Message {
+	args := map[string]string{"key": v.Key, "got": strings.Join(v.Got, ", ")}
+	switch v.Kind {
+	case ViolationMissing:
+		args["want"] = v.Want
+		return Message{ID: MsgSpecMissing, Args: args}
+	case ViolationForbidden:
+		return Message{ID: MsgSpecForbidden, Args: args}
+	default:
+		args["want"] = v.Want
+		return Message{ID: MsgSpecValue, Args: args}
+	}
+}
+
+// Code returns the stable code of the kind of v.
+func (v Violation) Code() Code {
+	return v.Message().ID.Code()
+}
+
+// String returns a description of v.
+func (v Violation) String() string {
+	switch v.Kind {
+	case ViolationMissing:
+		return fmt.Sprintf("%s: %s=%s is missing", v.Kind, v.Key, v.Want)
+	case ViolationForbidden:
+		return fmt.Sprintf("%s: %s is set", v.Kind, v.Key)
+	default:
+		return fmt.Sprintf("%s: %s must be %s, is %s", v.Kind, v.Key, v.Want, strings.Join(v.Got, ", "))
+	}
+}
+
+// Apply changes k to satisfy s: the keys of Absent are deleted, the keys of
+// Values are set with SetKargPosition at the SetPosition of k, which leaves a
+// single occurrence even of accumulating keys, and the pairs of Present are added with
+// AddKargValue, in this order, so that Present and Values win over Absent. It
+// keeps going when a key fails, e.g. because its value is rejected by the
+// QuoteMode of k, and returns a KeyErrors holding the failures, or nil.
+func (s KargsSpec) Apply(k *Kargs) error {
+	var errs KeyErrors
+	for _, key := range s.Absent {
+		if k.ContainsKarg(key) {
+			if err := k.DeleteKarg(key); err != nil {
+				errs = append(errs, &KeyError{Key: key, Err: err})
+			}
+		}
+	}
+	for _, karg := range s.Values {
+		if err := k.SetKargPosition(karg.Key, karg.Value, k.cfg.setPosition); err != nil {
+			errs = append(errs, &KeyError{Key: karg.Key, Err: err})
+		}
+	}
+	for _, karg := range s.Present {
+		if err := k.AddKargValue(karg.Key, karg.Value); err != nil {
+			errs = append(errs, &KeyError{Key: karg.Key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Verify returns how k violates s, in the order of Present, Absent, and Values,
+// or nil if k satisfies s.
+func (s KargsSpec) Verify(k *Kargs) []Violation {
+	var violations []Violation
+	for _, karg := range s.Present {
+		if !k.HasKargValue(karg.Key, karg.Value) {
+			got, _ := k.GetKarg(karg.Key)
+			violations = append(violations, Violation{Kind: ViolationMissing, Key: karg.Key, Want: karg.Value, Got: got})
+		}
+	}
+	for _, key := range s.Absent {
+		if got, present := k.GetKarg(key); present {
+			violations = append(violations, Violation{Kind: ViolationForbidden, Key: key, Got: got})
+		}
+	}
+	for _, karg := range s.Values {
+		if got, _ := k.GetKarg(karg.Key); len(got) != 1 || got[0] != karg.Value {
+			violations = append(violations, Violation{Kind: ViolationValue, Key: karg.Key, Want: karg.Value, Got: got})
+		}
+	}
+	return violations
+}
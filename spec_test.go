@@ -0,0 +1,65 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSpec = KargsSpec{
+	Present: []Karg{{Key: "console", Value: "ttyS0,115200"}, {Key: "rd.neednet", Value: "1"}},
+	Absent:  []string{"quiet", "rd-break"},
+	Values:  []Karg{{Key: "root", Value: "/dev/sda2"}, {Key: "selinux", Value: "1"}},
+}
+
+func TestKargsSpec_Verify(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet rd.neednet=1 rd_break selinux=1 selinux=0"))
+
+	assert.Equal(t, []Violation{
+		{Kind: ViolationMissing, Key: "console", Want: "ttyS0,115200", Got: []string{"tty0"}},
+		{Kind: ViolationForbidden, Key: "quiet", Got: []string{""}},
+		{Kind: ViolationForbidden, Key: "rd-break", Got: []string{""}},
+		{Kind: ViolationValue, Key: "root", Want: "/dev/sda2", Got: []string{"/dev/sda1"}},
+		{Kind: ViolationValue, Key: "selinux", Want: "1", Got: []string{"1", "0"}},
+	}, testSpec.Verify(k))
+}
+
+func TestKargsSpec_Apply(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1 console=tty0 quiet rd.neednet=1 rd_break selinux=1 selinux=0"))
+
+	assert.NoError(t, testSpec.Apply(k))
+	assert.Equal(t, "root=/dev/sda2 console=tty0 rd.neednet=1 selinux=1 console=ttyS0,115200", k.String())
+	assert.Empty(t, testSpec.Verify(k))
+
+	// Applying again changes nothing
+	assert.NoError(t, testSpec.Apply(k))
+	assert.Equal(t, "root=/dev/sda2 console=tty0 rd.neednet=1 selinux=1 console=ttyS0,115200", k.String())
+
+	// Values leave a single occurrence of accumulating keys.
+	spec := KargsSpec{Values: []Karg{{Key: "console", Value: "ttyS0"}}}
+	k = NewKargs([]byte("console=tty0 quiet"))
+	assert.NoError(t, spec.Apply(k))
+	assert.Equal(t, "console=ttyS0 quiet", k.String())
+	assert.Empty(t, spec.Verify(k))
+	k = NewKargs([]byte("console=tty0 quiet console=ttyS1"), WithSetPosition(SetMoveToEnd))
+	assert.NoError(t, spec.Apply(k))
+	assert.Equal(t, "quiet console=ttyS0", k.String())
+	assert.Empty(t, spec.Verify(k))
+
+	k = NewKargs([]byte("root=/dev/sda1"), WithQuoteMode(QuoteNever))
+	err := KargsSpec{Values: []Karg{{Key: "init", Value: "/sbin/init --verbose"}}}.Apply(k)
+	assert.ErrorIs(t, err, ErrUnquotable)
+	var keyErrs KeyErrors
+	assert.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, []string{"init"}, keyErrs.Keys())
+}
+
+func TestViolation_String(t *testing.T) {
+	assert.Equal(t, "missing: console=ttyS0 is missing", Violation{Kind: ViolationMissing, Key: "console", Want: "ttyS0"}.String())
+	assert.Equal(t, "forbidden: quiet is set", Violation{Kind: ViolationForbidden, Key: "quiet", Got: []string{""}}.String())
+	assert.Equal(t, "value: root must be /dev/sda2, is /dev/sda1", Violation{Kind: ViolationValue, Key: "root", Want: "/dev/sda2", Got: []string{"/dev/sda1"}}.String())
+	assert.Equal(t, "ViolationKind(42)", ViolationKind(42).String())
+}
@@ -0,0 +1,100 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import "strings"
+
+// Spelling selects how CanonicalizeInPlace spells the keys it rewrites.
+type Spelling int
+
+const (
+	// SpellingUnderscore spells keys with underscores, e.g. foo_bar.
+	SpellingUnderscore Spelling = iota
+
+	// SpellingHyphen spells keys with hyphens, e.g. foo-bar.
+	SpellingHyphen
+
+	// SpellingFirst spells every occurrence of a key like its first
+	// occurrence on the command line.
+	SpellingFirst
+)
+
+// SpellingConflict describes a parameter that appears with more than one
+// spelling of its key, e.g. foo-bar and foo_bar.
+type SpellingConflict struct {
+	CanonicalKey string   // Canonical key of the parameter
+	Spellings    []string // Distinct spellings, in command line order
+}
+
+// CanonicalizeInPlace rewrites the key of every karg to the chosen spelling,
+// keeping values and positions, and returns how many kargs were rewritten.
+// Since '-' and '_' are equivalent in keys, this does not change the meaning of
+// the command line.
+func (k *Kargs) CanonicalizeInPlace(spelling Spelling) int {
+	rewritten := 0
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		karg := &llTracker.karg
+		var newKey string
+		switch spelling {
+		case SpellingHyphen:
+			newKey = strings.Replace(karg.CanonicalKey, "_", "-", -1)
+		case SpellingFirst:
+			newKey = k.keyMap[karg.CanonicalKey][0].karg.Key
+		default:
+			newKey = karg.CanonicalKey
+		}
+		if newKey == karg.Key || !strings.HasPrefix(karg.Raw, karg.Key) {
+			continue
+		}
+		karg.Raw = newKey + karg.Raw[len(karg.Key):]
+		karg.Key = newKey
+		rewritten++
+	}
+	if rewritten > 0 {
+		k.invalidate()
+	}
+	return rewritten
+}
+
+// MixedSpellings reports every parameter whose key appears with more than one
+// spelling, in command line order. It returns nil if all keys are spelled
+// consistently.
+func (k *Kargs) MixedSpellings() []SpellingConflict {
+	var conflicts []SpellingConflict
+	seen := make(map[string]bool)
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		karg := llTracker.karg
+		if seen[karg.CanonicalKey] {
+			continue
+		}
+		seen[karg.CanonicalKey] = true
+		spellings := uniqueSpellings(k.keyMap[karg.CanonicalKey])
+		if len(spellings) < 2 {
+			continue
+		}
+		conflicts = append(conflicts, SpellingConflict{
+			CanonicalKey: karg.CanonicalKey,
+			Spellings:    spellings,
+		})
+	}
+	return conflicts
+}
+
+// uniqueSpellings returns the distinct keys of items in order.
+func uniqueSpellings(items []*kargItem) []string {
+	var spellings []string
+	for _, item := range items {
+		found := false
+		for _, s := range spellings {
+			if s == item.karg.Key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			spellings = append(spellings, item.karg.Key)
+		}
+	}
+	return spellings
+}
@@ -0,0 +1,47 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_CanonicalizeInPlace(t *testing.T) {
+	in := `foo-bar=1 baz foo_bar=2 mod.some-flag="a b" mod-x.y`
+	checks := []struct {
+		spelling  Spelling
+		rewritten int
+		want      string
+	}{
+		{spelling: SpellingUnderscore, rewritten: 3, want: `foo_bar=1 baz foo_bar=2 mod.some_flag="a b" mod_x.y`},
+		{spelling: SpellingHyphen, rewritten: 1, want: `foo-bar=1 baz foo-bar=2 mod.some-flag="a b" mod-x.y`},
+		{spelling: SpellingFirst, rewritten: 1, want: `foo-bar=1 baz foo-bar=2 mod.some-flag="a b" mod-x.y`},
+	}
+	for _, check := range checks {
+		k := NewKargs([]byte(in))
+		assert.Equal(t, check.rewritten, k.CanonicalizeInPlace(check.spelling))
+		assert.Equal(t, check.want, k.String())
+		assert.Empty(t, k.MixedSpellings())
+
+		// Values and lookups are unaffected
+		vals, _ := k.GetKarg("foo-bar")
+		assert.Equal(t, []string{"1", "2"}, vals)
+		opts, err := k.LoadModuleOptions("mod")
+		assert.NoError(t, err)
+		assert.Equal(t, `some_flag="a b"`, opts)
+	}
+}
+
+func TestKargs_MixedSpellings(t *testing.T) {
+	k := NewKargs([]byte("foo-bar=1 baz foo_bar=2 a_b a_b foo-bar=3 x-y x_y"))
+	assert.Equal(t, []SpellingConflict{
+		{CanonicalKey: "foo_bar", Spellings: []string{"foo-bar", "foo_bar"}},
+		{CanonicalKey: "x_y", Spellings: []string{"x-y", "x_y"}},
+	}, k.MixedSpellings())
+
+	k = NewKargs([]byte("foo-bar=1 foo-bar=2 baz"))
+	assert.Empty(t, k.MixedSpellings())
+}
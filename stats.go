@@ -0,0 +1,173 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Aggregator collects statistics about the use of parameters across many
+// command lines, e.g. those of a fleet read with ProcessCorpus. Its methods are
+// safe for concurrent use. Create it with NewAggregator.
+type Aggregator struct {
+	mu       sync.Mutex
+	cmdlines int
+	keys     map[string]int            // Number of command lines using each canonical key
+	occurs   map[string]int            // Number of occurrences of each canonical key
+	values   map[string]map[string]int // Number of occurrences of each value of each canonical key
+	modules  map[string]int            // Number of command lines setting parameters of each module
+}
+
+// NewAggregator returns an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		keys:    make(map[string]int),
+		occurs:  make(map[string]int),
+		values:  make(map[string]map[string]int),
+		modules: make(map[string]int),
+	}
+}
+
+// Add counts the parameters of k.
+func (a *Aggregator) Add(k *Kargs) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cmdlines++
+	for key, items := range k.keyMap {
+		if len(items) == 0 {
+			continue
+		}
+		a.keys[key]++
+		a.occurs[key] += len(items)
+		if a.values[key] == nil {
+			a.values[key] = make(map[string]int)
+		}
+		for _, item := range items {
+			a.values[key][item.karg.Value]++
+		}
+	}
+	for mod, items := range k.moduleMap {
+		if len(items) > 0 {
+			a.modules[mod]++
+		}
+	}
+}
+
+// CorpusStats holds the statistics collected by an Aggregator.
+type CorpusStats struct {
+	Cmdlines int          `json:"cmdlines"` // Number of command lines added
+	Keys     []KeyStats   `json:"keys"`     // Statistics per canonical key
+	Modules  []ModuleStat `json:"modules"`  // Statistics per module
+}
+
+// KeyStats holds the statistics of a canonical key.
+type KeyStats struct {
+	Key         string      `json:"key"`         // Canonical key
+	Cmdlines    int         `json:"cmdlines"`    // Number of command lines using the key
+	Occurrences int         `json:"occurrences"` // Number of occurrences of the key
+	Values      []ValueStat `json:"values"`      // Distribution of its dequoted values
+}
+
+// ValueStat is the number of occurrences of a value of a key.
+type ValueStat struct {
+	Value string `json:"value"` // Dequoted value, empty for none
+	Count int    `json:"count"` // Number of occurrences
+}
+
+// ModuleStat is the number of command lines setting parameters of a module.
+type ModuleStat struct {
+	Module   string `json:"module"`   // Canonical module name
+	Cmdlines int    `json:"cmdlines"` // Number of command lines
+}
+
+// Stats returns the statistics collected so far. Keys are ordered by the number
+// of command lines using them, most used first, values and modules likewise,
+// with ties broken by name.
+func (a *Aggregator) Stats() CorpusStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := CorpusStats{
+		Cmdlines: a.cmdlines,
+		Keys:     make([]KeyStats, 0, len(a.keys)),
+		Modules:  make([]ModuleStat, 0, len(a.modules)),
+	}
+	for key, cmdlines := range a.keys {
+		keyStats := KeyStats{Key: key, Cmdlines: cmdlines, Occurrences: a.occurs[key]}
+		for value, count := range a.values[key] {
+			keyStats.Values = append(keyStats.Values, ValueStat{Value: value, Count: count})
+		}
+		sort.Slice(keyStats.Values, func(i, j int) bool {
+			return byCount(keyStats.Values[i].Count, keyStats.Values[j].Count, keyStats.Values[i].Value, keyStats.Values[j].Value)
+		})
+		stats.Keys = append(stats.Keys, keyStats)
+	}
+	sort.Slice(stats.Keys, func(i, j int) bool {
+		return byCount(stats.Keys[i].Cmdlines, stats.Keys[j].Cmdlines, stats.Keys[i].Key, stats.Keys[j].Key)
+	})
+	for mod, cmdlines := range a.modules {
+		stats.Modules = append(stats.Modules, ModuleStat{Module: mod, Cmdlines: cmdlines})
+	}
+	sort.Slice(stats.Modules, func(i, j int) bool {
+		return byCount(stats.Modules[i].Cmdlines, stats.Modules[j].Cmdlines, stats.Modules[i].Module, stats.Modules[j].Module)
+	})
+	return stats
+}
+
+// byCount orders by descending count, then by ascending name.
+func byCount(countI, countJ int, nameI, nameJ string) bool {
+	if countI != countJ {
+		return countI > countJ
+	}
+	return nameI < nameJ
+}
+
+// WriteJSON writes s to w as a JSON object with the members cmdlines, keys, and
+// modules, named like the fields of the structs in CorpusStats.
+func (s CorpusStats) WriteJSON(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		return fmt.Errorf("writing statistics: %w", err)
+	}
+	return nil
+}
+
+// statsTableHeader holds the column names written by CorpusStats.WriteTable.
+var statsTableHeader = []string{"kind", "name", "value", "count"}
+
+// WriteTable writes s to w as a table in format, with a header row followed by
+// one row per key, value, and module, in the order of Stats. The columns are
+// the kind of row, i.e. key, value, or module; the canonical key or module
+// name; the dequoted value for value rows; and the count, which is the number
+// of command lines for key and module rows and the number of occurrences for
+// value rows. An error wrapping ErrUnsupported is returned for an unknown
+// format.
+func (s CorpusStats) WriteTable(w io.Writer, format TableFormat) error {
+	cw := csv.NewWriter(w)
+	switch format {
+	case TableCSV:
+	case TableTSV:
+		cw.Comma = '\t'
+	default:
+		return fmt.Errorf("writing statistics as %s: %w", format, ErrUnsupported)
+	}
+	rows := [][]string{statsTableHeader}
+	for _, keyStats := range s.Keys {
+		rows = append(rows, []string{"key", keyStats.Key, "", strconv.Itoa(keyStats.Cmdlines)})
+		for _, value := range keyStats.Values {
+			rows = append(rows, []string{"value", keyStats.Key, value.Value, strconv.Itoa(value.Count)})
+		}
+	}
+	for _, mod := range s.Modules {
+		rows = append(rows, []string{"module", mod.Module, "", strconv.Itoa(mod.Cmdlines)})
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("writing statistics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregator(t *testing.T) {
+	a := NewAggregator()
+	a.Add(NewKargs([]byte("root=/dev/sda1 console=tty0 console=ttyS0 quiet nvme_core.multipath=Y")))
+	a.Add(NewKargs([]byte("root=/dev/sda1 console=ttyS0 nvme-core.io_timeout=30")))
+	a.Add(NewKargs([]byte("root=/dev/sda2 quiet i915.modeset=0")))
+
+	stats := a.Stats()
+	assert.Equal(t, 3, stats.Cmdlines)
+	assert.Equal(t, []KeyStats{
+		{Key: "root", Cmdlines: 3, Occurrences: 3, Values: []ValueStat{{Value: "/dev/sda1", Count: 2}, {Value: "/dev/sda2", Count: 1}}},
+		{Key: "console", Cmdlines: 2, Occurrences: 3, Values: []ValueStat{{Value: "ttyS0", Count: 2}, {Value: "tty0", Count: 1}}},
+		{Key: "quiet", Cmdlines: 2, Occurrences: 2, Values: []ValueStat{{Value: "", Count: 2}}},
+		{Key: "i915.modeset", Cmdlines: 1, Occurrences: 1, Values: []ValueStat{{Value: "0", Count: 1}}},
+		{Key: "nvme_core.io_timeout", Cmdlines: 1, Occurrences: 1, Values: []ValueStat{{Value: "30", Count: 1}}},
+		{Key: "nvme_core.multipath", Cmdlines: 1, Occurrences: 1, Values: []ValueStat{{Value: "Y", Count: 1}}},
+	}, stats.Keys)
+	assert.Equal(t, []ModuleStat{{Module: "nvme_core", Cmdlines: 2}, {Module: "i915", Cmdlines: 1}}, stats.Modules)
+}
+
+func TestCorpusStats_WriteJSON(t *testing.T) {
+	a := NewAggregator()
+	a.Add(NewKargs([]byte("quiet usbcore.autosuspend=-1")))
+
+	var sb strings.Builder
+	assert.NoError(t, a.Stats().WriteJSON(&sb))
+	assert.JSONEq(t, `{
+		"cmdlines": 1,
+		"keys": [
+			{"key": "quiet", "cmdlines": 1, "occurrences": 1, "values": [{"value": "", "count": 1}]},
+			{"key": "usbcore.autosuspend", "cmdlines": 1, "occurrences": 1, "values": [{"value": "-1", "count": 1}]}
+		],
+		"modules": [{"module": "usbcore", "cmdlines": 1}]
+	}`, sb.String())
+}
+
+func TestCorpusStats_WriteTable(t *testing.T) {
+	a := NewAggregator()
+	a.Add(NewKargs([]byte(`console=tty0 msg="a, b" usbcore.autosuspend=-1`)))
+	a.Add(NewKargs([]byte("console=tty0")))
+
+	var sb strings.Builder
+	assert.NoError(t, a.Stats().WriteTable(&sb, TableCSV))
+	assert.Equal(t, `kind,name,value,count
+key,console,,2
+value,console,tty0,2
+key,msg,,1
+value,msg,"a, b",1
+key,usbcore.autosuspend,,1
+value,usbcore.autosuspend,-1,1
+module,usbcore,,1
+`, sb.String())
+
+	sb.Reset()
+	assert.NoError(t, NewAggregator().Stats().WriteTable(&sb, TableTSV))
+	assert.Equal(t, "kind\tname\tvalue\tcount\n", sb.String())
+
+	assert.ErrorIs(t, a.Stats().WriteTable(&sb, TableFormat(42)), ErrUnsupported)
+}
+
+func TestAggregator_corpus(t *testing.T) {
+	a := NewAggregator()
+	err := ProcessCorpus(strings.NewReader("quiet\nroot=/dev/sda1 quiet\n\nsplash\n"), func(_ int, k *Kargs) error {
+		a.Add(k)
+		return nil
+	})
+	assert.NoError(t, err)
+	stats := a.Stats()
+	assert.Equal(t, 3, stats.Cmdlines)
+	assert.Equal(t, "quiet", stats.Keys[0].Key)
+	assert.Equal(t, 2, stats.Keys[0].Cmdlines)
+}
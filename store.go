@@ -0,0 +1,162 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// NodeStore stores the kernel command line arguments of nodes, identified by an
+// arbitrary ID. Implementations store a copy of the Kargs they are given and
+// return a new Kargs on each lookup, so callers may modify both freely. Remote
+// implementations must honor the cancelation and deadline of ctx; if ctx is
+// done, they return an error wrapping ctx.Err().
+type NodeStore interface {
+	// LookupNode returns the kargs stored for the node identified by id.
+	// An error wrapping ErrNoNode is returned if there are none.
+	LookupNode(ctx context.Context, id string) (*Kargs, error)
+
+	// StoreNode stores k for the node identified by id, replacing the
+	// kargs stored for it before.
+	StoreNode(ctx context.Context, id string, k *Kargs) error
+}
+
+// MemoryStore is a NodeStore keeping the kargs of nodes in memory. The zero
+// value is an empty store ready to use. It is safe for concurrent use.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	nodes map[string]string // Node ID to command line
+}
+
+// LookupNode returns the kargs stored for the node identified by id.
+func (s *MemoryStore) LookupNode(ctx context.Context, id string) (*Kargs, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("looking up node %s: %w", id, err)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cmdline, exists := s.nodes[id]
+	if !exists {
+		return nil, fmt.Errorf("looking up node %s: %w", id, ErrNoNode)
+	}
+	return NewKargs([]byte(cmdline)), nil
+}
+
+// StoreNode stores k for the node identified by id.
+func (s *MemoryStore) StoreNode(ctx context.Context, id string, k *Kargs) error {
+	if k == nil {
+		return fmt.Errorf("storing node %s: %w", id, ErrNilPtr)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("storing node %s: %w", id, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nodes == nil {
+		s.nodes = make(map[string]string)
+	}
+	s.nodes[id] = k.String()
+	return nil
+}
+
+// JSONFileStore is a NodeStore keeping the kargs of nodes in a JSON file, which
+// holds an object mapping node IDs to command line strings. The file is read on
+// every lookup and rewritten atomically on every store, so it can be inspected
+// and edited by hand. A missing file is treated as an empty store. A
+// JSONFileStore is safe for concurrent use within one process, but not across
+// processes.
+type JSONFileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewJSONFileStore returns a JSONFileStore backed by the file at path.
+func NewJSONFileStore(path string) *JSONFileStore {
+	return &JSONFileStore{path: path}
+}
+
+// LookupNode returns the kargs stored for the node identified by id.
+func (s *JSONFileStore) LookupNode(ctx context.Context, id string) (*Kargs, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("looking up node %s: %w", id, err)
+	}
+	nodes, err := s.read()
+	if err != nil {
+		return nil, fmt.Errorf("looking up node %s: %w", id, err)
+	}
+	cmdline, exists := nodes[id]
+	if !exists {
+		return nil, fmt.Errorf("looking up node %s: %w", id, ErrNoNode)
+	}
+	return NewKargs([]byte(cmdline)), nil
+}
+
+// StoreNode stores k for the node identified by id.
+func (s *JSONFileStore) StoreNode(ctx context.Context, id string, k *Kargs) error {
+	if k == nil {
+		return fmt.Errorf("storing node %s: %w", id, ErrNilPtr)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("storing node %s: %w", id, err)
+	}
+	nodes, err := s.read()
+	if err != nil {
+		return fmt.Errorf("storing node %s: %w", id, err)
+	}
+	nodes[id] = k.String()
+	if err := s.write(nodes); err != nil {
+		return fmt.Errorf("storing node %s: %w", id, err)
+	}
+	return nil
+}
+
+// read returns the contents of the store file, which are empty if it doesn't
+// exist.
+func (s *JSONFileStore) read() (map[string]string, error) {
+	nodes := make(map[string]string)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nodes, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to decode store %s: %w", s.path, err)
+	}
+	return nodes, nil
+}
+
+// write replaces the contents of the store file with nodes by writing them to a
+// temporary file in the same directory and renaming it over the store file.
+func (s *JSONFileStore) write(nodes map[string]string) error {
+	data, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testNodeStore runs the checks every NodeStore must pass against s.
+func testNodeStore(t *testing.T, s NodeStore) {
+	ctx := context.Background()
+	_, err := s.LookupNode(ctx, "node1")
+	assert.ErrorIs(t, err, ErrNoNode)
+
+	k := NewKargs([]byte("root=/dev/sda1 console=ttyS0"))
+	err = s.StoreNode(ctx, "node1", k)
+	assert.NoError(t, err)
+
+	// The store keeps a copy
+	err = k.SetKarg("quiet", "")
+	assert.NoError(t, err)
+	stored, err := s.LookupNode(ctx, "node1")
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda1 console=ttyS0", stored.String())
+
+	// Lookups return a new Kargs each time
+	err = stored.DeleteKarg("root")
+	assert.NoError(t, err)
+	stored, err = s.LookupNode(ctx, "node1")
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda1 console=ttyS0", stored.String())
+
+	// Storing replaces the previous kargs
+	err = s.StoreNode(ctx, "node1", k)
+	assert.NoError(t, err)
+	err = s.StoreNode(ctx, "node2", NewKargs([]byte("nomodeset")))
+	assert.NoError(t, err)
+	stored, err = s.LookupNode(ctx, "node1")
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda1 console=ttyS0 quiet", stored.String())
+	stored, err = s.LookupNode(ctx, "node2")
+	assert.NoError(t, err)
+	assert.Equal(t, "nomodeset", stored.String())
+
+	err = s.StoreNode(ctx, "node3", nil)
+	assert.ErrorIs(t, err, ErrNilPtr)
+
+	// Done contexts are honored
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.LookupNode(canceled, "node1")
+	assert.ErrorIs(t, err, context.Canceled)
+	err = s.StoreNode(canceled, "node1", NewKargsEmpty())
+	assert.ErrorIs(t, err, context.Canceled)
+	stored, err = s.LookupNode(ctx, "node1")
+	assert.NoError(t, err)
+	assert.Equal(t, "root=/dev/sda1 console=ttyS0 quiet", stored.String())
+}
+
+func TestMemoryStore(t *testing.T) {
+	testNodeStore(t, &MemoryStore{})
+}
+
+func TestJSONFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	testNodeStore(t, NewJSONFileStore(path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"node1": "root=/dev/sda1 console=ttyS0 quiet", "node2": "nomodeset"}`, string(data))
+
+	// Only the store file is left behind
+	files, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestJSONFileStore_invalid(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	err := os.WriteFile(path, []byte("not json"), 0644)
+	assert.NoError(t, err)
+
+	s := NewJSONFileStore(path)
+	_, err = s.LookupNode(ctx, "node1")
+	assert.Error(t, err)
+	err = s.StoreNode(ctx, "node1", NewKargsEmpty())
+	assert.Error(t, err)
+}
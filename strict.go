@@ -0,0 +1,126 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseIssue is a problem found in a command line by ParseStrictAll.
+type ParseIssue struct {
+	Raw   string // Karg the problem was found in
+	Start int    // Byte offset of the start of Raw in the command line
+	End   int    // Byte offset just past the end of Raw in the command line
+	Err   error  // Description of the problem
+}
+
+func (i ParseIssue) Error() string {
+	return fmt.Sprintf("offset %d: %s: %v", i.Start, i.Raw, i.Err)
+}
+
+// Unwrap returns the description of the problem.
+func (i ParseIssue) Unwrap() error {
+	return i.Err
+}
+
+// Message returns the problem as a Message, to be rendered by a Catalog.
+func (i ParseIssue) Message() Message {
+	return errorMessage(i.Err)
+}
+
+// Code returns the stable code of the problem, or "" if it has none.
+func (i ParseIssue) Code() Code {
+	return i.Message().ID.Code()
+}
+
+// ParseStrictAll parses line like NewKargs, but also checks each karg for
+// problems and returns all of them in order, instead of stopping at the first
+// one or ignoring them. Since the problems don't keep the kernel from booting,
+// kargs with problems are still part of the returned Kargs, as the kernel sees
+// them. The problems found are:
+//
+//   - quotes that are not terminated, wrapping ErrUnterminatedQuote
+//   - empty keys, as in "=value", and keys starting with a quote, which this
+//     package can't address, wrapping ErrInvalidKey
+//   - control characters and invalid UTF-8, wrapping ErrInvalidEncoding
+//   - kargs the kernel reads differently than this package, as told by
+//     SplitLikeKernel, wrapping ErrKernelMismatch; e.g. single quotes or
+//     escapes, which the kernel doesn't understand
+func ParseStrictAll(line []byte) (*Kargs, []ParseIssue) {
+	k := NewKargsEmpty()
+	var issues []ParseIssue
+	Tokenize(string(line), func(t Token) error {
+		for _, err := range checkToken(t) {
+			issues = append(issues, ParseIssue{Raw: t.Raw, Start: t.Start, End: t.End, Err: err})
+		}
+		k.appendItem(Karg{
+			CanonicalKey: t.CanonicalKey,
+			Key:          t.Key,
+			Raw:          t.Raw,
+			Value:        t.Value,
+		})
+		return nil
+	})
+	return k, issues
+}
+
+// checkToken returns the problems of t described in ParseStrictAll.
+func checkToken(t Token) []error {
+	var errs []error
+	report := func(id MessageID, err error, args ...string) {
+		msg := Message{ID: id, Args: map[string]string{"raw": t.Raw}}
+		for i := 0; i+1 < len(args); i += 2 {
+			msg.Args[args[i]] = args[i+1]
+		}
+		errs = append(errs, &messageError{msg: msg, err: err})
+	}
+	if hasOpenQuote(t.Raw) {
+		report(MsgUnterminatedQuote, fmt.Errorf("checking quotes: %w", ErrUnterminatedQuote))
+	}
+	switch {
+	case t.Key == "":
+		report(MsgEmptyKey, fmt.Errorf("empty key: %w", ErrInvalidKey))
+	case t.Key[0] == '"' || t.Key[0] == '\'':
+		report(MsgQuotedKey, fmt.Errorf("quoted key %s: %w", t.Key, ErrInvalidKey), "key", t.Key)
+	}
+	if !utf8.ValidString(t.Raw) {
+		report(MsgInvalidUTF8, fmt.Errorf("invalid UTF-8: %w", ErrInvalidEncoding))
+	}
+	for _, c := range t.Raw {
+		if unicode.IsControl(c) {
+			char := fmt.Sprintf("%U", c)
+			report(MsgControlCharacter, fmt.Errorf("control character %s: %w", char, ErrInvalidEncoding), "char", char)
+			break
+		}
+	}
+	want := t.Key
+	if t.HasValue {
+		want += "=" + t.Value
+	}
+	if kernel := SplitLikeKernel(t.Raw); len(kernel) != 1 || kernel[0] != want {
+		report(MsgKernelMismatch, fmt.Errorf("kernel reads %q instead of %q: %w", kernel, want, ErrKernelMismatch),
+			"kernel", strings.Join(kernel, " "), "want", want)
+	}
+	return errs
+}
+
+// hasOpenQuote returns whether a quote opened in raw is not closed by its end,
+// following the rules of Tokenize.
+func hasOpenQuote(raw string) bool {
+	lastQuote := rune(0)
+	for _, c := range raw {
+		switch {
+		case lastQuote != 0:
+			if c == lastQuote {
+				lastQuote = 0
+			}
+		case unicode.In(c, unicode.Quotation_Mark):
+			lastQuote = c
+		}
+	}
+	return lastQuote != 0
+}
@@ -0,0 +1,53 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStrictAll(t *testing.T) {
+	k, issues := ParseStrictAll([]byte(`root=/dev/sda1 =orphan "quoted=key" ok="x y" bad=` + "\x01\xff" + ` msg="unterminated end`))
+
+	assert.Equal(t, 6, k.Len())
+	if assert.Len(t, issues, 6) {
+		assert.Equal(t, "=orphan", issues[0].Raw)
+		assert.Equal(t, 15, issues[0].Start)
+		assert.ErrorIs(t, issues[0], ErrInvalidKey)
+
+		assert.Equal(t, `"quoted=key"`, issues[1].Raw)
+		assert.ErrorIs(t, issues[1], ErrInvalidKey)
+		assert.Equal(t, `"quoted=key"`, issues[2].Raw)
+		assert.ErrorIs(t, issues[2], ErrKernelMismatch)
+
+		assert.Equal(t, "bad=\x01\xff", issues[3].Raw)
+		assert.ErrorIs(t, issues[3], ErrInvalidEncoding)
+		assert.ErrorIs(t, issues[4], ErrInvalidEncoding)
+		assert.Equal(t, issues[3].Start, issues[4].Start)
+
+		assert.Equal(t, `msg="unterminated end`, issues[5].Raw)
+		assert.Equal(t, len(k.String()), issues[5].End)
+		assert.ErrorIs(t, issues[5], ErrUnterminatedQuote)
+		assert.Equal(t, `offset 52: msg="unterminated end: checking quotes: quote is not terminated`, issues[5].Error())
+	}
+
+	k, issues = ParseStrictAll([]byte(`root=/dev/sda1 msg="a b" quiet`))
+	assert.Empty(t, issues)
+	assert.Equal(t, `root=/dev/sda1 msg="a b" quiet`, k.String())
+}
+
+func TestParseStrictAll_kernelMismatch(t *testing.T) {
+	_, issues := ParseStrictAll([]byte(`single='a b' plain="a b"`))
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, `single='a b'`, issues[0].Raw)
+		assert.ErrorIs(t, issues[0], ErrKernelMismatch)
+	}
+
+	_, issues = ParseStrictAll([]byte(`value="a\"`))
+	if assert.Len(t, issues, 1) {
+		assert.ErrorIs(t, issues[0], ErrKernelMismatch)
+	}
+}
@@ -0,0 +1,68 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// TableFormat selects the format written by ToTable.
+type TableFormat int
+
+const (
+	// TableCSV writes comma-separated values as described in RFC 4180.
+	TableCSV TableFormat = iota
+
+	// TableTSV writes tab-separated values, quoting fields like TableCSV.
+	TableTSV
+)
+
+// String returns the name of format.
+func (format TableFormat) String() string {
+	switch format {
+	case TableCSV:
+		return "csv"
+	case TableTSV:
+		return "tsv"
+	default:
+		return fmt.Sprintf("TableFormat(%d)", int(format))
+	}
+}
+
+// tableHeader holds the column names written by ToTable.
+var tableHeader = []string{"key", "value", "raw", "module", "provenance"}
+
+// ToTable writes k to w as a table in format, with a header row followed by one
+// row per karg in command line order. The columns are the key as written, the
+// dequoted value, the raw karg, for module parameters, the canonical module
+// name (see Classify), and the provenance of the karg, if known (see
+// Provenance). An error wrapping ErrUnsupported is returned for an unknown
+// format.
+func (k *Kargs) ToTable(w io.Writer, format TableFormat) error {
+	cw := csv.NewWriter(w)
+	switch format {
+	case TableCSV:
+	case TableTSV:
+		cw.Comma = '\t'
+	default:
+		return fmt.Errorf("writing table as %s: %w", format, ErrUnsupported)
+	}
+	if err := cw.Write(tableHeader); err != nil {
+		return fmt.Errorf("writing table: %w", err)
+	}
+	for llTracker := k.list; llTracker != nil; llTracker = llTracker.next {
+		karg := llTracker.karg
+		row := []string{karg.Key, karg.Value, karg.Raw, Classify(karg).Module, llTracker.source}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("writing table: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing table: %w", err)
+	}
+	return nil
+}
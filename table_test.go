@@ -0,0 +1,42 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_ToTable(t *testing.T) {
+	k := NewKargs([]byte(`root=/dev/sda1 usb-core.autosuspend=-1 msg="a, b" quiet`))
+	k.MergeDHCP([]byte("nfsroot=10.0.0.1:/export"), func(Karg) bool { return true })
+
+	var buf bytes.Buffer
+	assert.NoError(t, k.ToTable(&buf, TableCSV))
+	assert.Equal(t, "key,value,raw,module,provenance\n"+
+		"root,/dev/sda1,root=/dev/sda1,,\n"+
+		"usb-core.autosuspend,-1,usb-core.autosuspend=-1,usb_core,\n"+
+		`msg,"a, b","msg=""a, b""",,`+"\n"+
+		"quiet,,quiet,,\n"+
+		"nfsroot,10.0.0.1:/export,nfsroot=10.0.0.1:/export,,dhcp\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, k.ToTable(&buf, TableTSV))
+	assert.Equal(t, "key\tvalue\traw\tmodule\tprovenance\n"+
+		"root\t/dev/sda1\troot=/dev/sda1\t\t\n"+
+		"usb-core.autosuspend\t-1\tusb-core.autosuspend=-1\tusb_core\t\n"+
+		"msg\ta, b\t\"msg=\"\"a, b\"\"\"\t\t\n"+
+		"quiet\t\tquiet\t\t\n"+
+		"nfsroot\t10.0.0.1:/export\tnfsroot=10.0.0.1:/export\t\tdhcp\n", buf.String())
+
+	assert.ErrorIs(t, k.ToTable(&buf, TableFormat(42)), ErrUnsupported)
+}
+
+func TestTableFormat_String(t *testing.T) {
+	assert.Equal(t, "csv", TableCSV.String())
+	assert.Equal(t, "tsv", TableTSV.String())
+	assert.Equal(t, "TableFormat(42)", TableFormat(42).String())
+}
@@ -0,0 +1,132 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a single parameter of a command line as found by Tokenize.
+type Token struct {
+	Raw          string // Token as it appears in the input
+	Key          string // Part of Raw left of the first =
+	CanonicalKey string // Key with hyphens turned into underscores
+	RawValue     string // Part of Raw right of the first =, as it appears in the input
+	Value        string // RawValue with its quotes removed
+	HasValue     bool   // Whether Raw contains an =
+	Start        int    // Byte offset of the start of Raw in the input
+	End          int    // Byte offset just past the end of Raw in the input
+}
+
+// Tokenize splits input into parameters the same way NewKargs does and calls fn
+// with each of them, in order. Parameters are separated by whitespace, except
+// inside quotes, so that quoted values may contain spaces.
+//
+// If fn returns an error, tokenizing stops and Tokenize returns that error
+// unchanged, so callers may use their own sentinel error to stop early.
+func Tokenize(input string, fn func(Token) error) error {
+	lastQuote := rune(0)
+	start := -1
+	for i := 0; i <= len(input); {
+		c, size := utf8.RuneError, 0
+		isSep := true
+		if i < len(input) {
+			c, size = utf8.DecodeRuneInString(input[i:])
+			switch {
+			case c == lastQuote:
+				lastQuote = rune(0)
+				isSep = false
+			case lastQuote != rune(0):
+				isSep = false
+			case unicode.In(c, unicode.Quotation_Mark):
+				lastQuote = c
+				isSep = false
+			default:
+				isSep = unicode.IsSpace(c)
+			}
+		} else {
+			// End of input terminates the last token
+			size = 1
+		}
+
+		if !isSep && start < 0 {
+			start = i
+		} else if isSep && start >= 0 {
+			if err := fn(newToken(input, start, i)); err != nil {
+				return err
+			}
+			start = -1
+		}
+		i += size
+	}
+	return nil
+}
+
+// tokenizeASCII is like Tokenize, but only treats ASCII characters as
+// whitespace and quotes, i.e. spaces, tabs, newlines, carriage returns,
+// vertical tabs, and form feeds, and single and double quotes. All other bytes
+// are part of tokens. It avoids decoding UTF-8 and consulting Unicode tables,
+// and gives the same result as Tokenize for input that is pure ASCII.
+func tokenizeASCII(input string, fn func(Token) error) error {
+	return scanASCII(input, func(start, end int) error {
+		return fn(newToken(input, start, end))
+	})
+}
+
+// scanASCII splits input like tokenizeASCII and calls fn with the byte offsets
+// of each token. It works on strings and byte slices alike, so that a byte
+// slice can be scanned without converting it.
+func scanASCII[T string | []byte](input T, fn func(start, end int) error) error {
+	var lastQuote byte
+	start := -1
+	for i := 0; i <= len(input); i++ {
+		isSep := true
+		if i < len(input) {
+			switch c := input[i]; {
+			case c == lastQuote:
+				lastQuote = 0
+				isSep = false
+			case lastQuote != 0:
+				isSep = false
+			case c == '"' || c == '\'':
+				lastQuote = c
+				isSep = false
+			default:
+				isSep = c == ' ' || (c >= '\t' && c <= '\r')
+			}
+		}
+
+		if !isSep && start < 0 {
+			start = i
+		} else if isSep && start >= 0 {
+			if err := fn(start, i); err != nil {
+				return err
+			}
+			start = -1
+		}
+	}
+	return nil
+}
+
+// newToken returns the Token found between the byte offsets start and end of
+// input.
+func newToken(input string, start, end int) Token {
+	raw := input[start:end]
+	t := Token{
+		Raw:   raw,
+		Key:   raw,
+		Start: start,
+		End:   end,
+	}
+	if split := strings.Index(raw, "="); split != -1 {
+		t.Key = raw[:split]
+		t.RawValue = raw[split+1:]
+		t.HasValue = true
+	}
+	t.CanonicalKey = canonicalizeKey(t.Key)
+	t.Value = dequote(t.RawValue)
+	return t
+}
@@ -0,0 +1,160 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	in := `  noval key=val  with-dashes="quoted value" key= "key quotes"`
+	expTokens := []Token{
+		{Raw: "noval", Key: "noval", CanonicalKey: "noval", Start: 2, End: 7},
+		{Raw: "key=val", Key: "key", CanonicalKey: "key", RawValue: "val", Value: "val", HasValue: true, Start: 8, End: 15},
+		{Raw: `with-dashes="quoted value"`, Key: "with-dashes", CanonicalKey: "with_dashes", RawValue: `"quoted value"`, Value: "quoted value", HasValue: true, Start: 17, End: 43},
+		{Raw: "key=", Key: "key", CanonicalKey: "key", HasValue: true, Start: 44, End: 48},
+		{Raw: `"key quotes"`, Key: `"key quotes"`, CanonicalKey: `"key quotes"`, Start: 49, End: 61},
+	}
+	var tokens []Token
+	err := Tokenize(in, func(tok Token) error {
+		tokens = append(tokens, tok)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expTokens, tokens)
+	for _, tok := range tokens {
+		assert.Equal(t, tok.Raw, in[tok.Start:tok.End])
+	}
+}
+
+func TestTokenize_abort(t *testing.T) {
+	errStop := errors.New("stop")
+	var keys []string
+	err := Tokenize("key1 key2 key3", func(tok Token) error {
+		keys = append(keys, tok.Key)
+		if tok.Key == "key2" {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, []string{"key1", "key2"}, keys)
+}
+
+func TestTokenize_empty(t *testing.T) {
+	for _, in := range []string{"", " ", "\t\n "} {
+		err := Tokenize(in, func(tok Token) error {
+			t.Errorf("unexpected token %q", tok.Raw)
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+}
+
+// fieldsTokenize is the reference implementation of the splitting done by
+// Tokenize, based on strings.FieldsFunc.
+func fieldsTokenize(input string) []string {
+	lastQuote := rune(0)
+	return strings.FieldsFunc(input, func(c rune) bool {
+		switch {
+		case c == lastQuote:
+			lastQuote = rune(0)
+			return false
+		case lastQuote != rune(0):
+			return false
+		case unicode.In(c, unicode.Quotation_Mark):
+			lastQuote = c
+			return false
+		default:
+			return unicode.IsSpace(c)
+		}
+	})
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range []string{``, `a b`, `a="b c" d`, `"unterminated a b`, "tab\tsep\nline", "\xff\xfe bad", `«a b» c`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		var raws []string
+		err := Tokenize(in, func(tok Token) error {
+			assert.Equal(t, tok.Raw, in[tok.Start:tok.End])
+			raws = append(raws, tok.Raw)
+			return nil
+		})
+		assert.NoError(t, err)
+		want := fieldsTokenize(in)
+		if len(want) == 0 {
+			assert.Empty(t, raws)
+		} else {
+			assert.Equal(t, want, raws)
+		}
+	})
+}
+
+func TestTokenizeASCII(t *testing.T) {
+	collect := func(tokenize func(string, func(Token) error) error, in string) []Token {
+		var toks []Token
+		assert.NoError(t, tokenize(in, func(tok Token) error {
+			toks = append(toks, tok)
+			return nil
+		}))
+		return toks
+	}
+
+	for _, in := range []string{``, `a b`, `a="b c"  d='e f'`, "tab\tsep\nline\r\v\fend", `"unterminated a b`, `key="x y"z w`} {
+		assert.Equal(t, collect(Tokenize, in), collect(tokenizeASCII, in), in)
+	}
+
+	// Non-ASCII whitespace and quotes are not special.
+	toks := collect(tokenizeASCII, "a b «c d»")
+	if assert.Len(t, toks, 3) {
+		assert.Equal(t, "a b", toks[0].Raw)
+		assert.Equal(t, "«c", toks[1].Raw)
+		assert.Equal(t, "d»", toks[2].Raw)
+	}
+	assert.Len(t, collect(Tokenize, "a b «c d»"), 3)
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Tokenize(benchCmdline, func(Token) error { return nil })
+	}
+}
+
+func BenchmarkTokenizeASCII(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tokenizeASCII(benchCmdline, func(Token) error { return nil })
+	}
+}
+
+func FuzzTokenizeASCII(f *testing.F) {
+	for _, seed := range []string{``, `a b`, `a="b c" d`, `"unterminated a b`, "tab\tsep\nline"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		for i := 0; i < len(in); i++ {
+			if in[i] >= 0x80 {
+				t.Skip("not ASCII")
+			}
+		}
+		var want, got []Token
+		Tokenize(in, func(tok Token) error {
+			want = append(want, tok)
+			return nil
+		})
+		tokenizeASCII(in, func(tok Token) error {
+			got = append(got, tok)
+			return nil
+		})
+		assert.Equal(t, want, got)
+	})
+}
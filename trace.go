@@ -0,0 +1,120 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TraceKind is the kind of a TraceEvent.
+type TraceKind int
+
+const (
+	// TraceToken reports a parameter split from the input. Offset and Token
+	// give its position and raw form.
+	TraceToken TraceKind = iota
+
+	// TraceQuoteOpen reports a quote that starts a quoted section, inside
+	// which whitespace does not split parameters. Detail holds the quote.
+	TraceQuoteOpen
+
+	// TraceQuoteClose reports a quote that ends a quoted section. Detail holds
+	// the quote.
+	TraceQuoteClose
+
+	// TraceUnterminatedQuote reports a quoted section that is still open at
+	// the end of the input. Offset is that of the opening quote, Detail holds
+	// the quote.
+	TraceUnterminatedQuote
+
+	// TraceCanonicalize reports a key whose canonical form differs from it.
+	// Detail holds the canonical key.
+	TraceCanonicalize
+
+	// TraceDequote reports a value whose quotes were removed. Offset is that of
+	// the value, Detail holds the dequoted value.
+	TraceDequote
+)
+
+// String returns the name of kind.
+func (kind TraceKind) String() string {
+	switch kind {
+	case TraceToken:
+		return "token"
+	case TraceQuoteOpen:
+		return "quote-open"
+	case TraceQuoteClose:
+		return "quote-close"
+	case TraceUnterminatedQuote:
+		return "unterminated-quote"
+	case TraceCanonicalize:
+		return "canonicalize"
+	case TraceDequote:
+		return "dequote"
+	default:
+		return fmt.Sprintf("TraceKind(%d)", int(kind))
+	}
+}
+
+// TraceEvent is a decision of the parser reported to the function set with
+// WithTrace.
+type TraceEvent struct {
+	Kind   TraceKind // Kind of decision
+	Offset int       // Byte offset in the input the decision was made at
+	Token  string    // Raw parameter the decision belongs to
+	Detail string    // Additional information depending on Kind
+}
+
+// String returns a description of e for debug output.
+func (e TraceEvent) String() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d: %s %q", e.Offset, e.Kind, e.Token)
+	}
+	return fmt.Sprintf("%d: %s %q: %s", e.Offset, e.Kind, e.Token, e.Detail)
+}
+
+// trace reports the decisions made while parsing the token t to the trace
+// function of cfg. The quote decisions are found by scanning t again with the
+// rules of the tokenizer selected by cfg, which starts every token outside of
+// quotes.
+func (cfg parseConfig) trace(t Token, value string) {
+	cfg.traceFn(TraceEvent{Kind: TraceToken, Offset: t.Start, Token: t.Raw})
+	var open rune
+	openAt := 0
+	for i := 0; i < len(t.Raw); {
+		c, size := rune(t.Raw[i]), 1
+		if !cfg.ascii {
+			c, size = utf8.DecodeRuneInString(t.Raw[i:])
+		}
+		switch {
+		case open != 0 && c == open:
+			cfg.traceFn(TraceEvent{Kind: TraceQuoteClose, Offset: t.Start + i, Token: t.Raw, Detail: string(c)})
+			open = 0
+		case open == 0 && cfg.isQuote(c):
+			cfg.traceFn(TraceEvent{Kind: TraceQuoteOpen, Offset: t.Start + i, Token: t.Raw, Detail: string(c)})
+			open, openAt = c, i
+		}
+		i += size
+	}
+	if open != 0 {
+		cfg.traceFn(TraceEvent{Kind: TraceUnterminatedQuote, Offset: t.Start + openAt, Token: t.Raw, Detail: string(open)})
+	}
+	if t.CanonicalKey != t.Key {
+		cfg.traceFn(TraceEvent{Kind: TraceCanonicalize, Offset: t.Start, Token: t.Raw, Detail: t.CanonicalKey})
+	}
+	if value != t.RawValue {
+		cfg.traceFn(TraceEvent{Kind: TraceDequote, Offset: t.Start + len(t.Key) + 1, Token: t.Raw, Detail: value})
+	}
+}
+
+// isQuote returns whether c opens a quoted section for the tokenizer selected
+// by cfg.
+func (cfg parseConfig) isQuote(c rune) bool {
+	if cfg.ascii {
+		return c == '"' || c == '\''
+	}
+	return unicode.In(c, unicode.Quotation_Mark)
+}
@@ -0,0 +1,53 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTrace(t *testing.T) {
+	var events []string
+	trace := WithTrace(func(e TraceEvent) {
+		events = append(events, e.String())
+	})
+
+	k := NewKargs([]byte(`rd-break init="/sbin/init --verbose" msg='unterminated`), trace)
+	assert.Equal(t, 3, k.Len())
+	assert.Equal(t, []string{
+		`0: token "rd-break"`,
+		`0: canonicalize "rd-break": rd_break`,
+		`9: token "init=\"/sbin/init --verbose\""`,
+		`14: quote-open "init=\"/sbin/init --verbose\"": "`,
+		`35: quote-close "init=\"/sbin/init --verbose\"": "`,
+		`14: dequote "init=\"/sbin/init --verbose\"": /sbin/init --verbose`,
+		`37: token "msg='unterminated"`,
+		`41: quote-open "msg='unterminated": '`,
+		`41: unterminated-quote "msg='unterminated": '`,
+		`41: dequote "msg='unterminated": unterminated`,
+	}, events)
+}
+
+func TestWithTrace_ascii(t *testing.T) {
+	var kinds []TraceKind
+	trace := WithTrace(func(e TraceEvent) {
+		kinds = append(kinds, e.Kind)
+	})
+
+	// Typographic quotes only close with the same character
+	NewKargs([]byte("msg=“a b”"), trace)
+	assert.Equal(t, []TraceKind{TraceToken, TraceQuoteOpen, TraceUnterminatedQuote}, kinds)
+
+	kinds = nil
+	NewKargs([]byte("msg=“a b”"), trace, WithASCII())
+	assert.Equal(t, []TraceKind{TraceToken, TraceToken}, kinds)
+}
+
+func TestTraceKind_String(t *testing.T) {
+	assert.Equal(t, "quote-open", TraceQuoteOpen.String())
+	assert.Equal(t, "TraceKind(42)", fmt.Sprint(TraceKind(42)))
+}
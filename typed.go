@@ -0,0 +1,178 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// lastValue returns the value of the last occurrence of key, which is the one
+// the kernel uses, and whether key is set.
+func (k *Kargs) lastValue(key string) (string, bool) {
+	items := k.keyMap[canonicalizeKey(key)]
+	if len(items) == 0 {
+		return "", false
+	}
+	return items[len(items)-1].karg.Value, true
+}
+
+// GetKargBool returns the value of the karg identified by key interpreted as a
+// boolean the way the kernel does, as well as whether it was set. If key occurs
+// more than once, the last occurrence is used, like the kernel does. A key
+// without a value is true. Otherwise, like kstrtobool, only the start of the
+// value is looked at: y, Y, t, T, 1, and on are true; n, N, f, F, 0, and off are
+// false, so e.g. yes and no work as well. For any other value, an error
+// wrapping ErrInvalidValue is returned.
+func (k *Kargs) GetKargBool(key string) (value, present bool, err error) {
+	raw, present := k.lastValue(key)
+	if !present {
+		return false, false, nil
+	}
+	if value, err = parseKernelBool(raw); err != nil {
+		return false, true, fmt.Errorf("parsing value %s of key %s as boolean: %w", raw, key, ErrInvalidValue)
+	}
+	return value, true, nil
+}
+
+// parseKernelBool parses raw, a value or "" for a key without a value, as a
+// boolean as described in GetKargBool.
+func parseKernelBool(raw string) (bool, error) {
+	if raw == "" {
+		return true, nil
+	}
+	switch raw[0] {
+	case 'y', 'Y', 't', 'T', '1':
+		return true, nil
+	case 'n', 'N', 'f', 'F', '0':
+		return false, nil
+	case 'o', 'O':
+		if len(raw) > 1 {
+			switch raw[1] {
+			case 'n', 'N':
+				return true, nil
+			case 'f', 'F':
+				return false, nil
+			}
+		}
+	}
+	return false, strconv.ErrSyntax
+}
+
+// GetKargInt returns the value of the last occurrence of the karg identified by
+// key parsed as a signed integer the way the kernel's kstrtoll does with base 0:
+// an optional sign, followed by a hexadecimal number with a 0x prefix, an octal
+// number with a leading 0, or a decimal number. An error wrapping ErrNotExists
+// is returned if key is not set, and one wrapping ErrInvalidValue if its value
+// is not such a number or out of range.
+func (k *Kargs) GetKargInt(key string) (int64, error) {
+	raw, present := k.lastValue(key)
+	if !present {
+		return 0, fmt.Errorf("failed to get key %s: %w", key, ErrNotExists)
+	}
+	n, err := parseKernelInt(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parsing value %s of key %s as integer: %v: %w", raw, key, err, ErrInvalidValue)
+	}
+	return n, nil
+}
+
+// parseKernelInt parses raw as a signed integer as described in GetKargInt.
+func parseKernelInt(raw string) (int64, error) {
+	digits, negative := raw, false
+	if raw != "" && (raw[0] == '+' || raw[0] == '-') {
+		digits, negative = raw[1:], raw[0] == '-'
+	}
+	magnitude, err := parseKernelUint(digits)
+	switch {
+	case err != nil:
+		return 0, err
+	case negative && magnitude <= 1<<63:
+		return -int64(magnitude), nil
+	case !negative && magnitude < 1<<63:
+		return int64(magnitude), nil
+	}
+	return 0, strconv.ErrRange
+}
+
+// GetKargUint is like GetKargInt, but parses the value as an unsigned integer
+// like kstrtoull, which allows a leading + but no -.
+func (k *Kargs) GetKargUint(key string) (uint64, error) {
+	raw, present := k.lastValue(key)
+	if !present {
+		return 0, fmt.Errorf("failed to get key %s: %w", key, ErrNotExists)
+	}
+	n, err := parseKernelUint(strings.TrimPrefix(raw, "+"))
+	if err != nil {
+		return 0, fmt.Errorf("parsing value %s of key %s as unsigned integer: %v: %w", raw, key, err, ErrInvalidValue)
+	}
+	return n, nil
+}
+
+// parseKernelUint parses s as an unsigned number without sign, in base 16 if it
+// starts with 0x or 0X, in base 8 if it starts with 0, and in base 10
+// otherwise.
+func parseKernelUint(s string) (uint64, error) {
+	base := 10
+	switch {
+	case len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X'):
+		s, base = s[2:], 16
+	case len(s) > 1 && s[0] == '0':
+		s, base = s[1:], 8
+	}
+	// strconv accepts signs and underscores in some cases, which the kernel
+	// does not.
+	if s == "" || strings.ContainsAny(s, "+-_") {
+		return 0, strconv.ErrSyntax
+	}
+	n, err := strconv.ParseUint(s, base, 64)
+	if err != nil {
+		return 0, err.(*strconv.NumError).Err
+	}
+	return n, nil
+}
+
+// GetKargSize returns the value of the last occurrence of the karg identified
+// by key parsed as a size in bytes the way the kernel's memparse does, as used
+// by e.g. mem=, hugepagesz=, and crashkernel=, as well as whether key is set.
+// The value is a number without sign in one of the bases GetKargUint accepts,
+// optionally followed by one of the suffixes K, M, G, T, P, or E, in either
+// case, which multiply it by 1024 raised to the power of 1 to 6, e.g. 512M or
+// 0x10G. In a hexadecimal
+// number, a trailing E is a digit rather than a suffix, like in the kernel.
+// Unlike memparse, which silently stops at the first character it doesn't
+// understand, an error wrapping ErrInvalidValue is returned if the value has
+// any trailing characters or the size doesn't fit into 64 bits.
+func (k *Kargs) GetKargSize(key string) (uint64, bool, error) {
+	raw, present := k.lastValue(key)
+	if !present {
+		return 0, false, nil
+	}
+	n, err := parseMemSize(raw)
+	if err != nil {
+		return 0, true, fmt.Errorf("parsing value %s of key %s as size: %v: %w", raw, key, err, ErrInvalidValue)
+	}
+	return n, true, nil
+}
+
+// parseMemSize parses s as a size with an optional memparse suffix.
+func parseMemSize(s string) (uint64, error) {
+	digits, shift := s, 0
+	if s != "" {
+		hex := len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+		if i := strings.IndexByte("KMGTPE", s[len(s)-1]&^0x20); i >= 0 && !(hex && i == 5) {
+			digits, shift = s[:len(s)-1], 10*(i+1)
+		}
+	}
+	n, err := parseKernelUint(digits)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint64>>shift {
+		return 0, strconv.ErrRange
+	}
+	return n << shift, nil
+}
@@ -0,0 +1,133 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKargs_GetKargBool(t *testing.T) {
+	checks := []struct {
+		cmdline string
+		want    bool
+	}{
+		{cmdline: "flag", want: true},
+		{cmdline: "flag=1", want: true},
+		{cmdline: "flag=y", want: true},
+		{cmdline: "flag=Yes", want: true},
+		{cmdline: "flag=on", want: true},
+		{cmdline: "flag=True", want: true},
+		{cmdline: "flag=0", want: false},
+		{cmdline: "flag=N", want: false},
+		{cmdline: "flag=off", want: false},
+		{cmdline: "flag=Of", want: false},
+		{cmdline: "flag=false", want: false},
+		{cmdline: "flag=1 flag=0", want: false},
+		{cmdline: `flag="on"`, want: true},
+	}
+	for _, check := range checks {
+		value, present, err := NewKargs([]byte(check.cmdline)).GetKargBool("flag")
+		assert.NoError(t, err, check.cmdline)
+		assert.True(t, present, check.cmdline)
+		assert.Equal(t, check.want, value, check.cmdline)
+	}
+
+	for _, cmdline := range []string{"flag=2", "flag=o", "flag=maybe", "flag=0 flag=x"} {
+		_, present, err := NewKargs([]byte(cmdline)).GetKargBool("flag")
+		assert.ErrorIs(t, err, ErrInvalidValue, cmdline)
+		assert.True(t, present, cmdline)
+	}
+
+	value, present, err := NewKargs([]byte("other")).GetKargBool("flag")
+	assert.NoError(t, err)
+	assert.False(t, present)
+	assert.False(t, value)
+}
+
+func TestKargs_GetKargInt(t *testing.T) {
+	checks := []struct {
+		value string
+		want  int64
+	}{
+		{value: "7", want: 7},
+		{value: "+7", want: 7},
+		{value: "-7", want: -7},
+		{value: "0", want: 0},
+		{value: "0x1F", want: 31},
+		{value: "0X1f", want: 31},
+		{value: "017", want: 15},
+		{value: "-0x10", want: -16},
+		{value: "9223372036854775807", want: 1<<63 - 1},
+		{value: "-9223372036854775808", want: -1 << 63},
+	}
+	for _, check := range checks {
+		n, err := NewKargs([]byte("loglevel=" + check.value)).GetKargInt("loglevel")
+		assert.NoError(t, err, check.value)
+		assert.Equal(t, check.want, n, check.value)
+	}
+
+	for _, value := range []string{"", "x", "1_000", "0o17", "0b1", "08", "0x", "--1", "+-1", "1.5", "9223372036854775808", "-9223372036854775809"} {
+		_, err := NewKargs([]byte("loglevel=" + value)).GetKargInt("loglevel")
+		assert.ErrorIs(t, err, ErrInvalidValue, value)
+	}
+
+	n, err := NewKargs([]byte("loglevel=3 loglevel=7")).GetKargInt("loglevel")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), n)
+
+	_, err = NewKargs([]byte("quiet")).GetKargInt("loglevel")
+	assert.ErrorIs(t, err, ErrNotExists)
+	assert.NotErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestKargs_GetKargUint(t *testing.T) {
+	k := NewKargs([]byte("a=42 b=+0x2a c=052 d=18446744073709551615 e=-1 f=18446744073709551616"))
+
+	for _, key := range []string{"a", "b", "c"} {
+		n, err := k.GetKargUint(key)
+		assert.NoError(t, err, key)
+		assert.Equal(t, uint64(42), n, key)
+	}
+	n, err := k.GetKargUint("d")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1<<64-1), n)
+
+	_, err = k.GetKargUint("e")
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = k.GetKargUint("f")
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = k.GetKargUint("g")
+	assert.ErrorIs(t, err, ErrNotExists)
+}
+
+func TestKargs_GetKargSize(t *testing.T) {
+	k := NewKargs([]byte("mem=512M hugepagesz=1g crashkernel=0x10G a=4096 b=0x1e c=0x1E d=1e e=15E f=16E g=2X h=+1K i=K"))
+
+	for key, want := range map[string]uint64{
+		"mem":         512 << 20,
+		"hugepagesz":  1 << 30,
+		"crashkernel": 16 << 30,
+		"a":           4096,
+		"b":           0x1e,
+		"c":           0x1e,
+		"d":           1 << 60,
+		"e":           15 << 60,
+	} {
+		n, present, err := k.GetKargSize(key)
+		assert.NoError(t, err, key)
+		assert.True(t, present, key)
+		assert.Equal(t, want, n, key)
+	}
+	for _, key := range []string{"f", "g", "h", "i"} {
+		_, present, err := k.GetKargSize(key)
+		assert.ErrorIs(t, err, ErrInvalidValue, key)
+		assert.True(t, present, key)
+	}
+	n, present, err := k.GetKargSize("missing")
+	assert.NoError(t, err)
+	assert.False(t, present)
+	assert.Zero(t, n)
+}
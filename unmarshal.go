@@ -0,0 +1,230 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// kargField describes a struct field tagged with a karg struct tag.
+type kargField struct {
+	index int    // Index of the field in its struct
+	name  string // Name of the field, for error messages
+	key   string // Key of the karg the field corresponds to
+	multi bool   // Whether the field holds all occurrences of the key
+	csv   bool   // Whether values are comma-separated lists
+	size  bool   // Whether integers are sizes with memparse suffixes
+	omit  bool   // Whether Marshal omits the field if it has its zero value
+}
+
+// kargFields returns the fields of the struct type t that have a karg tag, in
+// declaration order. An error wrapping ErrUnsupported is returned if a tag is
+// malformed or doesn't fit the type of its field.
+func kargFields(t reflect.Type) ([]kargField, error) {
+	var fields []kargField
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("karg")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("field %s: unexported field tagged: %w", sf.Name, ErrUnsupported)
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		field := kargField{index: i, name: sf.Name, key: name}
+		if field.key == "" {
+			return nil, fmt.Errorf("field %s: tag has no key: %w", sf.Name, ErrUnsupported)
+		}
+		if err := checkKey(field.key); err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "":
+			case "multi":
+				field.multi = true
+			case "csv":
+				field.csv = true
+			case "size":
+				field.size = true
+			case "omitempty":
+				field.omit = true
+			default:
+				return nil, fmt.Errorf("field %s: unknown tag option %q: %w", sf.Name, opt, ErrUnsupported)
+			}
+		}
+		if err := field.checkType(sf.Type); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// checkType returns an error if t can't hold the field as tagged.
+func (f kargField) checkType(t reflect.Type) error {
+	elem := t
+	if t.Kind() == reflect.Slice {
+		if !f.multi && !f.csv {
+			return fmt.Errorf("field %s: slice needs the multi or csv option: %w", f.name, ErrUnsupported)
+		}
+		elem = t.Elem()
+	} else if f.multi || f.csv {
+		return fmt.Errorf("field %s: multi and csv need a slice: %w", f.name, ErrUnsupported)
+	}
+	switch elem.Kind() {
+	case reflect.String, reflect.Bool:
+		if f.size {
+			return fmt.Errorf("field %s: size needs an integer: %w", f.name, ErrUnsupported)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nil
+	}
+	return fmt.Errorf("field %s: type %s: %w", f.name, t, ErrUnsupported)
+}
+
+// Unmarshal populates the fields of the struct v points to from the kargs of k,
+// so that programs like an init in an initramfs can declare the parameters they
+// expect instead of looking up each of them. Fields are matched by their karg
+// struct tag, which holds the key followed by options, e.g.
+//
+//	type Params struct {
+//		Root     string   `karg:"root"`
+//		ReadOnly bool     `karg:"ro"`
+//		Consoles []string `karg:"console,multi"`
+//		Mem      uint64   `karg:"mem,size"`
+//		Blocked  []string `karg:"modprobe.blacklist,multi,csv"`
+//	}
+//
+// Fields without a tag or with the tag "-" are ignored. Like in keys, - and _
+// in tag keys are equivalent. Fields may be strings, booleans, integers of any
+// size, or slices of these. Values are converted like GetKargBool, GetKargInt,
+// and GetKargUint do; integers with the size option like GetKargSize does.
+// Unless a field has the multi option, the last occurrence of its key is used,
+// as by the kernel. The options are:
+//
+//   - multi: the slice gets one element per occurrence of the key
+//   - csv: the slice gets the fields of the value split like GetKargCSV does;
+//     with multi, those of all occurrences
+//   - size: integers may have K, M, G, T, P, or E suffixes
+//   - omitempty: only used by Marshal
+//
+// Fields whose key is not set are left unchanged, so defaults can be set before
+// calling Unmarshal. Slices are replaced, not appended to.
+//
+// An error wrapping ErrNilPtr is returned if v is nil, and one wrapping
+// ErrUnsupported if v is not a pointer to a struct or a tag is malformed or
+// doesn't fit its field; no field is set in these cases. Values that can't be
+// converted to the type of their field are reported as a KeyErrors wrapping
+// ErrInvalidValue, after all other fields have been set.
+func Unmarshal(k *Kargs, v any) error {
+	rv := reflect.ValueOf(v)
+	if v == nil || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return fmt.Errorf("unmarshaling kargs: %w", ErrNilPtr)
+	}
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unmarshaling kargs into %T: %w", v, ErrUnsupported)
+	}
+	rv = rv.Elem()
+	fields, err := kargFields(rv.Type())
+	if err != nil {
+		return fmt.Errorf("unmarshaling kargs into %T: %w", v, err)
+	}
+	var errs KeyErrors
+	for _, field := range fields {
+		values, present := k.GetKarg(field.key)
+		if !present {
+			continue
+		}
+		if err := field.set(rv.Field(field.index), values); err != nil {
+			errs = append(errs, &KeyError{Key: field.key, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// set sets fv, the value of f, from values, the values of all occurrences of
+// its key. fv is left unchanged if a value can't be converted.
+func (f kargField) set(fv reflect.Value, values []string) error {
+	if fv.Kind() != reflect.Slice {
+		return f.setScalar(fv, values[len(values)-1])
+	}
+	if !f.multi {
+		values = values[len(values)-1:]
+	}
+	var elems []string
+	for _, value := range values {
+		if f.csv {
+			elems = append(elems, splitCSV(value)...)
+		} else {
+			elems = append(elems, value)
+		}
+	}
+	slice := reflect.MakeSlice(fv.Type(), len(elems), len(elems))
+	for idx, elem := range elems {
+		if err := f.setScalar(slice.Index(idx), elem); err != nil {
+			return err
+		}
+	}
+	fv.Set(slice)
+	return nil
+}
+
+// setScalar sets fv, which is not a slice, from raw.
+func (f kargField) setScalar(fv reflect.Value, raw string) error {
+	var err error
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+		return nil
+	case reflect.Bool:
+		var b bool
+		if b, err = parseKernelBool(raw); err == nil {
+			fv.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if f.size {
+			var size uint64
+			if size, err = parseMemSize(raw); err == nil && size > 1<<63-1 {
+				err = strconv.ErrRange
+			}
+			n = int64(size)
+		} else {
+			n, err = parseKernelInt(raw)
+		}
+		if err == nil && fv.OverflowInt(n) {
+			err = strconv.ErrRange
+		}
+		if err == nil {
+			fv.SetInt(n)
+			return nil
+		}
+	default:
+		var n uint64
+		if f.size {
+			n, err = parseMemSize(raw)
+		} else {
+			n, err = parseKernelUint(strings.TrimPrefix(raw, "+"))
+		}
+		if err == nil && fv.OverflowUint(n) {
+			err = strconv.ErrRange
+		}
+		if err == nil {
+			fv.SetUint(n)
+			return nil
+		}
+	}
+	return fmt.Errorf("field %s: value %q: %v: %w", f.name, raw, err, ErrInvalidValue)
+}
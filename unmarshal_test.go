@@ -0,0 +1,108 @@
+// Use of this source code is governed by the LICENSE file in this module's root
+// directory.
+
+package kargs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal(t *testing.T) {
+	type params struct {
+		Root      string   `karg:"root"`
+		ReadOnly  bool     `karg:"ro"`
+		Debug     bool     `karg:"debug"`
+		Consoles  []string `karg:"console,multi"`
+		LastCon   string   `karg:"console"`
+		Mem       uint64   `karg:"mem,size"`
+		Loglevel  int8     `karg:"loglevel"`
+		Offset    int      `karg:"offset"`
+		Blocked   []string `karg:"rd.driver.blacklist,multi,csv"`
+		CPUs      []uint   `karg:"cpus,csv"`
+		Init      string   `karg:"init"`
+		Untagged  string
+		Ignored   string `karg:"-"`
+		Unchanged string `karg:"missing"`
+	}
+	k := NewKargs([]byte(`root=/dev/sda1 ro debug=off console=tty0 console=ttyS0,115200 mem=2G loglevel=7 offset=-0x10 ` +
+		`rd.driver.blacklist=nouveau,radeon rd.driver.blacklist=pcspkr cpus=1,2 cpus=0,3 init="/sbin/init --verbose" Untagged=x`))
+
+	p := params{Debug: true, Unchanged: "default", Ignored: "kept"}
+	assert.NoError(t, Unmarshal(k, &p))
+	assert.Equal(t, params{
+		Root:      "/dev/sda1",
+		ReadOnly:  true,
+		Debug:     false,
+		Consoles:  []string{"tty0", "ttyS0,115200"},
+		LastCon:   "ttyS0,115200",
+		Mem:       2 << 30,
+		Loglevel:  7,
+		Offset:    -16,
+		Blocked:   []string{"nouveau", "radeon", "pcspkr"},
+		CPUs:      []uint{0, 3},
+		Init:      "/sbin/init --verbose",
+		Ignored:   "kept",
+		Unchanged: "default",
+	}, p)
+}
+
+func TestUnmarshal_invalidValues(t *testing.T) {
+	var p struct {
+		Level int8   `karg:"loglevel"`
+		Size  uint32 `karg:"mem,size"`
+		Quiet bool   `karg:"quiet"`
+		Nums  []int  `karg:"nums,csv"`
+		Root  string `karg:"root"`
+	}
+	p.Nums = []int{42}
+	k := NewKargs([]byte("loglevel=300 mem=8G quiet=maybe nums=1,x root=/dev/sda1"))
+	err := Unmarshal(k, &p)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	var keyErrs KeyErrors
+	if assert.ErrorAs(t, err, &keyErrs) {
+		assert.Equal(t, []string{"loglevel", "mem", "quiet", "nums"}, keyErrs.Keys())
+	}
+	assert.Equal(t, "/dev/sda1", p.Root)
+	assert.Equal(t, []int{42}, p.Nums)
+	assert.Zero(t, p.Level)
+}
+
+func TestUnmarshal_unsupported(t *testing.T) {
+	k := NewKargs([]byte("root=/dev/sda1"))
+
+	assert.ErrorIs(t, Unmarshal(k, nil), ErrNilPtr)
+	var nilPtr *struct{}
+	assert.ErrorIs(t, Unmarshal(k, nilPtr), ErrNilPtr)
+
+	var s string
+	assert.ErrorIs(t, Unmarshal(k, &s), ErrUnsupported)
+	assert.ErrorIs(t, Unmarshal(k, struct{}{}), ErrUnsupported)
+
+	for name, v := range map[string]any{
+		"slice without option": &struct {
+			V []string `karg:"root"`
+		}{},
+		"multi without slice": &struct {
+			V string `karg:"root,multi"`
+		}{},
+		"size on string": &struct {
+			V string `karg:"root,size"`
+		}{},
+		"unknown option": &struct {
+			V string `karg:"root,bogus"`
+		}{},
+		"empty key": &struct {
+			V string `karg:",multi"`
+		}{},
+		"unsupported type": &struct {
+			V float64 `karg:"root"`
+		}{},
+		"unexported": &struct {
+			v string `karg:"root"`
+		}{},
+	} {
+		assert.ErrorIs(t, Unmarshal(k, v), ErrUnsupported, name)
+	}
+}